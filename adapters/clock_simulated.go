@@ -18,3 +18,17 @@ func (c *SimulatedClock) UnixNano() int64 {
 	c.now += c.step
 	return c.now
 }
+
+// Now returns the current simulated time without advancing it.
+func (c *SimulatedClock) Now() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *SimulatedClock) Advance(delta int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now += delta
+	return c.now
+}
@@ -0,0 +1,253 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"xenium/ports"
+)
+
+// LevelCritical sits above slog.LevelError.
+const LevelCritical = slog.Level(12)
+
+// LogConfig configures NewSlogLogger. Levels are debug, info, warn, error or
+// critical; Modules overrides Level per module (core, consensus, storage,
+// network, ...). With File set, logs go to that file and are rotated once
+// it exceeds MaxSizeMB, keeping MaxBackups old files.
+type LogConfig struct {
+	Level      string
+	Format     string // "json" (default) or "text"
+	Modules    map[string]string
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
+}
+
+// SlogLogger writes JSON lines through log/slog. Module loggers share the
+// output and filter by their own level.
+type SlogLogger struct {
+	root  *slogRoot
+	l     *slog.Logger
+	attrs []any
+}
+
+type slogRoot struct {
+	cfg    LogConfig
+	level  slog.Level
+	out    io.Writer
+	closer io.Closer
+}
+
+func NewSlogLogger(cfg LogConfig, stdout io.Writer) (*SlogLogger, error) {
+	level, err := ParseLogLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	for module, lvl := range cfg.Modules {
+		if _, err := ParseLogLevel(lvl); err != nil {
+			return nil, fmt.Errorf("module %s: %w", module, err)
+		}
+	}
+	root := &slogRoot{cfg: cfg, level: level}
+	if cfg.File != "" {
+		f, err := NewRotatingFile(cfg.File, int64(cfg.MaxSizeMB)<<20, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		root.out, root.closer = f, f
+	} else {
+		if stdout == nil {
+			stdout = os.Stdout
+		}
+		root.out = &lockedWriter{w: stdout}
+	}
+	return &SlogLogger{root: root, l: slog.New(root.handler(level))}, nil
+}
+
+func ParseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "critical":
+		return LevelCritical, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+func (r *slogRoot) handler(level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevel}
+	if r.cfg.Format == "text" {
+		return slog.NewTextHandler(r.out, opts)
+	}
+	return slog.NewJSONHandler(r.out, opts)
+}
+
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if lvl, ok := a.Value.Any().(slog.Level); ok && lvl >= LevelCritical {
+			a.Value = slog.StringValue("CRITICAL")
+		}
+	}
+	return a
+}
+
+func (l *SlogLogger) log(level slog.Level, format string, args []any) {
+	ctx := context.Background()
+	if !l.l.Enabled(ctx, level) {
+		return
+	}
+	l.l.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+func (l *SlogLogger) Debugf(format string, args ...any) { l.log(slog.LevelDebug, format, args) }
+func (l *SlogLogger) Infof(format string, args ...any)  { l.log(slog.LevelInfo, format, args) }
+func (l *SlogLogger) Warnf(format string, args ...any)  { l.log(slog.LevelWarn, format, args) }
+func (l *SlogLogger) Errorf(format string, args ...any) { l.log(slog.LevelError, format, args) }
+func (l *SlogLogger) Criticalf(format string, args ...any) {
+	l.log(LevelCritical, format, args)
+}
+
+func (l *SlogLogger) With(kv ...any) ports.Logger {
+	return &SlogLogger{root: l.root, l: l.l.With(kv...), attrs: append(append([]any(nil), l.attrs...), kv...)}
+}
+
+// Module returns a logger tagged with module=name at the module's
+// configured level. Fields added with With are kept.
+func (l *SlogLogger) Module(name string) ports.Logger {
+	level := l.root.level
+	if lvl, ok := l.root.cfg.Modules[name]; ok {
+		level, _ = ParseLogLevel(lvl)
+	}
+	attrs := make([]any, 0, len(l.attrs)+2)
+	for i := 0; i+1 < len(l.attrs); i += 2 {
+		if l.attrs[i] != "module" {
+			attrs = append(attrs, l.attrs[i], l.attrs[i+1])
+		}
+	}
+	attrs = append(attrs, "module", name)
+	return &SlogLogger{root: l.root, l: slog.New(l.root.handler(level)).With(attrs...), attrs: attrs}
+}
+
+// Close closes the log file, if any.
+func (l *SlogLogger) Close() error {
+	if l.root.closer == nil {
+		return nil
+	}
+	return l.root.closer.Close()
+}
+
+// lockedWriter serializes writes from handlers that share one output.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// RotatingFile is an append-only file that is renamed to path.1 (shifting
+// older backups up to path.<backups>) once a write would take it past
+// maxSize bytes. It is safe for concurrent use.
+type RotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	f       *os.File
+	size    int64
+}
+
+const defaultLogMaxSize = 100 << 20
+
+func NewRotatingFile(path string, maxSize int64, backups int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		maxSize = defaultLogMaxSize
+	}
+	if backups <= 0 {
+		backups = 3
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	r := &RotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+	_ = os.Remove(fmt.Sprintf("%s.%d", r.path, r.backups))
+	for i := r.backups - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", r.path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", r.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
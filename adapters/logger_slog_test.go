@@ -0,0 +1,102 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"xenium/ports"
+)
+
+func decodeLines(t *testing.T, data string) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("not a JSON line %q: %v", line, err)
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func TestSlogLoggerLevelsFieldsAndModules(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewSlogLogger(LogConfig{Level: "info", Modules: map[string]string{"network": "error", "core": "debug"}}, &buf)
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	logger.Debugf("hidden")
+	logger.With("depth", 2, "validator", "Alice").Warnf("Reorg detected at %d", 40)
+	ports.ForModule(logger, "network").Warnf("hidden too")
+	ports.ForModule(logger, "network").Errorf("dial failed")
+	core := ports.ForModule(logger, "core")
+	core.Debugf("visible")
+	core.With("slot", 7).Criticalf("finality violated")
+
+	entries := decodeLines(t, buf.String())
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d:\n%s", len(entries), buf.String())
+	}
+	reorg := entries[0]
+	if reorg["level"] != "WARN" || reorg["msg"] != "Reorg detected at 40" || reorg["depth"] != float64(2) || reorg["validator"] != "Alice" {
+		t.Fatalf("unexpected reorg entry %v", reorg)
+	}
+	if entries[1]["module"] != "network" || entries[1]["level"] != "ERROR" {
+		t.Fatalf("unexpected network entry %v", entries[1])
+	}
+	if entries[2]["module"] != "core" || entries[2]["level"] != "DEBUG" {
+		t.Fatalf("unexpected core debug entry %v", entries[2])
+	}
+	if entries[3]["level"] != "CRITICAL" || entries[3]["slot"] != float64(7) || entries[3]["module"] != "core" {
+		t.Fatalf("unexpected critical entry %v", entries[3])
+	}
+
+	if _, err := NewSlogLogger(LogConfig{Level: "loud"}, &buf); err == nil {
+		t.Fatalf("expected unknown level to be rejected")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "node.log")
+	f, err := NewRotatingFile(path, 64, 2)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	line := strings.Repeat("x", 39) + "\n"
+	for i := 0; i < 5; i++ {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(data) != line {
+			t.Fatalf("%s holds %q, want one line", name, data)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected at most 2 backups, stat err=%v", err)
+	}
+}
+
+func TestStdLoggerModuleReplacesModule(t *testing.T) {
+	l := ports.ForModule(StdLogger{}.With("peer", "a"), ports.ModuleNetwork)
+	l = ports.ForModule(l, ports.ModuleSync).With("height", 3)
+	if got := l.(StdLogger).suffix(); got != " module=sync peer=a height=3" {
+		t.Fatalf("unexpected fields %q", got)
+	}
+}
@@ -1,21 +1,77 @@
 package adapters
 
-import "fmt"
+import (
+	"fmt"
 
-type StdLogger struct{}
+	"xenium/ports"
+)
 
-func (StdLogger) Infof(format string, args ...any) {
-	fmt.Printf("[INFO] "+format+"\n", args...)
+// StdLogger prints plain text lines to stdout. Debug lines are printed only
+// when Debug is set; the module and fields from With are appended as
+// key=value.
+type StdLogger struct {
+	Debug  bool
+	module string
+	fields string
 }
 
-func (StdLogger) Warnf(format string, args ...any) {
-	fmt.Printf("[WARN] "+format+"\n", args...)
+func (l StdLogger) Debugf(format string, args ...any) {
+	if l.Debug {
+		l.print("DEBUG", format, args)
+	}
 }
 
-func (StdLogger) Errorf(format string, args ...any) {
-	fmt.Printf("[ERROR] "+format+"\n", args...)
+func (l StdLogger) Infof(format string, args ...any) {
+	l.print("INFO", format, args)
 }
 
-func (StdLogger) Criticalf(format string, args ...any) {
-	fmt.Printf("[CRITICAL] "+format+"\n", args...)
+func (l StdLogger) Warnf(format string, args ...any) {
+	l.print("WARN", format, args)
 }
+
+func (l StdLogger) Errorf(format string, args ...any) {
+	l.print("ERROR", format, args)
+}
+
+func (l StdLogger) Criticalf(format string, args ...any) {
+	l.print("CRITICAL", format, args)
+}
+
+func (l StdLogger) With(kv ...any) ports.Logger {
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 < len(kv) {
+			l.fields += fmt.Sprintf(" %v=%v", kv[i], kv[i+1])
+		} else {
+			l.fields += fmt.Sprintf(" !BADKEY=%v", kv[i])
+		}
+	}
+	return l
+}
+
+// Module returns a logger tagged with module=name, replacing any module set
+// before.
+func (l StdLogger) Module(name string) ports.Logger {
+	l.module = name
+	return l
+}
+
+func (l StdLogger) print(level string, format string, args []any) {
+	fmt.Printf("[%s] %s%s\n", level, fmt.Sprintf(format, args...), l.suffix())
+}
+
+func (l StdLogger) suffix() string {
+	if l.module == "" {
+		return l.fields
+	}
+	return " module=" + l.module + l.fields
+}
+
+// NopLogger discards everything.
+type NopLogger struct{}
+
+func (NopLogger) Debugf(string, ...any)      {}
+func (NopLogger) Infof(string, ...any)       {}
+func (NopLogger) Warnf(string, ...any)       {}
+func (NopLogger) Errorf(string, ...any)      {}
+func (NopLogger) Criticalf(string, ...any)   {}
+func (l NopLogger) With(...any) ports.Logger { return l }
@@ -0,0 +1,362 @@
+package adapters
+
+import (
+	"container/heap"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"xenium/consensus"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+// SimLink describes one direction of a simulated link. Durations are in
+// simulated nanoseconds; rates are probabilities in [0, 1].
+type SimLink struct {
+	Latency       int64
+	Jitter        int64
+	DropRate      float64
+	DuplicateRate float64
+	ReorderRate   float64
+}
+
+type SimNetworkConfig struct {
+	Seed        int64
+	DefaultLink SimLink
+}
+
+type SimStats struct {
+	Sent        uint64
+	Delivered   uint64
+	Dropped     uint64
+	Duplicated  uint64
+	Partitioned uint64
+}
+
+type SimDelivery struct {
+	At   int64
+	From string
+	To   string
+	Kind string
+	Hash string
+}
+
+// SimNetwork is an in-process network for multi-node simulations. Message
+// delivery is driven by a SimulatedClock and all randomness comes from a
+// single seeded source, so a run with the same seed and the same calls
+// replays exactly.
+type SimNetwork struct {
+	mu         sync.Mutex
+	clock      *SimulatedClock
+	rng        *rand.Rand
+	cfg        SimNetworkConfig
+	endpoints  map[string]*SimEndpoint
+	links      map[[2]string]SimLink
+	partition  map[string]int
+	events     []simEvent
+	queue      simQueue
+	seq        uint64
+	stats      SimStats
+	deliveries []SimDelivery
+}
+
+type SimEndpoint struct {
+	net     *SimNetwork
+	id      string
+	handler ports.NetworkHandler
+	seen    *seenCache
+	txs     *txCache
+}
+
+type simMessage struct {
+	at    int64
+	seq   uint64
+	from  string
+	to    string
+	block *domain.Block
+	tx    *domain.Transaction
+}
+
+type simEvent struct {
+	at     int64
+	seq    uint64
+	groups [][]string
+}
+
+func NewSimNetwork(clock *SimulatedClock, cfg SimNetworkConfig) *SimNetwork {
+	return &SimNetwork{
+		clock:     clock,
+		rng:       rand.New(rand.NewSource(cfg.Seed)),
+		cfg:       cfg,
+		endpoints: make(map[string]*SimEndpoint),
+		links:     make(map[[2]string]SimLink),
+	}
+}
+
+func (s *SimNetwork) Join(id string, handler ports.NetworkHandler) (*SimEndpoint, error) {
+	if handler == nil {
+		return nil, errors.New("network handler required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.endpoints[id]; ok {
+		return nil, errors.New("duplicate sim node " + id)
+	}
+	ep := &SimEndpoint{net: s, id: id, handler: handler, seen: newSeenCache(seenCacheSize), txs: newTxCache(txCacheSize)}
+	s.endpoints[id] = ep
+	return ep, nil
+}
+
+func (s *SimNetwork) SetLink(from string, to string, link SimLink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links[[2]string{from, to}] = link
+}
+
+// Partition splits the network into groups; nodes only reach nodes in the
+// same group. Nodes not listed share one implicit group.
+func (s *SimNetwork) Partition(groups ...[]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applyPartition(groups)
+}
+
+func (s *SimNetwork) Heal() {
+	s.Partition()
+}
+
+func (s *SimNetwork) SchedulePartition(at int64, groups ...[]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.events = append(s.events, simEvent{at: at, seq: s.seq, groups: groups})
+	sort.Slice(s.events, func(i, j int) bool {
+		if s.events[i].at != s.events[j].at {
+			return s.events[i].at < s.events[j].at
+		}
+		return s.events[i].seq < s.events[j].seq
+	})
+}
+
+func (s *SimNetwork) ScheduleHeal(at int64) {
+	s.SchedulePartition(at)
+}
+
+// Step advances the clock by one step and delivers every message and
+// partition change due by then. It returns the number of messages delivered.
+func (s *SimNetwork) Step() int {
+	return s.RunUntil(s.clock.UnixNano())
+}
+
+// RunUntil delivers everything due at or before t without touching the clock.
+func (s *SimNetwork) RunUntil(t int64) int {
+	delivered := 0
+	for {
+		s.mu.Lock()
+		if len(s.events) > 0 && s.events[0].at <= t && (s.queue.Len() == 0 || s.events[0].at <= s.queue[0].at) {
+			ev := s.events[0]
+			s.events = s.events[1:]
+			s.applyPartition(ev.groups)
+			s.mu.Unlock()
+			continue
+		}
+		if s.queue.Len() == 0 || s.queue[0].at > t {
+			s.mu.Unlock()
+			return delivered
+		}
+		msg := heap.Pop(&s.queue).(*simMessage)
+		ep := s.endpoints[msg.to]
+		if !s.connected(msg.from, msg.to) {
+			s.stats.Partitioned++
+			s.mu.Unlock()
+			continue
+		}
+		s.stats.Delivered++
+		d := SimDelivery{At: msg.at, From: msg.from, To: msg.to}
+		if msg.block != nil {
+			d.Kind, d.Hash = msgBlock, msg.block.Hash
+		} else {
+			d.Kind, d.Hash = msgTx, msg.tx.Hash
+		}
+		s.deliveries = append(s.deliveries, d)
+		s.mu.Unlock()
+
+		ep.receive(msg)
+		delivered++
+	}
+}
+
+func (s *SimNetwork) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queue.Len()
+}
+
+func (s *SimNetwork) Stats() SimStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Deliveries returns the ordered delivery log, useful for comparing runs.
+func (s *SimNetwork) Deliveries() []SimDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SimDelivery(nil), s.deliveries...)
+}
+
+func (s *SimNetwork) applyPartition(groups [][]string) {
+	if len(groups) == 0 {
+		s.partition = nil
+		return
+	}
+	s.partition = make(map[string]int)
+	for i, g := range groups {
+		for _, id := range g {
+			s.partition[id] = i + 1
+		}
+	}
+}
+
+func (s *SimNetwork) connected(a string, b string) bool {
+	if s.partition == nil {
+		return true
+	}
+	return s.partition[a] == s.partition[b]
+}
+
+func (s *SimNetwork) linkFor(from string, to string) SimLink {
+	if l, ok := s.links[[2]string{from, to}]; ok {
+		return l
+	}
+	return s.cfg.DefaultLink
+}
+
+// send schedules msg from one endpoint to every other endpoint except skip,
+// in sorted id order so random draws happen in a fixed sequence.
+func (s *SimNetwork) send(from string, skip string, block *domain.Block, tx *domain.Transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	ids := make([]string, 0, len(s.endpoints))
+	for id := range s.endpoints {
+		if id != from && id != skip {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, to := range ids {
+		s.stats.Sent++
+		if !s.connected(from, to) {
+			s.stats.Partitioned++
+			continue
+		}
+		link := s.linkFor(from, to)
+		if s.rng.Float64() < link.DropRate {
+			s.stats.Dropped++
+			continue
+		}
+		copies := 1
+		if s.rng.Float64() < link.DuplicateRate {
+			copies = 2
+			s.stats.Duplicated++
+		}
+		for c := 0; c < copies; c++ {
+			delay := link.Latency
+			if link.Jitter > 0 {
+				delay += s.rng.Int63n(link.Jitter + 1)
+			}
+			if s.rng.Float64() < link.ReorderRate {
+				delay += link.Latency + link.Jitter
+			}
+			s.seq++
+			heap.Push(&s.queue, &simMessage{at: now + delay, seq: s.seq, from: from, to: to, block: block, tx: tx})
+		}
+	}
+}
+
+func (e *SimEndpoint) ID() string {
+	return e.id
+}
+
+func (e *SimEndpoint) BroadcastBlock(block domain.Block) error {
+	e.seen.add(msgBlock + ":" + block.Hash)
+	e.net.send(e.id, "", &block, nil)
+	return nil
+}
+
+// BroadcastTx delivers the body directly; the simulator does not model the
+// announce/request round trip of the TCP transport.
+func (e *SimEndpoint) BroadcastTx(tx domain.Transaction) error {
+	e.seen.add(msgTx + ":" + tx.Hash)
+	e.txs.put(tx)
+	e.net.send(e.id, "", nil, &tx)
+	return nil
+}
+
+func (e *SimEndpoint) AnnounceTxs(txs []domain.Transaction) error {
+	for _, tx := range txs {
+		e.seen.add(msgTx + ":" + tx.Hash)
+		e.txs.put(tx)
+		e.net.send(e.id, "", nil, &tx)
+	}
+	return nil
+}
+
+// receive hands a message to the node and relays it if the node accepted it,
+// mirroring the gossip rules of the TCP transport: a hash is remembered only
+// once the node took the message or already held it.
+func (e *SimEndpoint) receive(msg *simMessage) {
+	if msg.block != nil {
+		if consensus.VerifyBlockHash(*msg.block) != nil {
+			return
+		}
+		key := msgBlock + ":" + msg.block.Hash
+		if e.seen.has(key) {
+			return
+		}
+		if err := e.handler.HandleBlock(msg.from, *msg.block); err != nil {
+			if errors.Is(err, ports.ErrKnown) {
+				e.seen.add(key)
+			}
+			return
+		}
+		e.seen.add(key)
+		e.net.send(e.id, msg.from, msg.block, nil)
+		return
+	}
+	key := msgTx + ":" + msg.tx.Hash
+	if e.seen.has(key) {
+		return
+	}
+	if err := e.handler.HandleTx(msg.from, *msg.tx); err != nil {
+		if errors.Is(err, ports.ErrKnown) {
+			e.seen.add(key)
+		}
+		return
+	}
+	e.seen.add(key)
+	e.txs.put(*msg.tx)
+	e.net.send(e.id, msg.from, nil, msg.tx)
+}
+
+type simQueue []*simMessage
+
+func (q simQueue) Len() int { return len(q) }
+func (q simQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+func (q simQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *simQueue) Push(x any)   { *q = append(*q, x.(*simMessage)) }
+func (q *simQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,152 @@
+package adapters
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+type recordingHandler struct {
+	blocks []string
+}
+
+func (h *recordingHandler) HandleBlock(peer string, block domain.Block) error {
+	h.blocks = append(h.blocks, block.Hash)
+	return nil
+}
+
+func (h *recordingHandler) HandleTx(peer string, tx domain.Transaction) error {
+	return nil
+}
+
+func simTestBlock(validator string, slot uint64) domain.Block {
+	b := domain.Block{Index: slot, PrevHash: "GENESIS", Slot: slot, Validator: validator}
+	b.Hash = consensus.HashBlock(b.Index, b.PrevHash, b.Slot, b.Tick, b.Validator, b.TxRoot, b.StateRoot, b.PoHHash)
+	return b
+}
+
+func runSimScenario(t *testing.T, seed int64) ([]SimDelivery, SimStats, map[string]*recordingHandler) {
+	t.Helper()
+	clock := NewSimulatedClock(0, 10)
+	net := NewSimNetwork(clock, SimNetworkConfig{
+		Seed: seed,
+		DefaultLink: SimLink{
+			Latency:       20,
+			Jitter:        15,
+			DropRate:      0.1,
+			DuplicateRate: 0.1,
+			ReorderRate:   0.2,
+		},
+	})
+	ids := []string{"a", "b", "c", "d"}
+	handlers := make(map[string]*recordingHandler)
+	endpoints := make(map[string]*SimEndpoint)
+	for _, id := range ids {
+		h := &recordingHandler{}
+		ep, err := net.Join(id, h)
+		if err != nil {
+			t.Fatalf("join: %v", err)
+		}
+		handlers[id] = h
+		endpoints[id] = ep
+	}
+	net.SchedulePartition(200, []string{"a", "b"}, []string{"c", "d"})
+	net.ScheduleHeal(400)
+
+	for i := 0; i < 60; i++ {
+		if i%3 == 0 {
+			from := ids[(i/3)%len(ids)]
+			_ = endpoints[from].BroadcastBlock(simTestBlock(from, uint64(i)))
+		}
+		net.Step()
+	}
+	for net.Pending() > 0 {
+		net.Step()
+	}
+	return net.Deliveries(), net.Stats(), handlers
+}
+
+func TestSimNetworkIsDeterministicFromSeed(t *testing.T) {
+	first, stats1, handlers1 := runSimScenario(t, 42)
+	second, stats2, _ := runSimScenario(t, 42)
+	if !reflect.DeepEqual(first, second) || stats1 != stats2 {
+		t.Fatalf("same seed produced different runs")
+	}
+	other, _, _ := runSimScenario(t, 43)
+	if reflect.DeepEqual(first, other) {
+		t.Fatalf("different seeds produced identical runs")
+	}
+	if stats1.Dropped == 0 || stats1.Duplicated == 0 || stats1.Partitioned == 0 {
+		t.Fatalf("expected drops, duplicates and partition losses, got %+v", stats1)
+	}
+	for id, h := range handlers1 {
+		if len(h.blocks) == 0 {
+			t.Fatalf("node %s handled no blocks", id)
+		}
+	}
+}
+
+func TestSimNetworkPartitionBlocksCrossTraffic(t *testing.T) {
+	deliveries, _, _ := runSimScenario(t, 7)
+	group := map[string]int{"a": 1, "b": 1, "c": 2, "d": 2}
+	for _, d := range deliveries {
+		if d.At >= 200 && d.At < 400 && group[d.From] != group[d.To] {
+			t.Fatalf("delivery crossed partition: %+v", d)
+		}
+	}
+}
+
+// refusingHandler refuses the first delivery of every message, as a node
+// still missing a parent block would, and accepts the rest.
+type refusingHandler struct {
+	handled map[string]int
+}
+
+func (h *refusingHandler) handle(hash string) error {
+	h.handled[hash]++
+	if h.handled[hash] == 1 {
+		return errors.New("unknown parent hash")
+	}
+	return nil
+}
+
+func (h *refusingHandler) HandleBlock(peer string, block domain.Block) error {
+	return h.handle(block.Hash)
+}
+
+func (h *refusingHandler) HandleTx(peer string, tx domain.Transaction) error {
+	return h.handle(tx.Hash)
+}
+
+func TestSimEndpointRetriesRefusedGossip(t *testing.T) {
+	net := NewSimNetwork(NewSimulatedClock(0, 10), SimNetworkConfig{Seed: 1, DefaultLink: SimLink{Latency: 5}})
+	a, err := net.Join("a", &recordingHandler{})
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	h := &refusingHandler{handled: make(map[string]int)}
+	if _, err := net.Join("b", h); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	block := simTestBlock("a", 1)
+	forged := block
+	forged.Slot = 2
+	tx := domain.Transaction{Hash: "tx-1", To: "bob", Amount: 1}
+	for i := 0; i < 3; i++ {
+		_ = a.BroadcastBlock(block)
+		_ = a.BroadcastBlock(forged)
+		_ = a.BroadcastTx(tx)
+		for net.Pending() > 0 {
+			net.Step()
+		}
+	}
+	if h.handled[block.Hash] != 2 || h.handled[tx.Hash] != 2 {
+		t.Fatalf("expected a refused message to be handled again until accepted, got block=%d tx=%d", h.handled[block.Hash], h.handled[tx.Hash])
+	}
+	if len(h.handled) != 2 {
+		t.Fatalf("block whose hash does not match its header reached the handler: %v", h.handled)
+	}
+}
@@ -0,0 +1,927 @@
+package adapters
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"xenium/consensus"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+const (
+	ProtocolVersion  = 2
+	maxFrameSize     = 16 << 20
+	handshakeTimeout = 5 * time.Second
+	peerSendQueue    = 256
+	seenCacheSize    = 8192
+	maxMsgsPerSecond = 1000
+)
+
+const (
+	msgHello = "hello"
+	msgAuth  = "auth"
+	msgBlock = "block"
+	msgTx    = "tx"
+
+	msgTxAnnounce = "tx_announce"
+	msgTxRequest  = "tx_request"
+	msgTxs        = "txs"
+
+	msgGetAddrs = "getaddrs"
+	msgAddrs    = "addrs"
+
+	msgStatusReq  = "status_req"
+	msgStatusRes  = "status_res"
+	msgHeadersReq = "headers_req"
+	msgHeadersRes = "headers_res"
+	msgBodiesReq  = "bodies_req"
+	msgBodiesRes  = "bodies_res"
+)
+
+const maxHeadersPerRequest = 512
+const maxBodiesPerRequest = 128
+
+var ErrHandshake = errors.New("handshake failed")
+var ErrPeerRejected = errors.New("peer rejected")
+
+type TCPConfig struct {
+	ListenAddr  string
+	ChainID     string
+	GenesisHash string
+	NodeKey     *ecdsa.PrivateKey
+	Handler     ports.NetworkHandler
+	Logger      ports.Logger
+	// Peers tracks addresses, limits and scores. Nil uses an in-memory
+	// manager with default limits and no bootnodes.
+	Peers        *PeerManager
+	DialInterval time.Duration
+}
+
+type PeerInfo struct {
+	ID       string
+	Addr     string
+	Outbound bool
+}
+
+type TCPNetwork struct {
+	cfg      TCPConfig
+	pubKey   string
+	id       string
+	listener net.Listener
+	mu       sync.RWMutex
+	peers    map[string]*tcpPeer
+	seen     *seenCache
+	wg       sync.WaitGroup
+	closed   chan struct{}
+	once     sync.Once
+	reqMu    sync.Mutex
+	nextReq  uint64
+	pending  map[uint64]pendingRequest
+
+	txs         *txCache
+	txMu        sync.Mutex
+	txRequested map[string]txRequest
+}
+
+type tcpPeer struct {
+	id       string
+	addr     string
+	outbound bool
+	conn     net.Conn
+	send     chan []byte
+	done     chan struct{}
+	once     sync.Once
+	knownTxs *seenCache
+
+	// read loop only
+	addrsServed   bool
+	addrsAsked    bool
+	window        time.Time
+	msgs          int
+	announceLimit *rateLimiter
+	requestLimit  *rateLimiter
+}
+
+type envelope struct {
+	Type    string          `json:"type"`
+	ID      uint64          `json:"id,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type headersReq struct {
+	From  uint64 `json:"from"`
+	Count int    `json:"count"`
+}
+
+type bodiesReq struct {
+	Hashes []string `json:"hashes"`
+}
+
+type helloMsg struct {
+	ChainID     string `json:"chain_id"`
+	GenesisHash string `json:"genesis_hash"`
+	Version     int    `json:"version"`
+	PubKey      string `json:"pubkey"`
+	Nonce       string `json:"nonce"`
+	ListenAddr  string `json:"listen_addr,omitempty"`
+}
+
+type authMsg struct {
+	Signature string `json:"signature"`
+}
+
+func NewTCPNetwork(cfg TCPConfig) (*TCPNetwork, error) {
+	if cfg.Handler == nil {
+		return nil, errors.New("network handler required")
+	}
+	if cfg.ChainID == "" {
+		return nil, errors.New("chain id required")
+	}
+	if cfg.NodeKey == nil {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		cfg.NodeKey = key
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = StdLogger{}
+	}
+	if cfg.Peers == nil {
+		peers, err := NewPeerManager(PeerManagerConfig{})
+		if err != nil {
+			return nil, err
+		}
+		cfg.Peers = peers
+	}
+	if cfg.DialInterval <= 0 {
+		cfg.DialInterval = 5 * time.Second
+	}
+	pub := hex.EncodeToString(elliptic.Marshal(cfg.NodeKey.Curve, cfg.NodeKey.PublicKey.X, cfg.NodeKey.PublicKey.Y))
+	id, err := domain.AddressFromPubKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &TCPNetwork{
+		cfg:     cfg,
+		pubKey:  pub,
+		id:      id,
+		peers:   make(map[string]*tcpPeer),
+		seen:    newSeenCache(seenCacheSize),
+		closed:  make(chan struct{}),
+		pending: make(map[uint64]pendingRequest),
+
+		txs:         newTxCache(txCacheSize),
+		txRequested: make(map[string]txRequest),
+	}, nil
+}
+
+func (n *TCPNetwork) ID() string {
+	return n.id
+}
+
+func (n *TCPNetwork) Start() error {
+	ln, err := net.Listen("tcp", n.cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+	n.listener = ln
+	n.wg.Add(2)
+	go n.acceptLoop()
+	go n.dialLoop()
+	return nil
+}
+
+func (n *TCPNetwork) Addr() string {
+	if n.listener == nil {
+		return ""
+	}
+	return n.listener.Addr().String()
+}
+
+func (n *TCPNetwork) Close() error {
+	n.once.Do(func() {
+		close(n.closed)
+		if n.listener != nil {
+			_ = n.listener.Close()
+		}
+		n.mu.Lock()
+		for _, p := range n.peers {
+			p.close()
+		}
+		n.mu.Unlock()
+	})
+	n.wg.Wait()
+	return n.cfg.Peers.Save()
+}
+
+func (n *TCPNetwork) PeerManager() *PeerManager {
+	return n.cfg.Peers
+}
+
+func (n *TCPNetwork) Connect(addr string) (string, error) {
+	if !n.cfg.Peers.DialAttempt(addr) {
+		return "", fmt.Errorf("already dialing %s", addr)
+	}
+	id, err := n.dial(addr)
+	n.cfg.Peers.DialDone(addr, err)
+	return id, err
+}
+
+func (n *TCPNetwork) dial(addr string) (string, error) {
+	conn, err := net.DialTimeout("tcp", addr, handshakeTimeout)
+	if err != nil {
+		return "", err
+	}
+	p, err := n.setupPeer(conn, addr)
+	if err != nil {
+		return "", err
+	}
+	return p.id, nil
+}
+
+// dialLoop keeps outbound slots filled from bootnodes and the address book
+// and flushes the book to disk.
+func (n *TCPNetwork) dialLoop() {
+	defer n.wg.Done()
+	ticker := time.NewTicker(n.cfg.DialInterval)
+	defer ticker.Stop()
+	for {
+		for _, addr := range n.cfg.Peers.DialCandidates(n.Addr()) {
+			if _, err := n.Connect(addr); err != nil {
+				n.cfg.Logger.Warnf("P2P dial %s failed: %v", addr, err)
+			}
+		}
+		if err := n.cfg.Peers.Save(); err != nil {
+			n.cfg.Logger.Warnf("P2P address book save failed: %v", err)
+		}
+		select {
+		case <-n.closed:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *TCPNetwork) Peers() []PeerInfo {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	out := make([]PeerInfo, 0, len(n.peers))
+	for _, p := range n.peers {
+		out = append(out, PeerInfo{ID: p.id, Addr: p.addr, Outbound: p.outbound})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (n *TCPNetwork) BroadcastBlock(block domain.Block) error {
+	n.seen.add(msgBlock + ":" + block.Hash)
+	return n.broadcast(msgBlock, block, "")
+}
+
+func (n *TCPNetwork) broadcast(msgType string, payload any, except string) error {
+	frame, err := encodeFrame(msgType, payload)
+	if err != nil {
+		return err
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	for id, p := range n.peers {
+		if id == except {
+			continue
+		}
+		p.enqueue(frame, n.cfg.Logger)
+	}
+	return nil
+}
+
+func (n *TCPNetwork) acceptLoop() {
+	defer n.wg.Done()
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			select {
+			case <-n.closed:
+				return
+			default:
+			}
+			n.cfg.Logger.Warnf("P2P accept error: %v", err)
+			continue
+		}
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			if _, err := n.setupPeer(conn, ""); err != nil {
+				n.cfg.Logger.Warnf("P2P inbound peer %s rejected: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// setupPeer handshakes and registers a connection. dialAddr is the address
+// we dialed for outbound peers and empty for inbound ones.
+func (n *TCPNetwork) setupPeer(conn net.Conn, dialAddr string) (*tcpPeer, error) {
+	outbound := dialAddr != ""
+	peerHello, err := n.handshake(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	id, err := domain.AddressFromPubKey(peerHello.PubKey)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	bookAddr := dialAddr
+	if !outbound {
+		bookAddr = advertisedAddr(conn.RemoteAddr(), peerHello.ListenAddr)
+	}
+	p := &tcpPeer{
+		id:       id,
+		addr:     conn.RemoteAddr().String(),
+		outbound: outbound,
+		conn:     conn,
+		send:     make(chan []byte, peerSendQueue),
+		done:     make(chan struct{}),
+		knownTxs: newSeenCache(peerKnownTxsLimit),
+
+		announceLimit: newRateLimiter(txAnnouncePerSec, 2*txAnnouncePerSec),
+		requestLimit:  newRateLimiter(txRequestsPerSec, 2*txRequestsPerSec),
+	}
+	n.mu.Lock()
+	select {
+	case <-n.closed:
+		n.mu.Unlock()
+		conn.Close()
+		return nil, errors.New("network closed")
+	default:
+	}
+	if _, ok := n.peers[id]; ok {
+		n.mu.Unlock()
+		conn.Close()
+		return nil, fmt.Errorf("%w: duplicate peer %s", ErrHandshake, id)
+	}
+	if !n.cfg.Peers.Admit(id, bookAddr, outbound) {
+		n.mu.Unlock()
+		conn.Close()
+		return nil, fmt.Errorf("%w: %s is banned or peer limit reached", ErrPeerRejected, id)
+	}
+	n.peers[id] = p
+	n.mu.Unlock()
+
+	n.cfg.Logger.Infof("P2P peer connected id=%s addr=%s outbound=%t", id, p.addr, outbound)
+	// Set before the read loop starts: it owns the flag from then on and
+	// only takes one address list in reply.
+	p.addrsAsked = true
+	n.wg.Add(2)
+	go n.writeLoop(p)
+	go n.readLoop(p)
+	if frame, err := encodeFrame(msgGetAddrs, struct{}{}); err == nil {
+		p.enqueue(frame, n.cfg.Logger)
+	}
+	n.announceTo(p, n.txs.hashes(maxTxAnnounce))
+	return p, nil
+}
+
+// advertisedAddr combines the remote IP with the port the peer says it
+// listens on, or returns "" when the peer does not accept connections.
+func advertisedAddr(remote net.Addr, listen string) string {
+	if listen == "" {
+		return ""
+	}
+	_, port, err := net.SplitHostPort(listen)
+	if err != nil || port == "0" {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(remote.String())
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// handshake exchanges hello messages, checks chain ID, genesis hash and
+// protocol version, then proves key ownership by signing the peer's nonce.
+func (n *TCPNetwork) handshake(conn net.Conn) (helloMsg, error) {
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return helloMsg{}, err
+	}
+	hello := helloMsg{
+		ChainID:     n.cfg.ChainID,
+		GenesisHash: n.cfg.GenesisHash,
+		Version:     ProtocolVersion,
+		PubKey:      n.pubKey,
+		Nonce:       hex.EncodeToString(nonce),
+		ListenAddr:  n.Addr(),
+	}
+	if err := writeMessage(conn, msgHello, hello); err != nil {
+		return helloMsg{}, err
+	}
+	var peerHello helloMsg
+	if err := readExpected(conn, msgHello, &peerHello); err != nil {
+		return helloMsg{}, err
+	}
+	if peerHello.ChainID != n.cfg.ChainID {
+		return helloMsg{}, fmt.Errorf("%w: chain id %q", ErrHandshake, peerHello.ChainID)
+	}
+	if peerHello.GenesisHash != n.cfg.GenesisHash {
+		return helloMsg{}, fmt.Errorf("%w: genesis %s", ErrHandshake, peerHello.GenesisHash)
+	}
+	if peerHello.Version != ProtocolVersion {
+		return helloMsg{}, fmt.Errorf("%w: protocol version %d", ErrHandshake, peerHello.Version)
+	}
+	if peerHello.PubKey == n.pubKey {
+		return helloMsg{}, fmt.Errorf("%w: self connection", ErrHandshake)
+	}
+
+	digest := handshakeDigest(peerHello.Nonce, n.pubKey, n.cfg.ChainID)
+	sig, err := ecdsa.SignASN1(rand.Reader, n.cfg.NodeKey, digest)
+	if err != nil {
+		return helloMsg{}, err
+	}
+	if err := writeMessage(conn, msgAuth, authMsg{Signature: hex.EncodeToString(sig)}); err != nil {
+		return helloMsg{}, err
+	}
+	var peerAuth authMsg
+	if err := readExpected(conn, msgAuth, &peerAuth); err != nil {
+		return helloMsg{}, err
+	}
+	pubBytes, err := hex.DecodeString(peerHello.PubKey)
+	if err != nil {
+		return helloMsg{}, err
+	}
+	x, y := elliptic.Unmarshal(elliptic.P256(), pubBytes)
+	if x == nil || y == nil {
+		return helloMsg{}, fmt.Errorf("%w: invalid node key", ErrHandshake)
+	}
+	sigBytes, err := hex.DecodeString(peerAuth.Signature)
+	if err != nil {
+		return helloMsg{}, err
+	}
+	expected := handshakeDigest(hello.Nonce, peerHello.PubKey, n.cfg.ChainID)
+	if !ecdsa.VerifyASN1(&ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, expected, sigBytes) {
+		return helloMsg{}, fmt.Errorf("%w: invalid auth signature", ErrHandshake)
+	}
+	return peerHello, nil
+}
+
+func (n *TCPNetwork) writeLoop(p *tcpPeer) {
+	defer n.wg.Done()
+	for {
+		select {
+		case frame := <-p.send:
+			if _, err := p.conn.Write(frame); err != nil {
+				n.dropPeer(p, err)
+				return
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (n *TCPNetwork) readLoop(p *tcpPeer) {
+	defer n.wg.Done()
+	for {
+		env, err := readFrame(p.conn)
+		if err != nil {
+			n.dropPeer(p, err)
+			return
+		}
+		if n.overRate(p) {
+			n.penalize(p, ScoreSpam, "message rate exceeded")
+		}
+		if err := n.dispatch(p, env); err != nil {
+			n.cfg.Logger.Warnf("P2P message from %s rejected type=%s: %v", p.id, env.Type, err)
+			if delta := scoreFor(err); delta != 0 {
+				n.penalize(p, delta, err.Error())
+			}
+		}
+	}
+}
+
+func (n *TCPNetwork) dispatch(p *tcpPeer, env envelope) error {
+	switch env.Type {
+	case msgBlock:
+		var block domain.Block
+		if err := json.Unmarshal(env.Payload, &block); err != nil {
+			return malformed(err)
+		}
+		// The hash is only trusted once it matches the header, and only
+		// remembered once the handler took the block: one refused for an
+		// unknown parent must be accepted when it arrives again later.
+		if err := consensus.VerifyBlockHash(block); err != nil {
+			return fmt.Errorf("%w: %v", ports.ErrInvalidBlock, err)
+		}
+		key := msgBlock + ":" + block.Hash
+		if n.seen.has(key) {
+			return nil
+		}
+		if err := n.cfg.Handler.HandleBlock(p.id, block); err != nil {
+			if errors.Is(err, ports.ErrKnown) {
+				n.seen.add(key)
+				return nil
+			}
+			return err
+		}
+		if !n.seen.add(key) {
+			return nil
+		}
+		n.cfg.Peers.Adjust(p.id, ScoreGoodBlock)
+		return n.broadcast(msgBlock, block, p.id)
+	case msgTxAnnounce:
+		return n.handleTxAnnounce(p, env.Payload)
+	case msgTxRequest:
+		return n.handleTxRequest(p, env.Payload)
+	case msgTxs:
+		return n.handleTxs(p, env.Payload)
+	case msgGetAddrs:
+		if p.addrsServed {
+			return errSpam
+		}
+		p.addrsServed = true
+		frame, err := encodeFrame(msgAddrs, n.cfg.Peers.ExchangeAddresses(n.cfg.Peers.addrOf(p.id)))
+		if err != nil {
+			return err
+		}
+		p.enqueue(frame, n.cfg.Logger)
+		return nil
+	case msgAddrs:
+		if !p.addrsAsked {
+			return errSpam
+		}
+		p.addrsAsked = false
+		var addrs []string
+		if err := json.Unmarshal(env.Payload, &addrs); err != nil {
+			return malformed(err)
+		}
+		if len(addrs) > maxExchangeLen {
+			return errSpam
+		}
+		n.cfg.Peers.AddAddresses(addrs)
+		return nil
+	case msgStatusReq, msgHeadersReq, msgBodiesReq:
+		return n.serve(p, env)
+	case msgStatusRes, msgHeadersRes, msgBodiesRes:
+		// Request ids are sequential across peers, so a response only
+		// counts from the peer the request went to.
+		n.reqMu.Lock()
+		req, ok := n.pending[env.ID]
+		if ok && req.peer == p {
+			delete(n.pending, env.ID)
+		}
+		n.reqMu.Unlock()
+		if !ok {
+			return malformed(fmt.Errorf("unsolicited response id=%d", env.ID))
+		}
+		if req.peer != p {
+			return fmt.Errorf("%w: response id=%d was requested from another peer", errSpam, env.ID)
+		}
+		req.ch <- env
+		return nil
+	default:
+		return malformed(fmt.Errorf("unknown message type %q", env.Type))
+	}
+}
+
+type pendingRequest struct {
+	peer *tcpPeer
+	ch   chan envelope
+}
+
+var errSpam = errors.New("unrequested or oversized message")
+var errMalformed = errors.New("malformed message")
+
+func malformed(err error) error {
+	return fmt.Errorf("%w: %v", errMalformed, err)
+}
+
+// scoreFor maps a rejected message to a score change for its sender.
+// Errors that do not prove misbehaviour, such as an unknown parent, cost
+// nothing.
+func scoreFor(err error) int {
+	switch {
+	case errors.Is(err, ports.ErrBadSignature):
+		return ScoreBadSignature
+	case errors.Is(err, ports.ErrInvalidBlock):
+		return ScoreInvalidBlock
+	case errors.Is(err, ports.ErrInvalidTx):
+		return ScoreInvalidTx
+	case errors.Is(err, errSpam):
+		return ScoreSpam
+	case errors.Is(err, errMalformed):
+		return ScoreMalformed
+	}
+	return 0
+}
+
+func (n *TCPNetwork) penalize(p *tcpPeer, delta int, reason string) {
+	if n.cfg.Peers.Adjust(p.id, delta) {
+		n.cfg.Logger.Warnf("P2P banning peer %s: score below threshold after %s", p.id, reason)
+		n.dropPeer(p, io.EOF)
+	}
+}
+
+func (n *TCPNetwork) overRate(p *tcpPeer) bool {
+	now := time.Now()
+	if now.Sub(p.window) >= time.Second {
+		p.window = now
+		p.msgs = 0
+	}
+	p.msgs++
+	return p.msgs == maxMsgsPerSecond+1
+}
+
+func (n *TCPNetwork) serve(p *tcpPeer, env envelope) error {
+	provider, ok := n.cfg.Handler.(ports.SyncProvider)
+	var resType string
+	var result any
+	var err error
+	switch env.Type {
+	case msgStatusReq:
+		resType = msgStatusRes
+		if ok {
+			result = provider.ChainStatus()
+		}
+	case msgHeadersReq:
+		resType = msgHeadersRes
+		var req headersReq
+		if err = json.Unmarshal(env.Payload, &req); err == nil && ok {
+			if req.Count <= 0 || req.Count > maxHeadersPerRequest {
+				req.Count = maxHeadersPerRequest
+			}
+			result, err = provider.HeadersFrom(req.From, req.Count)
+		}
+	case msgBodiesReq:
+		resType = msgBodiesRes
+		var req bodiesReq
+		if err = json.Unmarshal(env.Payload, &req); err == nil && ok {
+			if len(req.Hashes) > maxBodiesPerRequest {
+				req.Hashes = req.Hashes[:maxBodiesPerRequest]
+			}
+			result, err = provider.Bodies(req.Hashes)
+		}
+	}
+	if !ok && err == nil {
+		err = errors.New("sync not supported")
+	}
+	res := envelope{Type: resType, ID: env.ID}
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		raw, merr := json.Marshal(result)
+		if merr != nil {
+			return merr
+		}
+		res.Payload = raw
+	}
+	frame, err := encodeEnvelope(res)
+	if err != nil {
+		return err
+	}
+	p.enqueue(frame, n.cfg.Logger)
+	return nil
+}
+
+func (n *TCPNetwork) SyncPeers() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	out := make([]string, 0, len(n.peers))
+	for id := range n.peers {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (n *TCPNetwork) RequestStatus(ctx context.Context, peer string) (ports.ChainStatus, error) {
+	var status ports.ChainStatus
+	err := n.request(ctx, peer, msgStatusReq, struct{}{}, &status)
+	return status, err
+}
+
+func (n *TCPNetwork) RequestHeaders(ctx context.Context, peer string, from uint64, count int) ([]domain.BlockHeader, error) {
+	var headers []domain.BlockHeader
+	err := n.request(ctx, peer, msgHeadersReq, headersReq{From: from, Count: count}, &headers)
+	return headers, err
+}
+
+func (n *TCPNetwork) RequestBodies(ctx context.Context, peer string, hashes []string) ([][]domain.Transaction, error) {
+	var bodies [][]domain.Transaction
+	err := n.request(ctx, peer, msgBodiesReq, bodiesReq{Hashes: hashes}, &bodies)
+	return bodies, err
+}
+
+func (n *TCPNetwork) DisconnectPeer(peer string, reason string) {
+	n.mu.RLock()
+	p, ok := n.peers[peer]
+	n.mu.RUnlock()
+	if !ok {
+		return
+	}
+	n.cfg.Logger.Warnf("P2P disconnecting peer %s: %s", peer, reason)
+	n.cfg.Peers.Ban(peer)
+	n.dropPeer(p, io.EOF)
+}
+
+func (n *TCPNetwork) request(ctx context.Context, peer string, msgType string, payload any, out any) error {
+	n.mu.RLock()
+	p, ok := n.peers[peer]
+	n.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown peer %s", peer)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ch := make(chan envelope, 1)
+	n.reqMu.Lock()
+	n.nextReq++
+	id := n.nextReq
+	n.pending[id] = pendingRequest{peer: p, ch: ch}
+	n.reqMu.Unlock()
+	defer func() {
+		n.reqMu.Lock()
+		delete(n.pending, id)
+		n.reqMu.Unlock()
+	}()
+
+	frame, err := encodeEnvelope(envelope{Type: msgType, ID: id, Payload: raw})
+	if err != nil {
+		return err
+	}
+	select {
+	case p.send <- frame:
+	case <-p.done:
+		return fmt.Errorf("peer %s disconnected", peer)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case res := <-ch:
+		if res.Error != "" {
+			return errors.New(res.Error)
+		}
+		return json.Unmarshal(res.Payload, out)
+	case <-p.done:
+		return fmt.Errorf("peer %s disconnected", peer)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *TCPNetwork) dropPeer(p *tcpPeer, err error) {
+	n.mu.Lock()
+	if cur, ok := n.peers[p.id]; ok && cur == p {
+		delete(n.peers, p.id)
+		n.cfg.Peers.Release(p.id)
+	}
+	n.mu.Unlock()
+	p.close()
+	select {
+	case <-n.closed:
+	default:
+		if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+			n.cfg.Logger.Warnf("P2P peer %s disconnected: %v", p.id, err)
+		}
+	}
+}
+
+func (p *tcpPeer) enqueue(frame []byte, logger ports.Logger) {
+	select {
+	case p.send <- frame:
+	case <-p.done:
+	default:
+		logger.Warnf("P2P send queue full for peer %s, dropping message", p.id)
+	}
+}
+
+func (p *tcpPeer) close() {
+	p.once.Do(func() {
+		close(p.done)
+		_ = p.conn.Close()
+	})
+}
+
+func handshakeDigest(nonce string, pubKey string, chainID string) []byte {
+	sum := sha256.Sum256([]byte("xenium-handshake|" + chainID + "|" + nonce + "|" + pubKey))
+	return sum[:]
+}
+
+func encodeFrame(msgType string, payload any) ([]byte, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return encodeEnvelope(envelope{Type: msgType, Payload: raw})
+}
+
+func encodeEnvelope(env envelope) ([]byte, error) {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxFrameSize {
+		return nil, fmt.Errorf("message too large: %d bytes", len(body))
+	}
+	frame := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(frame[:4], uint32(len(body)))
+	copy(frame[4:], body)
+	return frame, nil
+}
+
+func writeMessage(w io.Writer, msgType string, payload any) error {
+	frame, err := encodeFrame(msgType, payload)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(frame)
+	return err
+}
+
+func readFrame(r io.Reader) (envelope, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return envelope{}, err
+	}
+	size := binary.BigEndian.Uint32(lenBuf[:])
+	if size > maxFrameSize {
+		return envelope{}, fmt.Errorf("frame too large: %d bytes", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return envelope{}, err
+	}
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return envelope{}, err
+	}
+	return env, nil
+}
+
+func readExpected(r io.Reader, msgType string, out any) error {
+	env, err := readFrame(r)
+	if err != nil {
+		return err
+	}
+	if env.Type != msgType {
+		return fmt.Errorf("%w: expected %s, got %s", ErrHandshake, msgType, env.Type)
+	}
+	return json.Unmarshal(env.Payload, out)
+}
+
+// seenCache remembers recently gossiped hashes in insertion order and
+// forgets the oldest once full.
+type seenCache struct {
+	mu    sync.Mutex
+	set   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newSeenCache(size int) *seenCache {
+	return &seenCache{set: make(map[string]struct{}, size), order: make([]string, size)}
+}
+
+func (c *seenCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.set[key]
+	return ok
+}
+
+// add records key and reports whether it was new.
+func (c *seenCache) add(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.set[key]; ok {
+		return false
+	}
+	if old := c.order[c.next]; old != "" {
+		delete(c.set, old)
+	}
+	c.order[c.next] = key
+	c.next = (c.next + 1) % len(c.order)
+	c.set[key] = struct{}{}
+	return true
+}
@@ -0,0 +1,225 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"xenium/consensus"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+type countingHandler struct {
+	mu  sync.Mutex
+	txs map[string]int
+}
+
+func (h *countingHandler) HandleBlock(peer string, block domain.Block) error {
+	return nil
+}
+
+func (h *countingHandler) HandleTx(peer string, tx domain.Transaction) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.txs[tx.Hash]++
+	return nil
+}
+
+func (h *countingHandler) count(hash string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.txs[hash]
+}
+
+func startTCPTestNode(t *testing.T) (*TCPNetwork, *countingHandler) {
+	t.Helper()
+	h := &countingHandler{txs: make(map[string]int)}
+	n, err := NewTCPNetwork(TCPConfig{ListenAddr: "127.0.0.1:0", ChainID: "tx-test", GenesisHash: "g", Handler: h, Logger: nopTestLogger{}})
+	if err != nil {
+		t.Fatalf("network: %v", err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(func() { n.Close() })
+	return n, h
+}
+
+type nopTestLogger struct{}
+
+func (nopTestLogger) Debugf(string, ...any)      {}
+func (nopTestLogger) Infof(string, ...any)       {}
+func (nopTestLogger) Warnf(string, ...any)       {}
+func (nopTestLogger) Errorf(string, ...any)      {}
+func (nopTestLogger) Criticalf(string, ...any)   {}
+func (l nopTestLogger) With(...any) ports.Logger { return l }
+
+func TestTCPTxAnnounceRequestDoesNotReflood(t *testing.T) {
+	a, _ := startTCPTestNode(t)
+	b, hb := startTCPTestNode(t)
+	c, hc := startTCPTestNode(t)
+	for _, pair := range [][2]*TCPNetwork{{b, a}, {c, a}, {c, b}} {
+		if _, err := pair[0].Connect(pair[1].Addr()); err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+	}
+	tx := domain.Transaction{Hash: "tx-1", To: "bob", Amount: 1}
+	if err := a.BroadcastTx(tx); err != nil {
+		t.Fatalf("broadcast: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for (hb.count(tx.Hash) == 0 || hc.count(tx.Hash) == 0) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if hb.count(tx.Hash) != 1 || hc.count(tx.Hash) != 1 {
+		t.Fatalf("expected each peer to handle the tx once, got b=%d c=%d", hb.count(tx.Hash), hc.count(tx.Hash))
+	}
+
+	// A peer connecting later hears about cached txs on connect.
+	d, hd := startTCPTestNode(t)
+	if _, err := d.Connect(b.Addr()); err != nil {
+		t.Fatalf("connect d: %v", err)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for hd.count(tx.Hash) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hd.count(tx.Hash) != 1 {
+		t.Fatalf("late peer did not receive announced tx")
+	}
+}
+
+// scriptedBlockHandler returns the queued errors for the first blocks it is
+// handed and accepts the rest.
+type scriptedBlockHandler struct {
+	countingHandler
+	errs  []error
+	calls int
+}
+
+func (h *scriptedBlockHandler) HandleBlock(peer string, block domain.Block) error {
+	h.calls++
+	if len(h.errs) == 0 {
+		return nil
+	}
+	err := h.errs[0]
+	h.errs = h.errs[1:]
+	return err
+}
+
+func TestTCPBlockSeenOnlyOnceAccepted(t *testing.T) {
+	h := &scriptedBlockHandler{errs: []error{errors.New("unknown parent hash")}}
+	n, err := NewTCPNetwork(TCPConfig{ListenAddr: "127.0.0.1:0", ChainID: "block-test", GenesisHash: "g", Handler: h, Logger: nopTestLogger{}})
+	if err != nil {
+		t.Fatalf("network: %v", err)
+	}
+	p := &tcpPeer{id: "peer"}
+	block := domain.Block{Index: 2, PrevHash: "parent", Slot: 2, Validator: "Alice"}
+	block.Hash = consensus.HashBlock(block.Index, block.PrevHash, block.Slot, block.Tick, block.Validator, block.TxRoot, block.StateRoot, block.PoHHash)
+	deliver := func(b domain.Block) error {
+		payload, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		return n.dispatch(p, envelope{Type: msgBlock, Payload: payload})
+	}
+
+	forged := block
+	forged.Validator = "Mallory"
+	if err := deliver(forged); !errors.Is(err, ports.ErrInvalidBlock) || h.calls != 0 {
+		t.Fatalf("expected a hash mismatch to be refused before the handler, got %v after %d calls", err, h.calls)
+	}
+	if err := deliver(block); err == nil {
+		t.Fatalf("expected the handler error")
+	}
+	if err := deliver(block); err != nil || h.calls != 2 {
+		t.Fatalf("refused block was not handled again: err=%v calls=%d", err, h.calls)
+	}
+	if err := deliver(block); err != nil || h.calls != 2 {
+		t.Fatalf("accepted block was handled again: err=%v calls=%d", err, h.calls)
+	}
+
+	h.errs = []error{ports.ErrKnown}
+	other := block
+	other.Slot = 3
+	other.Hash = consensus.HashBlock(other.Index, other.PrevHash, other.Slot, other.Tick, other.Validator, other.TxRoot, other.StateRoot, other.PoHHash)
+	if err := deliver(other); err != nil {
+		t.Fatalf("known block reported as an error: %v", err)
+	}
+	if err := deliver(other); err != nil || h.calls != 3 {
+		t.Fatalf("known block was handled again: err=%v calls=%d", err, h.calls)
+	}
+}
+
+func TestTCPAddrsOnlyAcceptedWhenAsked(t *testing.T) {
+	n, err := NewTCPNetwork(TCPConfig{ListenAddr: "127.0.0.1:0", ChainID: "addr-test", GenesisHash: "g", Handler: &countingHandler{}, Logger: nopTestLogger{}})
+	if err != nil {
+		t.Fatalf("network: %v", err)
+	}
+	p := &tcpPeer{id: "peer"}
+	payload, err := json.Marshal([]string{"10.0.0.1:9000"})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	addrs := envelope{Type: msgAddrs, Payload: payload}
+	if err := n.dispatch(p, addrs); !errors.Is(err, errSpam) {
+		t.Fatalf("expected unsolicited addrs to be spam, got %v", err)
+	}
+	if len(n.PeerManager().Addresses()) != 0 {
+		t.Fatalf("unsolicited addresses were stored")
+	}
+	p.addrsAsked = true
+	if err := n.dispatch(p, addrs); err != nil {
+		t.Fatalf("solicited addrs: %v", err)
+	}
+	if len(n.PeerManager().Addresses()) != 1 {
+		t.Fatalf("expected the solicited address to be stored, got %v", n.PeerManager().Addresses())
+	}
+	if err := n.dispatch(p, addrs); !errors.Is(err, errSpam) {
+		t.Fatalf("expected a second reply to be spam, got %v", err)
+	}
+}
+
+func TestTCPResponseOnlyAcceptedFromAskedPeer(t *testing.T) {
+	n, err := NewTCPNetwork(TCPConfig{ListenAddr: "127.0.0.1:0", ChainID: "res-test", GenesisHash: "g", Handler: &countingHandler{}, Logger: nopTestLogger{}})
+	if err != nil {
+		t.Fatalf("network: %v", err)
+	}
+	asked, other := &tcpPeer{id: "asked"}, &tcpPeer{id: "other"}
+	ch := make(chan envelope, 1)
+	n.pending[7] = pendingRequest{peer: asked, ch: ch}
+	res := envelope{Type: msgHeadersRes, ID: 7, Payload: []byte("[]")}
+	if err := n.dispatch(other, res); !errors.Is(err, errSpam) {
+		t.Fatalf("expected a response from another peer to be spam, got %v", err)
+	}
+	if len(ch) != 0 {
+		t.Fatalf("response from another peer was delivered")
+	}
+	if err := n.dispatch(asked, res); err != nil {
+		t.Fatalf("response from the asked peer: %v", err)
+	}
+	if len(ch) != 1 {
+		t.Fatalf("response from the asked peer was not delivered")
+	}
+	if err := n.dispatch(asked, res); !errors.Is(err, errMalformed) {
+		t.Fatalf("expected a repeated response to be unsolicited, got %v", err)
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	r := newRateLimiter(10, 10)
+	if !r.allow(10) {
+		t.Fatalf("burst should be available")
+	}
+	if r.allow(1) {
+		t.Fatalf("bucket should be empty")
+	}
+	r.last = r.last.Add(-500 * time.Millisecond)
+	if !r.allow(4) {
+		t.Fatalf("bucket should refill over time")
+	}
+}
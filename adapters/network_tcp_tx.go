@@ -0,0 +1,275 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"xenium/domain"
+	"xenium/ports"
+)
+
+// Transactions are gossiped by announcing hashes. A peer requests the
+// hashes it has not seen from the announcer, validates the bodies through
+// the handler and announces accepted ones onwards.
+const (
+	maxTxAnnounce     = 256
+	txCacheSize       = 4096
+	txRequestTimeout  = 5 * time.Second
+	txAnnouncePerSec  = 500
+	txRequestsPerSec  = 500
+	peerKnownTxsLimit = 4096
+)
+
+type txRequest struct {
+	peer string
+	at   time.Time
+}
+
+// BroadcastTx announces a locally accepted transaction to all peers.
+func (n *TCPNetwork) BroadcastTx(tx domain.Transaction) error {
+	n.seen.add(msgTx + ":" + tx.Hash)
+	n.txs.put(tx)
+	n.announceTxs([]string{tx.Hash}, "")
+	return nil
+}
+
+// AnnounceTxs announces transactions the node holds again, such as ones a
+// reorg returned to the mempool. Peers already known to have a hash are
+// skipped.
+func (n *TCPNetwork) AnnounceTxs(txs []domain.Transaction) error {
+	hashes := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		n.seen.add(msgTx + ":" + tx.Hash)
+		n.txs.put(tx)
+		hashes = append(hashes, tx.Hash)
+	}
+	n.announceTxs(hashes, "")
+	return nil
+}
+
+// announceTxs sends each peer the hashes it is not already known to have.
+func (n *TCPNetwork) announceTxs(hashes []string, except string) {
+	if len(hashes) == 0 {
+		return
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	for id, p := range n.peers {
+		if id == except {
+			continue
+		}
+		n.announceTo(p, hashes)
+	}
+}
+
+func (n *TCPNetwork) announceTo(p *tcpPeer, hashes []string) {
+	var fresh []string
+	for _, h := range hashes {
+		if p.knownTxs.add(h) {
+			fresh = append(fresh, h)
+		}
+	}
+	for len(fresh) > 0 {
+		batch := fresh
+		if len(batch) > maxTxAnnounce {
+			batch = batch[:maxTxAnnounce]
+		}
+		fresh = fresh[len(batch):]
+		frame, err := encodeFrame(msgTxAnnounce, batch)
+		if err != nil {
+			return
+		}
+		p.enqueue(frame, n.cfg.Logger)
+	}
+}
+
+func (n *TCPNetwork) handleTxAnnounce(p *tcpPeer, payload json.RawMessage) error {
+	var hashes []string
+	if err := json.Unmarshal(payload, &hashes); err != nil {
+		return malformed(err)
+	}
+	if len(hashes) > maxTxAnnounce || !p.announceLimit.allow(len(hashes)) {
+		return errSpam
+	}
+	now := time.Now()
+	var want []string
+	n.txMu.Lock()
+	if len(n.txRequested) > txCacheSize {
+		for h, req := range n.txRequested {
+			if now.Sub(req.at) >= txRequestTimeout {
+				delete(n.txRequested, h)
+			}
+		}
+	}
+	for _, h := range hashes {
+		p.knownTxs.add(h)
+		if n.seen.has(msgTx + ":" + h) {
+			continue
+		}
+		if req, ok := n.txRequested[h]; ok && now.Sub(req.at) < txRequestTimeout {
+			continue
+		}
+		n.txRequested[h] = txRequest{peer: p.id, at: now}
+		want = append(want, h)
+	}
+	n.txMu.Unlock()
+	if len(want) == 0 {
+		return nil
+	}
+	frame, err := encodeFrame(msgTxRequest, want)
+	if err != nil {
+		return err
+	}
+	p.enqueue(frame, n.cfg.Logger)
+	return nil
+}
+
+func (n *TCPNetwork) handleTxRequest(p *tcpPeer, payload json.RawMessage) error {
+	var hashes []string
+	if err := json.Unmarshal(payload, &hashes); err != nil {
+		return malformed(err)
+	}
+	if len(hashes) > maxTxAnnounce || !p.requestLimit.allow(len(hashes)) {
+		return errSpam
+	}
+	txs := make([]domain.Transaction, 0, len(hashes))
+	for _, h := range hashes {
+		if tx, ok := n.txs.get(h); ok {
+			txs = append(txs, tx)
+		}
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+	frame, err := encodeFrame(msgTxs, txs)
+	if err != nil {
+		return err
+	}
+	p.enqueue(frame, n.cfg.Logger)
+	return nil
+}
+
+// handleTxs accepts bodies we asked this peer for. Unrequested bodies are
+// treated as spam; accepted ones are announced to everyone else.
+func (n *TCPNetwork) handleTxs(p *tcpPeer, payload json.RawMessage) error {
+	var txs []domain.Transaction
+	if err := json.Unmarshal(payload, &txs); err != nil {
+		return malformed(err)
+	}
+	var accepted []string
+	var firstErr error
+	for _, tx := range txs {
+		n.txMu.Lock()
+		req, ok := n.txRequested[tx.Hash]
+		if ok && req.peer == p.id {
+			delete(n.txRequested, tx.Hash)
+		}
+		n.txMu.Unlock()
+		if !ok || req.peer != p.id {
+			if firstErr == nil {
+				firstErr = errSpam
+			}
+			continue
+		}
+		// Only remember the hash once the handler took the tx, so one refused
+		// for now, say for a nonce gap, can be fetched again later.
+		key := msgTx + ":" + tx.Hash
+		if n.seen.has(key) {
+			continue
+		}
+		if err := n.cfg.Handler.HandleTx(p.id, tx); err != nil {
+			if errors.Is(err, ports.ErrKnown) {
+				n.seen.add(key)
+				continue
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if !n.seen.add(key) {
+			continue
+		}
+		n.txs.put(tx)
+		accepted = append(accepted, tx.Hash)
+	}
+	n.announceTxs(accepted, p.id)
+	return firstErr
+}
+
+// txCache keeps the most recent transaction bodies so announced hashes
+// can be served on request.
+type txCache struct {
+	mu    sync.Mutex
+	byKey map[string]domain.Transaction
+	order []string
+	next  int
+}
+
+func newTxCache(size int) *txCache {
+	return &txCache{byKey: make(map[string]domain.Transaction, size), order: make([]string, size)}
+}
+
+func (c *txCache) put(tx domain.Transaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.byKey[tx.Hash]; ok {
+		return
+	}
+	if old := c.order[c.next]; old != "" {
+		delete(c.byKey, old)
+	}
+	c.order[c.next] = tx.Hash
+	c.next = (c.next + 1) % len(c.order)
+	c.byKey[tx.Hash] = tx
+}
+
+func (c *txCache) get(hash string) (domain.Transaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tx, ok := c.byKey[hash]
+	return tx, ok
+}
+
+func (c *txCache) hashes(max int) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]string, 0, max)
+	for i := 1; i <= len(c.order) && len(out) < max; i++ {
+		h := c.order[(c.next-i+len(c.order))%len(c.order)]
+		if h == "" {
+			break
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// rateLimiter is a token bucket refilled at rate tokens per second up to
+// burst. It is owned by a single read loop and not locked.
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate int, burst int) *rateLimiter {
+	return &rateLimiter{rate: float64(rate), burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+func (r *rateLimiter) allow(n int) bool {
+	now := time.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+	if r.tokens < float64(n) {
+		return false
+	}
+	r.tokens -= float64(n)
+	return true
+}
@@ -0,0 +1,526 @@
+package adapters
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Score changes applied for peer behaviour. A peer starts at zero and is
+// disconnected and banned once its score drops to BanThreshold. Scores are
+// kept per peer ID across reconnects, move one point back towards zero every
+// scoreDecayInterval and are forgotten ScoreTTL after the last change.
+const (
+	ScoreGoodBlock    = 1
+	ScoreInvalidTx    = -10
+	ScoreSpam         = -20
+	ScoreMalformed    = -25
+	ScoreInvalidBlock = -40
+	ScoreBadSignature = -50
+
+	maxPeerScore       = 100
+	maxExchangeLen     = 64
+	addressBook        = "peers.json"
+	scoreDecayInterval = time.Minute
+)
+
+type PeerManagerConfig struct {
+	// Path is the address book file; empty keeps the book in memory only.
+	Path         string
+	Bootnodes    []string
+	MaxInbound   int
+	MaxOutbound  int
+	BanThreshold int
+	BanDuration  time.Duration
+	ScoreTTL     time.Duration
+	// MaxAddresses caps the address book; the worst entries are evicted
+	// to make room.
+	MaxAddresses int
+}
+
+type AddressEntry struct {
+	Addr        string `json:"addr"`
+	ID          string `json:"id,omitempty"`
+	LastSeen    int64  `json:"last_seen,omitempty"`
+	LastAttempt int64  `json:"last_attempt,omitempty"`
+	Failures    int    `json:"failures,omitempty"`
+	BannedUntil int64  `json:"banned_until,omitempty"`
+	Score       int    `json:"score,omitempty"`
+	ScoreAt     int64  `json:"score_at,omitempty"`
+}
+
+type PeerScore struct {
+	ID       string
+	Addr     string
+	Score    int
+	Outbound bool
+}
+
+// PeerManager keeps the address book, connection limits and behaviour
+// scores for a TCPNetwork.
+type PeerManager struct {
+	mu        sync.Mutex
+	cfg       PeerManagerConfig
+	book      map[string]*AddressEntry
+	bans      map[string]int64
+	scores    map[string]scoreRecord
+	connected map[string]*PeerScore
+	dialing   map[string]bool
+	inbound   int
+	outbound  int
+	dirty     bool
+}
+
+func AddressBookPath(dataDir string) string {
+	if dataDir == "" {
+		return ""
+	}
+	return filepath.Join(dataDir, addressBook)
+}
+
+func NewPeerManager(cfg PeerManagerConfig) (*PeerManager, error) {
+	if cfg.MaxInbound <= 0 {
+		cfg.MaxInbound = 32
+	}
+	if cfg.MaxOutbound <= 0 {
+		cfg.MaxOutbound = 8
+	}
+	if cfg.BanThreshold >= 0 {
+		cfg.BanThreshold = -100
+	}
+	if cfg.BanDuration <= 0 {
+		cfg.BanDuration = 30 * time.Minute
+	}
+	if cfg.ScoreTTL <= 0 {
+		cfg.ScoreTTL = 24 * time.Hour
+	}
+	if cfg.MaxAddresses <= 0 {
+		cfg.MaxAddresses = 1024
+	}
+	m := &PeerManager{
+		cfg:       cfg,
+		book:      make(map[string]*AddressEntry),
+		bans:      make(map[string]int64),
+		scores:    make(map[string]scoreRecord),
+		connected: make(map[string]*PeerScore),
+		dialing:   make(map[string]bool),
+	}
+	if cfg.Path != "" {
+		data, err := os.ReadFile(cfg.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			var entries []AddressEntry
+			if err := json.Unmarshal(data, &entries); err != nil {
+				return nil, err
+			}
+			for i := range entries {
+				e := entries[i]
+				m.book[e.Addr] = &e
+				if e.ID != "" && e.BannedUntil > 0 {
+					m.bans[e.ID] = e.BannedUntil
+				}
+				if e.ID != "" && e.ScoreAt > 0 {
+					m.scores[e.ID] = scoreRecord{score: e.Score, at: e.ScoreAt}
+				}
+			}
+			for len(m.book) > cfg.MaxAddresses {
+				if !m.evictLocked() {
+					break
+				}
+			}
+		}
+	}
+	for _, addr := range cfg.Bootnodes {
+		m.addAddress(addr)
+	}
+	return m, nil
+}
+
+// AddAddresses records addresses learned from peer exchange.
+func (m *PeerManager) AddAddresses(addrs []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, addr := range addrs {
+		m.addAddress(addr)
+	}
+}
+
+func (m *PeerManager) addAddress(addr string) {
+	if addr == "" {
+		return
+	}
+	if _, ok := m.book[addr]; ok {
+		return
+	}
+	if len(m.book) >= m.cfg.MaxAddresses && !m.evictLocked() {
+		return
+	}
+	m.book[addr] = &AddressEntry{Addr: addr}
+	m.dirty = true
+}
+
+// evictLocked drops the worst address book entry that is not a bootnode,
+// connected or being dialed: banned entries first, then the most failed
+// dials, then the longest unseen. It reports false if nothing could go.
+func (m *PeerManager) evictLocked() bool {
+	now := time.Now().UnixNano()
+	keep := make(map[string]bool, len(m.cfg.Bootnodes)+len(m.connected))
+	for _, addr := range m.cfg.Bootnodes {
+		keep[addr] = true
+	}
+	for _, p := range m.connected {
+		keep[p.Addr] = true
+	}
+	var worst *AddressEntry
+	for _, e := range m.book {
+		if keep[e.Addr] || m.dialing[e.Addr] {
+			continue
+		}
+		if worst == nil || worseEntry(e, worst, now) {
+			worst = e
+		}
+	}
+	if worst == nil {
+		return false
+	}
+	delete(m.book, worst.Addr)
+	m.dirty = true
+	return true
+}
+
+func worseEntry(a *AddressEntry, b *AddressEntry, now int64) bool {
+	if aBanned, bBanned := a.BannedUntil > now, b.BannedUntil > now; aBanned != bBanned {
+		return aBanned
+	}
+	if a.Failures != b.Failures {
+		return a.Failures > b.Failures
+	}
+	if a.LastSeen != b.LastSeen {
+		return a.LastSeen < b.LastSeen
+	}
+	return a.Addr > b.Addr
+}
+
+// Admit reserves a connection slot for a handshaken peer. It fails for
+// banned peers and when the inbound or outbound limit is reached.
+func (m *PeerManager) Admit(id string, addr string, outbound bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UnixNano()
+	if m.bannedLocked(id, now) {
+		return false
+	}
+	if outbound && m.outbound >= m.cfg.MaxOutbound {
+		return false
+	}
+	if !outbound && m.inbound >= m.cfg.MaxInbound {
+		return false
+	}
+	if outbound {
+		m.outbound++
+	} else {
+		m.inbound++
+	}
+	m.connected[id] = &PeerScore{ID: id, Addr: addr, Outbound: outbound}
+	if addr != "" {
+		m.addAddress(addr)
+		if e, ok := m.book[addr]; ok {
+			e.ID = id
+			e.LastSeen = now
+			e.Failures = 0
+			m.dirty = true
+		}
+	}
+	return true
+}
+
+func (m *PeerManager) Release(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.connected[id]
+	if !ok {
+		return
+	}
+	delete(m.connected, id)
+	if p.Outbound {
+		m.outbound--
+	} else {
+		m.inbound--
+	}
+}
+
+// DialAttempt marks addr as being dialed and reports false if a dial to it
+// is already in flight.
+func (m *PeerManager) DialAttempt(addr string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dialing[addr] {
+		return false
+	}
+	m.dialing[addr] = true
+	m.addAddress(addr)
+	if e, ok := m.book[addr]; ok {
+		e.LastAttempt = time.Now().UnixNano()
+		m.dirty = true
+	}
+	return true
+}
+
+func (m *PeerManager) DialDone(addr string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.dialing, addr)
+	if e, ok := m.book[addr]; ok && err != nil {
+		e.Failures++
+		m.dirty = true
+	}
+}
+
+// Adjust changes a connected peer's score and reports whether the peer
+// crossed the ban threshold, in which case it is banned.
+func (m *PeerManager) Adjust(id string, delta int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.connected[id]; !ok {
+		return false
+	}
+	now := time.Now().UnixNano()
+	score := min(m.scoreLocked(id, now)+delta, maxPeerScore)
+	m.scores[id] = scoreRecord{score: score, at: now}
+	m.dirty = true
+	if score > m.cfg.BanThreshold {
+		return false
+	}
+	m.banLocked(id)
+	return true
+}
+
+type scoreRecord struct {
+	score int
+	at    int64
+}
+
+// scoreLocked returns id's score decayed to now.
+func (m *PeerManager) scoreLocked(id string, now int64) int {
+	r, ok := m.scores[id]
+	if !ok {
+		return 0
+	}
+	elapsed := time.Duration(now - r.at)
+	if elapsed >= m.cfg.ScoreTTL {
+		return 0
+	}
+	steps := int(elapsed / scoreDecayInterval)
+	if r.score > 0 {
+		return max(r.score-steps, 0)
+	}
+	return min(r.score+steps, 0)
+}
+
+func (m *PeerManager) Ban(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.banLocked(id)
+}
+
+func (m *PeerManager) banLocked(id string) {
+	until := time.Now().Add(m.cfg.BanDuration).UnixNano()
+	m.bans[id] = until
+	for _, e := range m.book {
+		if e.ID == id {
+			e.BannedUntil = until
+		}
+	}
+	m.dirty = true
+}
+
+func (m *PeerManager) IsBanned(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.bannedLocked(id, time.Now().UnixNano())
+}
+
+func (m *PeerManager) bannedLocked(id string, now int64) bool {
+	until, ok := m.bans[id]
+	if !ok {
+		return false
+	}
+	if now >= until {
+		delete(m.bans, id)
+		return false
+	}
+	return true
+}
+
+func (m *PeerManager) Score(id string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.scoreLocked(id, time.Now().UnixNano())
+}
+
+func (m *PeerManager) addrOf(id string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.connected[id]; ok {
+		return p.Addr
+	}
+	return ""
+}
+
+func (m *PeerManager) Scores() []PeerScore {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UnixNano()
+	out := make([]PeerScore, 0, len(m.connected))
+	for _, p := range m.connected {
+		score := *p
+		score.Score = m.scoreLocked(p.ID, now)
+		out = append(out, score)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// DialCandidates returns up to the number of free outbound slots worth of
+// addresses to dial: bootnodes first, then the address book ordered by
+// recency. Banned, connected and recently failed addresses are skipped;
+// the retry backoff doubles with every consecutive failure.
+func (m *PeerManager) DialCandidates(self string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	free := m.cfg.MaxOutbound - m.outbound
+	if free <= 0 {
+		return nil
+	}
+	now := time.Now().UnixNano()
+	connected := make(map[string]bool, len(m.connected))
+	for _, p := range m.connected {
+		connected[p.Addr] = true
+	}
+	usable := func(e *AddressEntry) bool {
+		if e.Addr == self || connected[e.Addr] || m.dialing[e.Addr] {
+			return false
+		}
+		if e.ID != "" && (m.connected[e.ID] != nil || m.bannedLocked(e.ID, now)) {
+			return false
+		}
+		if e.BannedUntil > now {
+			return false
+		}
+		if e.Failures > 0 {
+			shift := e.Failures
+			if shift > 8 {
+				shift = 8
+			}
+			backoff := int64(time.Second) << shift
+			if e.LastAttempt+backoff > now {
+				return false
+			}
+		}
+		return true
+	}
+	var out []string
+	seen := make(map[string]bool)
+	for _, addr := range m.cfg.Bootnodes {
+		if e := m.book[addr]; e != nil && !seen[addr] && usable(e) {
+			out = append(out, addr)
+			seen[addr] = true
+		}
+	}
+	rest := make([]*AddressEntry, 0, len(m.book))
+	for _, e := range m.book {
+		if !seen[e.Addr] && usable(e) {
+			rest = append(rest, e)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		if rest[i].LastSeen != rest[j].LastSeen {
+			return rest[i].LastSeen > rest[j].LastSeen
+		}
+		return rest[i].Addr < rest[j].Addr
+	})
+	for _, e := range rest {
+		out = append(out, e.Addr)
+	}
+	if len(out) > free {
+		out = out[:free]
+	}
+	return out
+}
+
+// ExchangeAddresses lists addresses worth sharing with other peers: ones we
+// have connected to before and that are not banned.
+func (m *PeerManager) ExchangeAddresses(except string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UnixNano()
+	entries := make([]*AddressEntry, 0, len(m.book))
+	for _, e := range m.book {
+		if e.LastSeen == 0 || e.Addr == except || e.BannedUntil > now {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastSeen > entries[j].LastSeen })
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if len(out) == maxExchangeLen {
+			break
+		}
+		out = append(out, e.Addr)
+	}
+	return out
+}
+
+func (m *PeerManager) Addresses() []AddressEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]AddressEntry, 0, len(m.book))
+	for _, e := range m.book {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Addr < out[j].Addr })
+	return out
+}
+
+// Save writes the address book if it changed since the last save.
+func (m *PeerManager) Save() error {
+	m.mu.Lock()
+	if m.cfg.Path == "" || !m.dirty {
+		m.mu.Unlock()
+		return nil
+	}
+	now := time.Now().UnixNano()
+	for id, r := range m.scores {
+		if time.Duration(now-r.at) >= m.cfg.ScoreTTL {
+			delete(m.scores, id)
+		}
+	}
+	entries := make([]AddressEntry, 0, len(m.book))
+	for _, e := range m.book {
+		entry := *e
+		entry.Score, entry.ScoreAt = 0, 0
+		if r, ok := m.scores[e.ID]; ok && e.ID != "" {
+			entry.Score, entry.ScoreAt = r.score, r.at
+		}
+		entries = append(entries, entry)
+	}
+	m.dirty = false
+	m.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Addr < entries[j].Addr })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.cfg.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.cfg.Path)
+}
@@ -0,0 +1,128 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPeerManagerScoringBansAndPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.json")
+	m, err := NewPeerManager(PeerManagerConfig{Path: path, Bootnodes: []string{"10.0.0.1:9000"}, MaxInbound: 1})
+	if err != nil {
+		t.Fatalf("peer manager: %v", err)
+	}
+	if got := m.DialCandidates(""); len(got) != 1 || got[0] != "10.0.0.1:9000" {
+		t.Fatalf("expected bootnode candidate, got %v", got)
+	}
+	if !m.Admit("peer-a", "10.0.0.2:9000", false) {
+		t.Fatalf("first inbound peer rejected")
+	}
+	if m.Admit("peer-b", "10.0.0.3:9000", false) {
+		t.Fatalf("inbound limit not enforced")
+	}
+	if m.Adjust("peer-a", ScoreInvalidBlock) || m.Adjust("peer-a", ScoreBadSignature) {
+		t.Fatalf("banned before reaching the threshold, score %d", m.Score("peer-a"))
+	}
+	if !m.Adjust("peer-a", ScoreSpam) || !m.IsBanned("peer-a") {
+		t.Fatalf("expected peer to be banned at score %d", m.Score("peer-a"))
+	}
+	m.Release("peer-a")
+	if m.Admit("peer-a", "10.0.0.2:9000", false) {
+		t.Fatalf("banned peer readmitted")
+	}
+	m.AddAddresses([]string{"10.0.0.4:9000"})
+	if err := m.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	reloaded, err := NewPeerManager(PeerManagerConfig{Path: path})
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(reloaded.Addresses()) != 3 {
+		t.Fatalf("expected 3 persisted addresses, got %+v", reloaded.Addresses())
+	}
+	if !reloaded.IsBanned("peer-a") {
+		t.Fatalf("ban not persisted")
+	}
+	for _, addr := range reloaded.DialCandidates("") {
+		if addr == "10.0.0.2:9000" {
+			t.Fatalf("banned address offered for dialing")
+		}
+	}
+}
+
+func TestPeerManagerScoreSurvivesReconnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.json")
+	m, err := NewPeerManager(PeerManagerConfig{Path: path})
+	if err != nil {
+		t.Fatalf("peer manager: %v", err)
+	}
+	m.Admit("peer-a", "10.0.0.2:9000", false)
+	m.Adjust("peer-a", ScoreInvalidBlock)
+	m.Adjust("peer-a", ScoreBadSignature)
+	m.Release("peer-a")
+	m.Admit("peer-a", "10.0.0.2:9000", false)
+	if got := m.Score("peer-a"); got != ScoreInvalidBlock+ScoreBadSignature {
+		t.Fatalf("score reset on reconnect to %d", got)
+	}
+	if !m.Adjust("peer-a", ScoreSpam) {
+		t.Fatalf("expected the reconnected peer to be banned at score %d", m.Score("peer-a"))
+	}
+
+	m.Admit("peer-b", "10.0.0.3:9000", false)
+	m.Adjust("peer-b", ScoreSpam)
+	if err := m.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	reloaded, err := NewPeerManager(PeerManagerConfig{Path: path})
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded.Score("peer-b"); got != ScoreSpam {
+		t.Fatalf("score not persisted, got %d", got)
+	}
+
+	r := reloaded.scores["peer-b"]
+	r.at -= int64(5 * scoreDecayInterval)
+	reloaded.scores["peer-b"] = r
+	if got := reloaded.Score("peer-b"); got != ScoreSpam+5 {
+		t.Fatalf("expected score to decay to %d, got %d", ScoreSpam+5, got)
+	}
+	r.at -= int64(24 * time.Hour)
+	reloaded.scores["peer-b"] = r
+	if got := reloaded.Score("peer-b"); got != 0 {
+		t.Fatalf("expected score to expire, got %d", got)
+	}
+}
+
+func TestPeerManagerAddressBookIsCapped(t *testing.T) {
+	m, err := NewPeerManager(PeerManagerConfig{Bootnodes: []string{"10.0.0.1:9000"}, MaxAddresses: 4})
+	if err != nil {
+		t.Fatalf("peer manager: %v", err)
+	}
+	m.Admit("peer-a", "10.0.0.2:9000", true)
+	m.DialAttempt("10.0.0.3:9000")
+	m.DialDone("10.0.0.3:9000", errors.New("refused"))
+	var gossip []string
+	for i := 0; i < 50; i++ {
+		gossip = append(gossip, fmt.Sprintf("10.1.0.%d:9000", i))
+	}
+	m.AddAddresses(gossip)
+	book := make(map[string]bool)
+	for _, e := range m.Addresses() {
+		book[e.Addr] = true
+	}
+	if len(book) != 4 {
+		t.Fatalf("address book grew to %d entries", len(book))
+	}
+	if !book["10.0.0.1:9000"] || !book["10.0.0.2:9000"] {
+		t.Fatalf("bootnode or connected peer evicted: %v", book)
+	}
+	if book["10.0.0.3:9000"] {
+		t.Fatalf("failing address kept over fresh ones: %v", book)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"xenium/domain"
@@ -16,10 +17,12 @@ type FileBlockStore struct {
 	dir          string
 	blocksPath   string
 	indexPath    string
+	anchorPath   string
 	blocks       map[string]domain.Block
 	heightToHash map[uint64]string
 	tipHash      string
 	tipHeight    uint64
+	readOnly     bool
 	mu           sync.RWMutex
 }
 
@@ -43,6 +46,7 @@ func NewFileBlockStore(dir string) (*FileBlockStore, error) {
 		dir:          dir,
 		blocksPath:   blocksPath,
 		indexPath:    indexPath,
+		anchorPath:   filepath.Join(dir, "anchor.json"),
 		blocks:       make(map[string]domain.Block),
 		heightToHash: make(map[uint64]string),
 	}
@@ -117,6 +121,62 @@ func (s *FileBlockStore) GetRange(startHeight uint64, endHeight uint64) ([]domai
 	return out, nil
 }
 
+func (s *FileBlockStore) Compact(anchor domain.PruneAnchor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		return ErrReadOnlyStore
+	}
+
+	for hash, b := range s.blocks {
+		if b.Index < anchor.Index || (b.Index == anchor.Index && hash != anchor.Hash) {
+			delete(s.blocks, hash)
+		}
+	}
+	for h := range s.heightToHash {
+		if h < anchor.Index {
+			delete(s.heightToHash, h)
+		}
+	}
+	s.heightToHash[anchor.Index] = anchor.Hash
+
+	data, err := json.Marshal(anchor)
+	if err != nil {
+		return err
+	}
+	tmp := s.anchorPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.anchorPath); err != nil {
+		return err
+	}
+	if err := s.rewriteBlocks(); err != nil {
+		return err
+	}
+	return s.writeIndex()
+}
+
+func (s *FileBlockStore) LoadPruneAnchor() (domain.PruneAnchor, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, err := os.ReadFile(s.anchorPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return domain.PruneAnchor{}, false, nil
+		}
+		return domain.PruneAnchor{}, false, err
+	}
+	var anchor domain.PruneAnchor
+	if err := json.Unmarshal(data, &anchor); err != nil {
+		return domain.PruneAnchor{}, false, err
+	}
+	if anchor.State == nil {
+		anchor.State = make(map[string]domain.Account)
+	}
+	return anchor, true, nil
+}
+
 func (s *FileBlockStore) load() error {
 	if err := s.loadIndex(); err != nil {
 		return err
@@ -125,6 +185,10 @@ func (s *FileBlockStore) load() error {
 		return err
 	}
 	if len(s.heightToHash) == 0 {
+		if s.readOnly {
+			s.indexFromTip()
+			return nil
+		}
 		if err := s.rebuildIndex(); err != nil {
 			return err
 		}
@@ -179,22 +243,47 @@ func (s *FileBlockStore) rebuildIndex() error {
 	if len(s.blocks) == 0 {
 		return nil
 	}
+	s.indexFromTip()
+	return s.writeIndex()
+}
+
+// indexFromTip rebuilds the in-memory index from the highest stored block.
+func (s *FileBlockStore) indexFromTip() {
+	if len(s.blocks) == 0 {
+		return
+	}
 	s.heightToHash = make(map[uint64]string)
-	var maxHeight uint64
-	var tipHash string
+	var tip domain.Block
+	found := false
 	for _, b := range s.blocks {
-		s.heightToHash[b.Index] = b.Hash
-		if b.Index >= maxHeight {
-			maxHeight = b.Index
-			tipHash = b.Hash
+		if !found || b.Index > tip.Index || (b.Index == tip.Index && b.Hash < tip.Hash) {
+			tip = b
+			found = true
 		}
 	}
-	s.tipHeight = maxHeight
-	s.tipHash = tipHash
-	return s.writeIndex()
+	// Walk back from the tip so forks stored at the same height do not
+	// shadow the chain the tip actually extends. Heights must drop on every
+	// step, which also stops corrupt files whose links form a cycle.
+	cur := tip
+	for {
+		s.heightToHash[cur.Index] = cur.Hash
+		if cur.PrevHash == "GENESIS" {
+			break
+		}
+		parent, ok := s.blocks[cur.PrevHash]
+		if !ok || parent.Index >= cur.Index {
+			break
+		}
+		cur = parent
+	}
+	s.tipHeight = tip.Index
+	s.tipHash = tip.Hash
 }
 
 func (s *FileBlockStore) appendBlock(block domain.Block) error {
+	if s.readOnly {
+		return ErrReadOnlyStore
+	}
 	f, err := os.OpenFile(s.blocksPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
@@ -211,7 +300,51 @@ func (s *FileBlockStore) appendBlock(block domain.Block) error {
 	return nil
 }
 
+func (s *FileBlockStore) rewriteBlocks() error {
+	if s.readOnly {
+		return ErrReadOnlyStore
+	}
+	blocks := make([]domain.Block, 0, len(s.blocks))
+	for _, b := range s.blocks {
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].Index != blocks[j].Index {
+			return blocks[i].Index < blocks[j].Index
+		}
+		return blocks[i].Hash < blocks[j].Hash
+	})
+	tmp := s.blocksPath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, b := range blocks {
+		data, err := json.Marshal(b)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.blocksPath)
+}
+
 func (s *FileBlockStore) writeIndex() error {
+	if s.readOnly {
+		return ErrReadOnlyStore
+	}
 	idx := blockIndex{
 		HeightToHash: s.heightToHash,
 		TipHash:      s.tipHash,
@@ -228,12 +361,44 @@ func (s *FileBlockStore) writeIndex() error {
 	return os.Rename(tmp, s.indexPath)
 }
 
+// Flush fsyncs the block log, the index and the data dir so that blocks
+// written so far survive a crash.
+func (s *FileBlockStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, path := range []string{s.blocksPath, s.indexPath, s.dir} {
+		if err := syncPath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
 type FileSnapshotStore struct {
-	dir string
-	mu  sync.RWMutex
+	dir      string
+	mu       sync.RWMutex
+	readOnly bool
 }
 
+// snapshotFileVersion is the epoch snapshot file format written by this
+// build. Files without a version predate it and are upgraded by the storage
+// schema v1 migration.
+const snapshotFileVersion = 1
+
 type snapshotFile struct {
+	Version      int               `json:"version"`
 	Epoch        uint64            `json:"epoch"`
 	StateRoot    string            `json:"state_root"`
 	ValidatorSet map[string]uint64 `json:"validator_set"`
@@ -253,8 +418,12 @@ func NewFileSnapshotStore(dir string) (*FileSnapshotStore, error) {
 func (s *FileSnapshotStore) SaveEpochSnapshot(epoch uint64, stateRoot string, validatorSet map[string]uint64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.readOnly {
+		return ErrReadOnlyStore
+	}
 	path := filepath.Join(s.dir, fmt.Sprintf("epoch_%d.json", epoch))
 	data, err := json.Marshal(snapshotFile{
+		Version:      snapshotFileVersion,
 		Epoch:        epoch,
 		StateRoot:    stateRoot,
 		ValidatorSet: validatorSet,
@@ -269,6 +438,12 @@ func (s *FileSnapshotStore) SaveEpochSnapshot(epoch uint64, stateRoot string, va
 	return os.Rename(tmp, path)
 }
 
+func (s *FileSnapshotStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return syncPath(s.dir)
+}
+
 func (s *FileSnapshotStore) LoadLatestSnapshot() (epoch uint64, stateRoot string, validatorSet map[string]uint64, ok bool, err error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -327,6 +502,9 @@ func (s *FileSnapshotStore) loadSnapshotFile(path string) (*snapshotFile, error)
 	if err := json.Unmarshal(data, &sf); err != nil {
 		return nil, err
 	}
+	if sf.Version != snapshotFileVersion {
+		return nil, fmt.Errorf("%s: snapshot format v%d, expected v%d", filepath.Base(path), sf.Version, snapshotFileVersion)
+	}
 	if sf.ValidatorSet == nil {
 		sf.ValidatorSet = make(map[string]uint64)
 	}
@@ -0,0 +1,279 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"xenium/ports"
+)
+
+// StorageSchemaVersion is the data dir layout written by this build.
+// Version 0 is the legacy layout without a metadata record; version 1 has
+// epoch snapshot files without a format version.
+const StorageSchemaVersion = 2
+
+const metaFileName = "meta.json"
+
+var ErrChainMismatch = errors.New("data dir belongs to a different chain")
+
+type StorageMeta struct {
+	SchemaVersion int    `json:"schema_version"`
+	ChainID       string `json:"chain_id"`
+	GenesisHash   string `json:"genesis_hash"`
+}
+
+type StorageMigration struct {
+	From        int
+	Description string
+	Apply       func(dir string, meta *StorageMeta) error
+}
+
+var storageMigrations = []StorageMigration{
+	{From: 0, Description: "add metadata record with genesis hash", Apply: migrateAddMeta},
+	{From: 1, Description: "add format version to epoch snapshot files", Apply: migrateVersionSnapshots},
+}
+
+// OpenDataDir checks the data dir metadata against the expected chain and
+// upgrades older layouts in place after taking a backup. Empty fields in
+// expect are not checked. A fresh dir is stamped with the current version.
+func OpenDataDir(dir string, expect StorageMeta, logger ports.Logger) (StorageMeta, error) {
+	if dir == "" {
+		return StorageMeta{}, errors.New("data dir required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return StorageMeta{}, err
+	}
+	meta, ok, err := ReadStorageMeta(dir)
+	if err != nil {
+		return StorageMeta{}, err
+	}
+	if !ok {
+		if !hasLegacyLayout(dir) {
+			meta = StorageMeta{SchemaVersion: StorageSchemaVersion, ChainID: expect.ChainID, GenesisHash: expect.GenesisHash}
+			return meta, WriteStorageMeta(dir, meta)
+		}
+		meta = StorageMeta{SchemaVersion: 0}
+	}
+	if meta.SchemaVersion > StorageSchemaVersion {
+		return meta, fmt.Errorf("data dir schema v%d is newer than supported v%d", meta.SchemaVersion, StorageSchemaVersion)
+	}
+	if meta.SchemaVersion < StorageSchemaVersion {
+		if err := migrateDataDir(dir, &meta, logger); err != nil {
+			return meta, err
+		}
+	}
+	changed := false
+	if meta.ChainID == "" && expect.ChainID != "" {
+		meta.ChainID = expect.ChainID
+		changed = true
+	}
+	if meta.GenesisHash == "" && expect.GenesisHash != "" {
+		meta.GenesisHash = expect.GenesisHash
+		changed = true
+	}
+	if expect.ChainID != "" && meta.ChainID != expect.ChainID {
+		return meta, fmt.Errorf("%w: chain id %q, expected %q", ErrChainMismatch, meta.ChainID, expect.ChainID)
+	}
+	if expect.GenesisHash != "" && meta.GenesisHash != expect.GenesisHash {
+		return meta, fmt.Errorf("%w: genesis %s, expected %s", ErrChainMismatch, meta.GenesisHash, expect.GenesisHash)
+	}
+	if changed {
+		return meta, WriteStorageMeta(dir, meta)
+	}
+	return meta, nil
+}
+
+func ReadStorageMeta(dir string) (StorageMeta, bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metaFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return StorageMeta{}, false, nil
+		}
+		return StorageMeta{}, false, err
+	}
+	var meta StorageMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return StorageMeta{}, false, err
+	}
+	return meta, true, nil
+}
+
+func WriteStorageMeta(dir string, meta StorageMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, metaFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// migrateDataDir runs every migration from meta.SchemaVersion up to the
+// current version. If one fails the data dir is restored from the backup
+// taken beforehand, which is kept either way.
+func migrateDataDir(dir string, meta *StorageMeta, logger ports.Logger) error {
+	from := *meta
+	backup, err := backupDataDir(dir, meta.SchemaVersion)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Storage schema v%d -> v%d, backup written to %s", meta.SchemaVersion, StorageSchemaVersion, backup)
+	if err := applyMigrations(dir, meta, logger); err != nil {
+		*meta = from
+		if rerr := restoreDataDir(dir, backup); rerr != nil {
+			return fmt.Errorf("%w; restoring %s from %s failed: %v", err, dir, backup, rerr)
+		}
+		logger.Warnf("Storage migration failed, data dir restored from %s", backup)
+		return err
+	}
+	return nil
+}
+
+func applyMigrations(dir string, meta *StorageMeta, logger ports.Logger) error {
+	for meta.SchemaVersion < StorageSchemaVersion {
+		var m *StorageMigration
+		for i := range storageMigrations {
+			if storageMigrations[i].From == meta.SchemaVersion {
+				m = &storageMigrations[i]
+				break
+			}
+		}
+		if m == nil {
+			return fmt.Errorf("no storage migration from schema v%d", meta.SchemaVersion)
+		}
+		if err := m.Apply(dir, meta); err != nil {
+			return fmt.Errorf("storage migration v%d (%s): %w", m.From, m.Description, err)
+		}
+		meta.SchemaVersion = m.From + 1
+		if err := WriteStorageMeta(dir, *meta); err != nil {
+			return err
+		}
+		logger.Infof("Storage migration applied v%d -> v%d: %s", m.From, meta.SchemaVersion, m.Description)
+	}
+	return nil
+}
+
+func hasLegacyLayout(dir string) bool {
+	for _, name := range []string{"blocks.jsonl", "index.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func restoreDataDir(dir string, backup string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return copyDir(backup, filepath.Clean(dir))
+}
+
+func backupDataDir(dir string, version int) (string, error) {
+	clean := filepath.Clean(dir)
+	base := clean + ".backup-v" + strconv.Itoa(version)
+	target := base
+	for i := 1; ; i++ {
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			break
+		}
+		target = base + "-" + strconv.Itoa(i)
+	}
+	return target, copyDir(clean, target)
+}
+
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func migrateAddMeta(dir string, meta *StorageMeta) error {
+	store, err := NewFileBlockStore(dir)
+	if err != nil {
+		return err
+	}
+	if genesis, ok := store.GetBlockByHeight(0); ok {
+		meta.GenesisHash = genesis.Hash
+	}
+	return nil
+}
+
+// migrateVersionSnapshots rewrites every epoch snapshot file with the
+// current format version.
+func migrateVersionSnapshots(dir string, meta *StorageMeta) error {
+	snapDir := filepath.Join(dir, "snapshots")
+	entries, err := os.ReadDir(snapDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		var epoch uint64
+		if _, err := fmt.Sscanf(e.Name(), "epoch_%d.json", &epoch); err != nil {
+			continue
+		}
+		path := filepath.Join(snapDir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var sf snapshotFile
+		if err := json.Unmarshal(data, &sf); err != nil {
+			return fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		if sf.Version != 0 || sf.Epoch != epoch {
+			return fmt.Errorf("%s: unexpected version %d or epoch %d", e.Name(), sf.Version, sf.Epoch)
+		}
+		sf.Version = snapshotFileVersion
+		if data, err = json.Marshal(sf); err != nil {
+			return err
+		}
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package adapters
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"xenium/domain"
+)
+
+func TestOpenDataDirMigratesLegacyLayout(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	store, err := NewFileBlockStore(dir)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	genesis := domain.Block{Index: 0, PrevHash: "GENESIS", Hash: "g0"}
+	if err := store.SaveBlock(genesis); err != nil {
+		t.Fatalf("save genesis: %v", err)
+	}
+
+	meta, err := OpenDataDir(dir, StorageMeta{ChainID: "test"}, StdLogger{})
+	if err != nil {
+		t.Fatalf("open data dir: %v", err)
+	}
+	if meta.SchemaVersion != StorageSchemaVersion || meta.GenesisHash != "g0" || meta.ChainID != "test" {
+		t.Fatalf("unexpected migrated meta: %+v", meta)
+	}
+	if _, err := os.Stat(filepath.Join(dir+".backup-v0", "blocks.jsonl")); err != nil {
+		t.Fatalf("expected backup of legacy layout: %v", err)
+	}
+
+	if _, err := OpenDataDir(dir, StorageMeta{ChainID: "other"}, StdLogger{}); !errors.Is(err, ErrChainMismatch) {
+		t.Fatalf("expected chain id mismatch, got %v", err)
+	}
+	if _, err := OpenDataDir(dir, StorageMeta{GenesisHash: "g1"}, StdLogger{}); !errors.Is(err, ErrChainMismatch) {
+		t.Fatalf("expected genesis mismatch, got %v", err)
+	}
+
+	meta.SchemaVersion = StorageSchemaVersion + 1
+	if err := WriteStorageMeta(dir, meta); err != nil {
+		t.Fatalf("write meta: %v", err)
+	}
+	if _, err := OpenDataDir(dir, StorageMeta{}, StdLogger{}); err == nil {
+		t.Fatalf("expected newer schema to be refused")
+	}
+}
+
+// writeV1DataDir lays out a schema v1 data dir: a metadata record, one block
+// and epoch snapshot files in the unversioned format.
+func writeV1DataDir(t *testing.T, dir string, snapshots map[string]string) {
+	t.Helper()
+	store, err := NewFileBlockStore(dir)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if err := store.SaveBlock(domain.Block{Index: 0, PrevHash: "GENESIS", Hash: "g0"}); err != nil {
+		t.Fatalf("save genesis: %v", err)
+	}
+	if err := WriteStorageMeta(dir, StorageMeta{SchemaVersion: 1, ChainID: "test", GenesisHash: "g0"}); err != nil {
+		t.Fatalf("write meta: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "snapshots"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for name, body := range snapshots {
+		if err := os.WriteFile(filepath.Join(dir, "snapshots", name), []byte(body), 0644); err != nil {
+			t.Fatalf("write snapshot: %v", err)
+		}
+	}
+}
+
+func TestOpenDataDirVersionsSnapshotFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	writeV1DataDir(t, dir, map[string]string{
+		"epoch_0.json": `{"epoch":0,"state_root":"r0","validator_set":{"Alice":100}}`,
+		"epoch_1.json": `{"epoch":1,"state_root":"r1","validator_set":{"Alice":101,"Bob":60}}`,
+	})
+	snaps, err := NewFileSnapshotStore(dir)
+	if err != nil {
+		t.Fatalf("snapshot store: %v", err)
+	}
+	if _, _, _, err := snaps.LoadSnapshotByEpoch(1); err == nil {
+		t.Fatalf("expected unversioned snapshot to be refused before migration")
+	}
+
+	meta, err := OpenDataDir(dir, StorageMeta{ChainID: "test"}, StdLogger{})
+	if err != nil {
+		t.Fatalf("open data dir: %v", err)
+	}
+	if meta.SchemaVersion != StorageSchemaVersion {
+		t.Fatalf("expected schema v%d, got %+v", StorageSchemaVersion, meta)
+	}
+	root, set, ok, err := snaps.LoadSnapshotByEpoch(1)
+	if err != nil || !ok || root != "r1" || set["Alice"] != 101 || set["Bob"] != 60 {
+		t.Fatalf("migrated snapshot: root=%q set=%v ok=%v err=%v", root, set, ok, err)
+	}
+	epoch, _, _, ok, err := snaps.LoadLatestSnapshot()
+	if err != nil || !ok || epoch != 1 {
+		t.Fatalf("latest snapshot after migration: epoch=%d ok=%v err=%v", epoch, ok, err)
+	}
+}
+
+func TestOpenDataDirRestoresBackupOnFailedMigration(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	writeV1DataDir(t, dir, map[string]string{
+		"epoch_0.json": `{"epoch":0,"state_root":"r0","validator_set":{"Alice":100}}`,
+		"epoch_1.json": `{"epoch":1,"state_root":`,
+	})
+	before := readTree(t, dir)
+
+	if _, err := OpenDataDir(dir, StorageMeta{ChainID: "test"}, StdLogger{}); err == nil {
+		t.Fatalf("expected migration of a corrupt snapshot to fail")
+	}
+	after := readTree(t, dir)
+	if len(after) != len(before) {
+		t.Fatalf("restored dir has %d files, want %d", len(after), len(before))
+	}
+	for name, data := range before {
+		if after[name] != data {
+			t.Fatalf("%s not restored:\n%s\nwant\n%s", name, after[name], data)
+		}
+	}
+	if _, err := os.Stat(dir + ".backup-v1"); err != nil {
+		t.Fatalf("expected backup to be kept: %v", err)
+	}
+}
+
+// readTree returns the contents of every file under dir by relative path.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk %s: %v", dir, err)
+	}
+	return files
+}
@@ -0,0 +1,245 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"xenium/domain"
+)
+
+var ErrReadOnlyStore = errors.New("block store is read-only")
+
+type BlockFileEntry struct {
+	Line  int
+	Block domain.Block
+	Err   error
+}
+
+type SnapshotInfo struct {
+	Epoch        uint64
+	StateRoot    string
+	ValidatorSet map[string]uint64
+}
+
+// OpenFileBlockStoreReadOnly opens an existing store for inspection. A missing
+// index is rebuilt in memory only, and every write fails with
+// ErrReadOnlyStore.
+func OpenFileBlockStoreReadOnly(dir string) (*FileBlockStore, error) {
+	if dir == "" {
+		return nil, errors.New("data dir required")
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	store := &FileBlockStore{
+		dir:          dir,
+		blocksPath:   filepath.Join(dir, "blocks.jsonl"),
+		indexPath:    filepath.Join(dir, "index.json"),
+		anchorPath:   filepath.Join(dir, "anchor.json"),
+		blocks:       make(map[string]domain.Block),
+		heightToHash: make(map[uint64]string),
+		readOnly:     true,
+	}
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// OpenFileSnapshotStoreReadOnly opens dir's epoch snapshots for reading
+// without creating the snapshots directory. Writes fail with ErrReadOnlyStore.
+func OpenFileSnapshotStoreReadOnly(dir string) (*FileSnapshotStore, error) {
+	if dir == "" {
+		return nil, errors.New("data dir required")
+	}
+	return &FileSnapshotStore{dir: filepath.Join(dir, "snapshots"), readOnly: true}, nil
+}
+
+// ScanBlockFile reads blocks.jsonl line by line without stopping at
+// undecodable lines, so corrupted stores can still be inspected.
+func ScanBlockFile(dir string) ([]BlockFileEntry, error) {
+	f, err := os.Open(filepath.Join(dir, "blocks.jsonl"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []BlockFileEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		entry := BlockFileEntry{Line: line}
+		if err := json.Unmarshal(scanner.Bytes(), &entry.Block); err != nil {
+			entry.Err = err
+		}
+		out = append(out, entry)
+	}
+	return out, scanner.Err()
+}
+
+// RepairBlockFile drops undecodable lines from blocks.jsonl and removes the
+// index so the next open rebuilds it. It returns the dropped line numbers.
+func RepairBlockFile(dir string) ([]int, error) {
+	entries, err := ScanBlockFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	var dropped []int
+	blocks := make(map[string]domain.Block, len(entries))
+	for _, e := range entries {
+		if e.Err != nil {
+			dropped = append(dropped, e.Line)
+			continue
+		}
+		blocks[e.Block.Hash] = e.Block
+	}
+	store := &FileBlockStore{
+		dir:        dir,
+		blocksPath: filepath.Join(dir, "blocks.jsonl"),
+		indexPath:  filepath.Join(dir, "index.json"),
+		blocks:     blocks,
+	}
+	if len(dropped) > 0 {
+		if err := store.rewriteBlocks(); err != nil {
+			return dropped, err
+		}
+	}
+	if err := os.Remove(store.indexPath); err != nil && !os.IsNotExist(err) {
+		return dropped, err
+	}
+	return dropped, nil
+}
+
+func (s *FileBlockStore) Blocks() []domain.Block {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]domain.Block, 0, len(s.blocks))
+	for _, b := range s.blocks {
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Index != out[j].Index {
+			return out[i].Index < out[j].Index
+		}
+		return out[i].Hash < out[j].Hash
+	})
+	return out
+}
+
+func (s *FileBlockStore) IndexedHeights() map[uint64]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[uint64]string, len(s.heightToHash))
+	for h, hash := range s.heightToHash {
+		out[h] = hash
+	}
+	return out
+}
+
+func (s *FileBlockStore) Reindex() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rebuildIndex()
+}
+
+// Rollback removes every block above height and moves the tip to the
+// indexed block at that height. It returns the number of blocks removed.
+func (s *FileBlockStore) Rollback(height uint64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		return 0, ErrReadOnlyStore
+	}
+	tipHash, ok := s.heightToHash[height]
+	if !ok {
+		return 0, fmt.Errorf("no indexed block at height %d", height)
+	}
+	if _, ok := s.blocks[tipHash]; !ok {
+		return 0, fmt.Errorf("missing block hash %s", tipHash)
+	}
+	removed := 0
+	for hash, b := range s.blocks {
+		if b.Index > height {
+			delete(s.blocks, hash)
+			removed++
+		}
+	}
+	for h := range s.heightToHash {
+		if h > height {
+			delete(s.heightToHash, h)
+		}
+	}
+	s.tipHash = tipHash
+	s.tipHeight = height
+	if err := s.rewriteBlocks(); err != nil {
+		return removed, err
+	}
+	return removed, s.writeIndex()
+}
+
+func (s *FileSnapshotStore) ListSnapshots() ([]SnapshotInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []SnapshotInfo
+	for _, e := range entries {
+		var ep uint64
+		if _, err := fmt.Sscanf(e.Name(), "epoch_%d.json", &ep); err != nil {
+			continue
+		}
+		sf, err := s.loadSnapshotFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		out = append(out, SnapshotInfo{Epoch: sf.Epoch, StateRoot: sf.StateRoot, ValidatorSet: sf.ValidatorSet})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Epoch < out[j].Epoch })
+	return out, nil
+}
+
+func (s *FileSnapshotStore) DeleteSnapshotsAfter(epoch uint64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		return 0, ErrReadOnlyStore
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	removed := 0
+	for _, e := range entries {
+		var ep uint64
+		if _, err := fmt.Sscanf(e.Name(), "epoch_%d.json", &ep); err != nil || ep <= epoch {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, e.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"xenium/core"
+	"xenium/domain"
+)
+
+const (
+	ArchiveFormat  = "xenium-archive"
+	ArchiveVersion = 1
+)
+
+type ArchiveHeader struct {
+	Format    string
+	Version   int
+	TipHash   string
+	TipHeight uint64
+	Blocks    int
+	Snapshots int
+}
+
+type archiveRecord struct {
+	Kind     string              `json:"kind"`
+	Header   *ArchiveHeader      `json:"header,omitempty"`
+	Genesis  *core.GenesisDoc    `json:"genesis,omitempty"`
+	Snapshot *core.EpochSnapshot `json:"snapshot,omitempty"`
+	Block    *domain.Block       `json:"block,omitempty"`
+}
+
+type ImportProgress struct {
+	Done     int
+	Total    int
+	Imported int
+	Skipped  int
+}
+
+type ImportResult struct {
+	Imported int
+	Skipped  int
+	TipHash  string
+}
+
+// ExportArchive writes a gzip-compressed JSON-lines archive: one header
+// record, the genesis document, every epoch snapshot, then every known block
+// (headers and bodies) ordered by slot so parents precede children.
+func ExportArchive(chain *core.Blockchain, w io.Writer) (ArchiveHeader, error) {
+	genesis, err := chain.GenesisDocument()
+	if err != nil {
+		return ArchiveHeader{}, err
+	}
+	if len(genesis.Validators) == 0 {
+		return ArchiveHeader{}, errors.New("genesis document has no validators")
+	}
+	snapshots := chain.GetAllEpochSnapshots()
+	all := chain.Blocks()
+	blocks := make([]domain.Block, 0, len(all))
+	for _, b := range all {
+		if b.Index == 0 {
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].Slot != blocks[j].Slot {
+			return blocks[i].Slot < blocks[j].Slot
+		}
+		if blocks[i].Index != blocks[j].Index {
+			return blocks[i].Index < blocks[j].Index
+		}
+		return blocks[i].Hash < blocks[j].Hash
+	})
+	tip := chain.Tip()
+	header := ArchiveHeader{
+		Format:    ArchiveFormat,
+		Version:   ArchiveVersion,
+		TipHash:   tip.Hash,
+		TipHeight: tip.Index,
+		Blocks:    len(blocks),
+		Snapshots: len(snapshots),
+	}
+
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(archiveRecord{Kind: "header", Header: &header}); err != nil {
+		return header, err
+	}
+	if err := enc.Encode(archiveRecord{Kind: "genesis", Genesis: &genesis}); err != nil {
+		return header, err
+	}
+	for i := range snapshots {
+		if err := enc.Encode(archiveRecord{Kind: "snapshot", Snapshot: &snapshots[i]}); err != nil {
+			return header, err
+		}
+	}
+	for i := range blocks {
+		if err := enc.Encode(archiveRecord{Kind: "block", Block: &blocks[i]}); err != nil {
+			return header, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return header, err
+	}
+	return header, nil
+}
+
+// ImportArchive replays an archive into chain through ImportBlock. Blocks the
+// chain already holds are skipped, so an interrupted import against a
+// persistent data dir resumes where it stopped. Epoch snapshots in the
+// archive are checked against the ones the chain takes, never installed.
+func ImportArchive(chain *core.Blockchain, r io.Reader, progress func(ImportProgress)) (ImportResult, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer gz.Close()
+	dec := json.NewDecoder(bufio.NewReader(gz))
+
+	var first archiveRecord
+	if err := dec.Decode(&first); err != nil {
+		return ImportResult{}, err
+	}
+	if first.Kind != "header" || first.Header == nil {
+		return ImportResult{}, errors.New("archive missing header")
+	}
+	header := *first.Header
+	if header.Format != ArchiveFormat {
+		return ImportResult{}, fmt.Errorf("unknown archive format %q", header.Format)
+	}
+	if header.Version != ArchiveVersion {
+		return ImportResult{}, fmt.Errorf("unsupported archive version %d", header.Version)
+	}
+
+	var result ImportResult
+	var snapshots []core.EpochSnapshot
+	state := ImportProgress{Total: header.Blocks}
+	for {
+		var rec archiveRecord
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return result, err
+		}
+		switch rec.Kind {
+		case "genesis":
+			if rec.Genesis == nil {
+				return result, errors.New("empty genesis record")
+			}
+			if err := chain.ApplyGenesis(*rec.Genesis); err != nil {
+				return result, err
+			}
+		case "snapshot":
+			if rec.Snapshot == nil {
+				return result, errors.New("empty snapshot record")
+			}
+			snapshots = append(snapshots, *rec.Snapshot)
+		case "block":
+			if rec.Block == nil {
+				return result, errors.New("empty block record")
+			}
+			err := chain.ImportBlock(*rec.Block)
+			switch {
+			case err == nil, errors.Is(err, core.ErrEquivocation):
+				state.Imported++
+			case errors.Is(err, core.ErrKnownBlock):
+				state.Skipped++
+			default:
+				return result, fmt.Errorf("block %d (%s): %w", rec.Block.Index, rec.Block.Hash, err)
+			}
+			state.Done++
+			if progress != nil {
+				progress(state)
+			}
+		default:
+			return result, fmt.Errorf("unknown archive record %q", rec.Kind)
+		}
+	}
+	result.Imported = state.Imported
+	result.Skipped = state.Skipped
+	result.TipHash = chain.CanonicalTipHash()
+	if state.Done != header.Blocks {
+		return result, fmt.Errorf("archive truncated: read %d of %d blocks", state.Done, header.Blocks)
+	}
+	// The chain took its own snapshots while importing; the archived ones
+	// only have to agree with them.
+	for _, snap := range snapshots {
+		if err := chain.CheckEpochSnapshot(snap); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -1,10 +1,38 @@
 package app
 
-import "xenium/core"
+import (
+	"time"
+
+	"xenium/adapters"
+	"xenium/core"
+)
 
 type Config struct {
 	Chain   core.ChainConfig
+	ChainID string
 	DataDir string
+	Network NetworkConfig
+	Node    ServicesConfig
+	Log     adapters.LogConfig
+}
+
+type NetworkConfig struct {
+	Bootnodes    []string
+	MaxInbound   int
+	MaxOutbound  int
+	DialInterval time.Duration
+}
+
+// ServicesConfig selects the services RegisterServices adds to a node.
+type ServicesConfig struct {
+	ListenAddr      string
+	RPCAddr         string
+	MetricsAddr     string
+	ExplorerAddr    string
+	TxIndex         bool
+	Produce         bool
+	JanitorInterval time.Duration
+	ShutdownTimeout time.Duration
 }
 
 func DefaultConfig() Config {
@@ -15,7 +43,25 @@ func DefaultConfig() Config {
 			MinReorgWeightDeltaP: 10,
 			EpochLength:          50,
 			MaxBlockTxs:          100,
+			PruneMode:            core.PruneArchive,
+			PruneKeepEpochs:      2,
 		},
+		ChainID: "xenium-local",
 		DataDir: "data",
+		Network: NetworkConfig{
+			MaxInbound:   32,
+			MaxOutbound:  8,
+			DialInterval: 5 * time.Second,
+		},
+		Node: ServicesConfig{
+			JanitorInterval: 10 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Log: adapters.LogConfig{
+			Level:      "info",
+			Format:     "json",
+			MaxSizeMB:  100,
+			MaxBackups: 3,
+		},
 	}
 }
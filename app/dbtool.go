@@ -0,0 +1,323 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"xenium/adapters"
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+)
+
+type DBStats struct {
+	DataDir        string `json:"data_dir"`
+	SchemaVersion  int    `json:"schema_version"`
+	ChainID        string `json:"chain_id"`
+	GenesisHash    string `json:"genesis_hash"`
+	Blocks         int    `json:"blocks"`
+	IndexedHeights int    `json:"indexed_heights"`
+	ForkBlocks     int    `json:"fork_blocks"`
+	TipHeight      uint64 `json:"tip_height"`
+	TipHash        string `json:"tip_hash"`
+	PrunedHeight   uint64 `json:"pruned_height"`
+	Snapshots      int    `json:"snapshots"`
+	BlocksBytes    int64  `json:"blocks_file_bytes"`
+}
+
+type DBIssue struct {
+	Check  string `json:"check"`
+	Height uint64 `json:"height,omitempty"`
+	Hash   string `json:"hash,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Error  string `json:"error"`
+}
+
+type DBCheckReport struct {
+	Blocks  int       `json:"blocks"`
+	Checked int       `json:"checked"`
+	OK      bool      `json:"ok"`
+	Issues  []DBIssue `json:"issues"`
+}
+
+type DBReindexReport struct {
+	DroppedLines []int  `json:"dropped_lines"`
+	Blocks       int    `json:"blocks"`
+	TipHeight    uint64 `json:"tip_height"`
+	TipHash      string `json:"tip_hash"`
+}
+
+type DBRollbackReport struct {
+	Height           uint64 `json:"height"`
+	TipHash          string `json:"tip_hash"`
+	RemovedBlocks    int    `json:"removed_blocks"`
+	RemovedSnapshots int    `json:"removed_snapshots"`
+}
+
+type DBSnapshot struct {
+	Epoch      uint64            `json:"epoch"`
+	StateRoot  string            `json:"state_root"`
+	TotalStake uint64            `json:"total_stake"`
+	Validators map[string]uint64 `json:"validators"`
+}
+
+func DBStatsFor(dir string) (DBStats, error) {
+	stats := DBStats{DataDir: dir}
+	meta, _, err := adapters.ReadStorageMeta(dir)
+	if err != nil {
+		return stats, err
+	}
+	stats.SchemaVersion = meta.SchemaVersion
+	stats.ChainID = meta.ChainID
+	stats.GenesisHash = meta.GenesisHash
+
+	store, err := adapters.OpenFileBlockStoreReadOnly(dir)
+	if err != nil {
+		return stats, err
+	}
+	blocks := store.Blocks()
+	heights := store.IndexedHeights()
+	indexed := make(map[string]bool, len(heights))
+	for _, hash := range heights {
+		indexed[hash] = true
+	}
+	stats.Blocks = len(blocks)
+	stats.IndexedHeights = len(heights)
+	stats.ForkBlocks = len(blocks) - len(indexed)
+	if tip, ok := store.GetTip(); ok {
+		stats.TipHeight = tip.Index
+		stats.TipHash = tip.Hash
+	}
+	if anchor, ok, err := store.LoadPruneAnchor(); err != nil {
+		return stats, err
+	} else if ok {
+		stats.PrunedHeight = anchor.Index
+	}
+	snapStore, err := adapters.NewFileSnapshotStore(dir)
+	if err != nil {
+		return stats, err
+	}
+	snaps, err := snapStore.ListSnapshots()
+	if err != nil {
+		return stats, err
+	}
+	stats.Snapshots = len(snaps)
+	if info, err := os.Stat(filepath.Join(dir, "blocks.jsonl")); err == nil {
+		stats.BlocksBytes = info.Size()
+	}
+	return stats, nil
+}
+
+// DBCheck verifies every stored block: hash, link to its parent and PoH
+// continuity, plus index consistency. It reads blocks.jsonl leniently so
+// corrupted lines are reported instead of aborting the check.
+func DBCheck(dir string) (DBCheckReport, error) {
+	report := DBCheckReport{Issues: []DBIssue{}}
+	entries, err := adapters.ScanBlockFile(dir)
+	if err != nil {
+		return report, err
+	}
+	blocks := make(map[string]domain.Block, len(entries))
+	for _, e := range entries {
+		if e.Err != nil {
+			report.Issues = append(report.Issues, DBIssue{Check: "decode", Line: e.Line, Error: e.Err.Error()})
+			continue
+		}
+		blocks[e.Block.Hash] = e.Block
+	}
+	report.Blocks = len(blocks)
+
+	anchorHash := ""
+	store, storeErr := adapters.OpenFileBlockStoreReadOnly(dir)
+	if storeErr == nil {
+		if anchor, ok, err := store.LoadPruneAnchor(); err == nil && ok {
+			anchorHash = anchor.Hash
+		}
+	}
+
+	for _, b := range blocks {
+		report.Checked++
+		if err := consensus.VerifyBlockHash(b); err != nil {
+			report.Issues = append(report.Issues, DBIssue{Check: "hash", Height: b.Index, Hash: b.Hash, Error: err.Error()})
+		}
+		if b.PrevHash == "GENESIS" || b.Hash == anchorHash {
+			continue
+		}
+		parent, ok := blocks[b.PrevHash]
+		if !ok {
+			report.Issues = append(report.Issues, DBIssue{Check: "link", Height: b.Index, Hash: b.Hash, Error: "missing parent " + b.PrevHash})
+			continue
+		}
+		if err := consensus.VerifyBlockLink(parent, b); err != nil {
+			report.Issues = append(report.Issues, DBIssue{Check: "link", Height: b.Index, Hash: b.Hash, Error: err.Error()})
+		}
+		if b.Index != parent.Index+1 {
+			report.Issues = append(report.Issues, DBIssue{Check: "link", Height: b.Index, Hash: b.Hash, Error: "index does not follow parent"})
+		}
+		parentPoH, err := consensus.ParsePoHHashHex(parent.PoHHash)
+		if err != nil {
+			report.Issues = append(report.Issues, DBIssue{Check: "poh", Height: parent.Index, Hash: parent.Hash, Error: err.Error()})
+			continue
+		}
+		if _, _, err := consensus.VerifyPoH(parentPoH, parent.Tick, b); err != nil {
+			report.Issues = append(report.Issues, DBIssue{Check: "poh", Height: b.Index, Hash: b.Hash, Error: err.Error()})
+		}
+	}
+
+	if storeErr != nil {
+		report.Issues = append(report.Issues, DBIssue{Check: "index", Error: storeErr.Error()})
+	} else {
+		for height, hash := range store.IndexedHeights() {
+			b, ok := blocks[hash]
+			if !ok {
+				report.Issues = append(report.Issues, DBIssue{Check: "index", Height: height, Hash: hash, Error: "indexed block missing"})
+				continue
+			}
+			if b.Index != height {
+				report.Issues = append(report.Issues, DBIssue{Check: "index", Height: height, Hash: hash, Error: "indexed at wrong height"})
+			}
+		}
+		if _, ok := store.GetTip(); !ok && len(blocks) > 0 {
+			report.Issues = append(report.Issues, DBIssue{Check: "index", Error: "tip missing"})
+		}
+	}
+	report.OK = len(report.Issues) == 0
+	return report, nil
+}
+
+func DBReindex(dir string) (DBReindexReport, error) {
+	dropped, err := adapters.RepairBlockFile(dir)
+	report := DBReindexReport{DroppedLines: dropped}
+	if err != nil {
+		return report, err
+	}
+	if report.DroppedLines == nil {
+		report.DroppedLines = []int{}
+	}
+	store, err := adapters.NewFileBlockStore(dir)
+	if err != nil {
+		return report, err
+	}
+	if err := store.Reindex(); err != nil {
+		return report, err
+	}
+	report.Blocks = len(store.Blocks())
+	if tip, ok := store.GetTip(); ok {
+		report.TipHeight = tip.Index
+		report.TipHash = tip.Hash
+	}
+	return report, nil
+}
+
+// DBGetBlock looks a block up by hash, or by height when ref is numeric.
+func DBGetBlock(dir string, ref string) (domain.Block, error) {
+	store, err := adapters.OpenFileBlockStoreReadOnly(dir)
+	if err != nil {
+		return domain.Block{}, err
+	}
+	if b, ok := store.GetBlockByHash(ref); ok {
+		return b, nil
+	}
+	if height, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		if b, ok := store.GetBlockByHeight(height); ok {
+			return b, nil
+		}
+	}
+	return domain.Block{}, fmt.Errorf("block %s not found", ref)
+}
+
+func DBRollback(dir string, height uint64, epochLength uint64) (DBRollbackReport, error) {
+	report := DBRollbackReport{Height: height}
+	store, err := adapters.NewFileBlockStore(dir)
+	if err != nil {
+		return report, err
+	}
+	if anchor, ok, err := store.LoadPruneAnchor(); err != nil {
+		return report, err
+	} else if ok && height < anchor.Index {
+		return report, fmt.Errorf("cannot roll back below prune anchor height %d", anchor.Index)
+	}
+	removed, err := store.Rollback(height)
+	report.RemovedBlocks = removed
+	if err != nil {
+		return report, err
+	}
+	tip, _ := store.GetTip()
+	report.TipHash = tip.Hash
+	if epochLength > 0 {
+		snapStore, err := adapters.NewFileSnapshotStore(dir)
+		if err != nil {
+			return report, err
+		}
+		report.RemovedSnapshots, err = snapStore.DeleteSnapshotsAfter(tip.Slot / epochLength)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func DBSnapshots(dir string) ([]DBSnapshot, error) {
+	snapStore, err := adapters.OpenFileSnapshotStoreReadOnly(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := snapStore.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DBSnapshot, 0, len(infos))
+	for _, info := range infos {
+		snap := DBSnapshot{Epoch: info.Epoch, StateRoot: info.StateRoot, Validators: info.ValidatorSet}
+		for _, stake := range info.ValidatorSet {
+			snap.TotalStake += stake
+		}
+		out = append(out, snap)
+	}
+	return out, nil
+}
+
+// DBBlockTree builds the block tree from every block in the data dir, forks
+// included, without opening a node. Blocks are weighted by the latest stored
+// snapshot at or before their epoch, and the finalized slot is the one the
+// chain derives from the indexed tip.
+func DBBlockTree(dir string, chain core.ChainConfig) (core.BlockTree, error) {
+	store, err := adapters.OpenFileBlockStoreReadOnly(dir)
+	if err != nil {
+		return core.BlockTree{}, err
+	}
+	snapStore, err := adapters.OpenFileSnapshotStoreReadOnly(dir)
+	if err != nil {
+		return core.BlockTree{}, err
+	}
+	snaps, err := snapStore.ListSnapshots()
+	if err != nil {
+		return core.BlockTree{}, err
+	}
+	var anchor *domain.PruneAnchor
+	if a, ok, err := store.LoadPruneAnchor(); err != nil {
+		return core.BlockTree{}, err
+	} else if ok {
+		anchor = &a
+	}
+	tip, _ := store.GetTip()
+	var finalized uint64
+	if tip.Slot >= chain.FinalitySlots {
+		finalized = tip.Slot - chain.FinalitySlots
+	}
+	stake := func(slot uint64, validator string) uint64 {
+		var epoch uint64
+		if chain.EpochLength > 0 {
+			epoch = slot / chain.EpochLength
+		}
+		i := sort.Search(len(snaps), func(i int) bool { return snaps[i].Epoch > epoch })
+		if i == 0 {
+			return 0
+		}
+		return snaps[i-1].ValidatorSet[validator]
+	}
+	return core.NewBlockTree(store.Blocks(), tip.Hash, finalized, anchor, stake), nil
+}
@@ -0,0 +1,268 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"xenium/adapters"
+	"xenium/core"
+	"xenium/domain"
+)
+
+// newDBTestDir writes a data dir holding a five block chain plus a fork block
+// at height 3, which the file store indexes over the canonical one. It
+// returns the canonical chain.
+func newDBTestDir(t *testing.T, dir string) []domain.Block {
+	t.Helper()
+	cfg := DefaultConfig().Chain
+	cfg.DeterministicPoH = true
+	cfg.PoHSeed = 11
+	cfg.EpochLength = 1
+	chain := core.NewBlockchain(cfg, nil, nil)
+	store, err := adapters.NewFileBlockStore(dir)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	snaps, err := adapters.NewFileSnapshotStore(dir)
+	if err != nil {
+		t.Fatalf("open snapshots: %v", err)
+	}
+	chain.SetStorage(store, snaps)
+	if err := chain.RestoreFromStorage(store, snaps); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	alice, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	if err := chain.AddValidator("Alice", 100, alice.PublicKey, alice.PrivateKey); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := chain.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	canonical := chain.CanonicalChain()
+	if _, err := chain.AddBlockExternal(canonical[2].Hash, nil); err != nil {
+		t.Fatalf("add fork block: %v", err)
+	}
+	if tip := chain.CanonicalTipHash(); tip != canonical[5].Hash {
+		t.Fatalf("fork block took over the tip")
+	}
+	return canonical
+}
+
+func truncateBlockFile(t *testing.T, dir string) {
+	t.Helper()
+	path := filepath.Join(dir, "blocks.jsonl")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if err := os.Truncate(path, info.Size()-20); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+}
+
+func TestDBCheckAndReindex(t *testing.T) {
+	cases := []struct {
+		name        string
+		edit        func(t *testing.T, dir string)
+		checkOK     bool
+		dropped     int
+		blocksAfter int
+	}{
+		{name: "clean", edit: func(*testing.T, string) {}, checkOK: true, blocksAfter: 7},
+		{name: "truncated line", edit: truncateBlockFile, dropped: 1, blocksAfter: 6},
+		{name: "missing index", edit: func(t *testing.T, dir string) {
+			if err := os.Remove(filepath.Join(dir, "index.json")); err != nil {
+				t.Fatalf("remove index: %v", err)
+			}
+		}, checkOK: true, blocksAfter: 7},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			canonical := newDBTestDir(t, dir)
+			tc.edit(t, dir)
+			before := readFiles(t, dir)
+
+			report, err := DBCheck(dir)
+			if err != nil {
+				t.Fatalf("check: %v", err)
+			}
+			if report.OK != tc.checkOK {
+				t.Fatalf("check ok=%v, want %v: %+v", report.OK, tc.checkOK, report.Issues)
+			}
+			after := readFiles(t, dir)
+			if len(after) != len(before) {
+				t.Fatalf("check changed the data dir: %d files, had %d", len(after), len(before))
+			}
+			for name, data := range before {
+				if after[name] != data {
+					t.Fatalf("check rewrote %s", name)
+				}
+			}
+
+			reindexed, err := DBReindex(dir)
+			if err != nil {
+				t.Fatalf("reindex: %v", err)
+			}
+			if len(reindexed.DroppedLines) != tc.dropped || reindexed.Blocks != tc.blocksAfter {
+				t.Fatalf("reindex dropped %v and kept %d blocks, want %d and %d", reindexed.DroppedLines, reindexed.Blocks, tc.dropped, tc.blocksAfter)
+			}
+			if reindexed.TipHash != canonical[5].Hash {
+				t.Fatalf("reindexed tip %s, want %s", reindexed.TipHash, canonical[5].Hash)
+			}
+			// The fork block shares height 3 with the canonical one; the
+			// rebuilt index must follow the chain the tip extends.
+			for _, b := range canonical {
+				got, err := DBGetBlock(dir, b.Hash)
+				if err != nil {
+					t.Fatalf("get block %d: %v", b.Index, err)
+				}
+				byHeight, err := DBGetBlock(dir, strconv.FormatUint(b.Index, 10))
+				if err != nil || byHeight.Hash != got.Hash {
+					t.Fatalf("height %d indexed as %s, want %s (%v)", b.Index, byHeight.Hash, b.Hash, err)
+				}
+			}
+			if report, err := DBCheck(dir); err != nil || !report.OK {
+				t.Fatalf("check after reindex: ok=%v err=%v issues=%+v", report.OK, err, report.Issues)
+			}
+		})
+	}
+}
+
+func TestDBRollback(t *testing.T) {
+	cases := []struct {
+		name    string
+		height  uint64
+		prune   bool
+		wantErr bool
+		removed int
+	}{
+		{name: "drops fork and canonical blocks", height: 2, removed: 4},
+		{name: "keeps fork below height", height: 4, removed: 1},
+		{name: "unknown height", height: 9, wantErr: true},
+		{name: "past prune anchor", height: 2, prune: true, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			canonical := newDBTestDir(t, dir)
+			if tc.prune {
+				store, err := adapters.NewFileBlockStore(dir)
+				if err != nil {
+					t.Fatalf("open store: %v", err)
+				}
+				anchor := canonical[3]
+				if err := store.Compact(domain.PruneAnchor{Hash: anchor.Hash, Index: anchor.Index, Slot: anchor.Slot}); err != nil {
+					t.Fatalf("compact: %v", err)
+				}
+			}
+			before := readFiles(t, dir)
+
+			report, err := DBRollback(dir, tc.height, 1)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected rollback to %d to fail", tc.height)
+				}
+				after := readFiles(t, dir)
+				for name, data := range before {
+					if after[name] != data {
+						t.Fatalf("failed rollback rewrote %s", name)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rollback: %v", err)
+			}
+			tip := canonical[tc.height]
+			if report.RemovedSnapshots == 0 {
+				t.Fatalf("expected snapshots past the new tip to be removed")
+			}
+			if report.RemovedBlocks != tc.removed || report.TipHash != tip.Hash {
+				t.Fatalf("rollback removed %d blocks to tip %s, want %d and %s", report.RemovedBlocks, report.TipHash, tc.removed, tip.Hash)
+			}
+			snaps, err := DBSnapshots(dir)
+			if err != nil {
+				t.Fatalf("snapshots: %v", err)
+			}
+			for _, snap := range snaps {
+				if snap.Epoch > tip.Slot {
+					t.Fatalf("snapshot for epoch %d kept past tip slot %d", snap.Epoch, tip.Slot)
+				}
+			}
+			if report, err := DBCheck(dir); err != nil || !report.OK {
+				t.Fatalf("check after rollback: ok=%v err=%v issues=%+v", report.OK, err, report.Issues)
+			}
+		})
+	}
+}
+
+func TestDBBlockTree(t *testing.T) {
+	dir := t.TempDir()
+	canonical := newDBTestDir(t, dir)
+	before := readFiles(t, dir)
+	cfg := DefaultConfig().Chain
+	cfg.EpochLength = 1
+
+	tree, err := DBBlockTree(dir, cfg)
+	if err != nil {
+		t.Fatalf("block tree: %v", err)
+	}
+	after := readFiles(t, dir)
+	if len(after) != len(before) {
+		t.Fatalf("block tree changed the data dir: %d files, had %d", len(after), len(before))
+	}
+	for name, data := range before {
+		if after[name] != data {
+			t.Fatalf("block tree rewrote %s", name)
+		}
+	}
+	tip := canonical[len(canonical)-1]
+	if tree.CanonicalTip != tip.Hash || tree.FinalizedSlot != tip.Slot-cfg.FinalitySlots {
+		t.Fatalf("tree tip %s finalized at %d, want %s and %d", tree.CanonicalTip, tree.FinalizedSlot, tip.Hash, tip.Slot-cfg.FinalitySlots)
+	}
+	var fork *core.BlockTreeNode
+	canonicalNodes := 0
+	for i, n := range tree.Nodes {
+		if n.Canonical {
+			canonicalNodes++
+		} else {
+			fork = &tree.Nodes[i]
+		}
+	}
+	if len(tree.Nodes) != len(canonical)+1 || canonicalNodes != len(canonical) {
+		t.Fatalf("tree has %d nodes, %d canonical; want %d and %d", len(tree.Nodes), canonicalNodes, len(canonical)+1, len(canonical))
+	}
+	if fork == nil || fork.Parent != canonical[2].Hash || !fork.Tip || fork.CumulativeWeight == 0 {
+		t.Fatalf("unexpected fork node %+v", fork)
+	}
+}
+
+// readFiles returns the contents of every file under dir by relative path.
+func readFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk %s: %v", dir, err)
+	}
+	return files
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"xenium/adapters"
+	"xenium/core"
+)
+
+const genesisFileName = "genesis.json"
+
+func LoadGenesisFile(path string) (core.GenesisDoc, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return core.GenesisDoc{}, false, nil
+		}
+		return core.GenesisDoc{}, false, err
+	}
+	var doc core.GenesisDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return core.GenesisDoc{}, false, err
+	}
+	return doc, true, nil
+}
+
+func SaveGenesisFile(path string, doc core.GenesisDoc) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SaveGenesis writes the chain's genesis document into the data dir so the
+// node can restore validators and balances on restart, and stamps the
+// genesis hash into the storage metadata.
+func (n *Node) SaveGenesis() error {
+	if n.DataDir == "" {
+		return errors.New("data dir required")
+	}
+	doc, err := n.Chain.GenesisDocument()
+	if err != nil {
+		return err
+	}
+	if n.Meta.GenesisHash != "" && n.Meta.GenesisHash != doc.Block.Hash {
+		return fmt.Errorf("%w: genesis %s, data dir has %s", adapters.ErrChainMismatch, doc.Block.Hash, n.Meta.GenesisHash)
+	}
+	if err := SaveGenesisFile(filepath.Join(n.DataDir, genesisFileName), doc); err != nil {
+		return err
+	}
+	if n.Meta.GenesisHash == "" {
+		n.Meta.GenesisHash = doc.Block.Hash
+		return adapters.WriteStorageMeta(n.DataDir, n.Meta)
+	}
+	return nil
+}
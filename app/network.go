@@ -0,0 +1,248 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"xenium/adapters"
+	"xenium/chainsync"
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+// ChainHandler delivers gossip into the chain through ImportBlock and the
+// mempool, and serves sync requests from it. The chain does its own
+// locking; the handler's lock only guards the sync trigger.
+type ChainHandler struct {
+	mu       sync.Mutex
+	chain    *core.Blockchain
+	onBehind func()
+}
+
+func NewChainHandler(chain *core.Blockchain) *ChainHandler {
+	return &ChainHandler{chain: chain}
+}
+
+// HandleBlock imports a gossiped block. Rejections that prove the block is
+// bad are wrapped in the ports errors so the transport can score the peer.
+func (h *ChainHandler) HandleBlock(peer string, block domain.Block) error {
+	v, ok := h.chain.Validator(block.Validator)
+	if !ok {
+		return fmt.Errorf("%w: unknown validator %s", ports.ErrInvalidBlock, block.Validator)
+	}
+	if err := consensus.VerifyBlockSignature(block, v.PubKey); err != nil {
+		return fmt.Errorf("%w: %v", ports.ErrBadSignature, err)
+	}
+	err := h.chain.ImportBlock(block)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, core.ErrEquivocation):
+		return nil
+	case errors.Is(err, core.ErrUnknownParent):
+		h.mu.Lock()
+		onBehind := h.onBehind
+		h.mu.Unlock()
+		if onBehind != nil {
+			onBehind()
+		}
+		return err
+	case errors.Is(err, core.ErrKnownBlock):
+		return fmt.Errorf("%w: %w", ports.ErrKnown, err)
+	case errors.Is(err, core.ErrPruned):
+		return err
+	}
+	return fmt.Errorf("%w: %v", ports.ErrInvalidBlock, err)
+}
+
+func (h *ChainHandler) HandleTx(peer string, tx domain.Transaction) error {
+	if tx.Hash == "" {
+		return fmt.Errorf("%w: missing hash", ports.ErrInvalidTx)
+	}
+	if err := consensus.VerifyTransactionSignature(tx); err != nil {
+		return fmt.Errorf("%w: %v", ports.ErrBadSignature, err)
+	}
+	err := h.chain.AddTx(tx)
+	if errors.Is(err, core.ErrDuplicateTx) {
+		return fmt.Errorf("%w: %w", ports.ErrKnown, err)
+	}
+	return err
+}
+
+func (h *ChainHandler) ChainStatus() ports.ChainStatus {
+	tip := h.chain.Tip()
+	status := ports.ChainStatus{
+		TipHash:   tip.Hash,
+		TipHeight: tip.Index,
+		TipSlot:   tip.Slot,
+		Weight:    h.chain.ScoreTip(tip.Hash).CumulativeWeight,
+	}
+	if genesis, err := h.chain.GenesisDocument(); err == nil {
+		status.GenesisHash = genesis.Block.Hash
+	}
+	return status
+}
+
+func (h *ChainHandler) HeadersFrom(height uint64, count int) ([]domain.BlockHeader, error) {
+	blocks, err := h.chain.CanonicalRange(height, count)
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]domain.BlockHeader, 0, len(blocks))
+	for _, block := range blocks {
+		headers = append(headers, block.Header())
+	}
+	return headers, nil
+}
+
+func (h *ChainHandler) Bodies(hashes []string) ([][]domain.Transaction, error) {
+	bodies := make([][]domain.Transaction, 0, len(hashes))
+	for _, hash := range hashes {
+		block, err := h.chain.GetBlockByHash(hash)
+		if err != nil {
+			return nil, fmt.Errorf("unknown block %s", hash)
+		}
+		bodies = append(bodies, block.Transactions)
+	}
+	return bodies, nil
+}
+
+func (h *ChainHandler) HasBlock(hash string) bool {
+	return h.chain.HasBlock(hash)
+}
+
+func (h *ChainHandler) Header(hash string) (domain.BlockHeader, bool) {
+	block, err := h.chain.GetBlockByHash(hash)
+	return block.Header(), err == nil
+}
+
+func (h *ChainHandler) ValidatorPubKey(name string) (string, bool) {
+	v, ok := h.chain.Validator(name)
+	if !ok {
+		return "", false
+	}
+	return v.PubKey, true
+}
+
+func (h *ChainHandler) LeaderForSlot(slot uint64) (string, bool) {
+	snap, ok := h.chain.PeekEpochSnapshot(slot)
+	if !ok {
+		return "", false
+	}
+	return consensus.LeaderFromSnapshot(slot, snap.Validators), true
+}
+
+func (h *ChainHandler) ImportBlock(block domain.Block) error {
+	err := h.chain.ImportBlock(block)
+	if errors.Is(err, core.ErrEquivocation) || errors.Is(err, core.ErrKnownBlock) {
+		return nil
+	}
+	return err
+}
+
+// StartNetwork listens on listenAddr and syncs with peers until ctx is
+// cancelled or the node is closed.
+func (n *Node) StartNetwork(ctx context.Context, listenAddr string) error {
+	if n.Network != nil {
+		return errors.New("network already started")
+	}
+	genesis, err := n.Chain.GenesisDocument()
+	if err != nil {
+		return err
+	}
+	peers, err := adapters.NewPeerManager(adapters.PeerManagerConfig{
+		Path:        adapters.AddressBookPath(n.DataDir),
+		Bootnodes:   n.NetConfig.Bootnodes,
+		MaxInbound:  n.NetConfig.MaxInbound,
+		MaxOutbound: n.NetConfig.MaxOutbound,
+	})
+	if err != nil {
+		return err
+	}
+	network, err := adapters.NewTCPNetwork(adapters.TCPConfig{
+		ListenAddr:   listenAddr,
+		ChainID:      n.ChainID,
+		GenesisHash:  genesis.Block.Hash,
+		Handler:      n.Handler,
+		Logger:       n.moduleLogger(ports.ModuleNetwork),
+		Peers:        peers,
+		DialInterval: n.NetConfig.DialInterval,
+	})
+	if err != nil {
+		return err
+	}
+	if err := network.Start(); err != nil {
+		return err
+	}
+	n.Network = network
+	n.announceSub = n.Chain.Subscribe(announceRequeued(network))
+	n.Sync = chainsync.New(chainsync.DefaultConfig(), n.Handler, network, n.moduleLogger(ports.ModuleSync))
+	n.Handler.mu.Lock()
+	n.Handler.onBehind = n.Sync.Trigger
+	n.Handler.mu.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	n.stopSync = cancel
+	n.syncDone = make(chan struct{})
+	go func() {
+		defer close(n.syncDone)
+		n.Sync.Run(ctx)
+	}()
+	return nil
+}
+
+// ProduceBlock builds the next local block and gossips it to peers.
+func (n *Node) ProduceBlock(txs []domain.Transaction) (domain.Block, error) {
+	before := n.Chain.CanonicalTipHash()
+	if err := n.Chain.AddBlock(txs); err != nil && !errors.Is(err, core.ErrEquivocation) {
+		return domain.Block{}, err
+	}
+	block := n.Chain.Tip()
+	if block.Hash == before {
+		return domain.Block{}, errors.New("produced block did not become canonical")
+	}
+	if n.Network != nil {
+		if err := n.Network.BroadcastBlock(block); err != nil {
+			return block, err
+		}
+	}
+	return block, nil
+}
+
+// SubmitTx adds a local transaction to the mempool and announces it.
+func (n *Node) SubmitTx(tx domain.Transaction) error {
+	if err := n.Handler.HandleTx("", tx); err != nil {
+		return err
+	}
+	if n.Network != nil {
+		return n.Network.BroadcastTx(tx)
+	}
+	return nil
+}
+
+func (n *Node) stopNetwork() error {
+	if n.stopSync != nil {
+		n.stopSync()
+		<-n.syncDone
+		n.stopSync = nil
+	}
+	if n.Network != nil {
+		n.Chain.Unsubscribe(n.announceSub)
+		return n.Network.Close()
+	}
+	return nil
+}
+
+// announceRequeued gossips transactions a reorg returned to the mempool.
+// Chain events are delivered under the chain lock; announcing only queues
+// frames for the peers.
+func announceRequeued(network ports.Network) core.EventHandler {
+	return func(e core.Event) {
+		if ev, ok := e.(core.TxPending); ok && ev.Requeued {
+			_ = network.AnnounceTxs([]domain.Transaction{ev.Tx})
+		}
+	}
+}
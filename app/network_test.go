@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"xenium/adapters"
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+)
+
+func newNetworkTestNodes(t *testing.T, count int, chainID string) ([]*Node, *domain.Wallet) {
+	t.Helper()
+	validator, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	nodes := make([]*Node, 0, count)
+	var genesis core.GenesisDoc
+	for i := 0; i < count; i++ {
+		cfg := DefaultConfig()
+		cfg.DataDir = ""
+		cfg.ChainID = chainID
+		node, err := NewNode(cfg, adapters.SystemClock{}, nil)
+		if err != nil {
+			t.Fatalf("node: %v", err)
+		}
+		if i == 0 {
+			if err := node.Chain.AddValidator("Alice", 100, validator.PublicKey, validator.PrivateKey); err != nil {
+				t.Fatalf("add validator: %v", err)
+			}
+			node.Chain.SetBalance(validator.Address, 1000)
+			genesis, err = node.Chain.GenesisDocument()
+			if err != nil {
+				t.Fatalf("genesis: %v", err)
+			}
+		} else if err := node.Chain.ApplyGenesis(genesis); err != nil {
+			t.Fatalf("apply genesis: %v", err)
+		}
+		if err := node.StartNetwork(context.Background(), "127.0.0.1:0"); err != nil {
+			t.Fatalf("start network: %v", err)
+		}
+		t.Cleanup(func() { node.Close() })
+		nodes = append(nodes, node)
+	}
+	return nodes, validator
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestTCPNetworkGossipsBlocksAndTxs(t *testing.T) {
+	nodes, validator := newNetworkTestNodes(t, 3, "net-test")
+	a, b, c := nodes[0], nodes[1], nodes[2]
+	if _, err := b.Network.Connect(a.Network.Addr()); err != nil {
+		t.Fatalf("connect b->a: %v", err)
+	}
+	if _, err := c.Network.Connect(b.Network.Addr()); err != nil {
+		t.Fatalf("connect c->b: %v", err)
+	}
+	waitFor(t, "peers", func() bool { return len(b.Network.Peers()) == 2 })
+
+	for i := 0; i < 5; i++ {
+		if _, err := a.ProduceBlock(nil); err != nil {
+			t.Fatalf("produce: %v", err)
+		}
+	}
+	tip := a.Chain.CanonicalTipHash()
+	for _, n := range []*Node{b, c} {
+		node := n
+		waitFor(t, "tip propagation", func() bool {
+			return node.Chain.CanonicalTipHash() == tip
+		})
+	}
+
+	tx := domain.Transaction{To: "bob", Amount: 5, Fee: 1, Nonce: 1}
+	if err := consensus.SignTransaction(validator.PrivateKey, &tx); err != nil {
+		t.Fatalf("sign tx: %v", err)
+	}
+	if err := a.SubmitTx(tx); err != nil {
+		t.Fatalf("submit tx: %v", err)
+	}
+	waitFor(t, "tx propagation", func() bool { return c.Chain.HasPendingTx(tx.Hash) })
+}
+
+func TestTCPNetworkRejectsForeignChain(t *testing.T) {
+	nodes, _ := newNetworkTestNodes(t, 1, "chain-a")
+	others, _ := newNetworkTestNodes(t, 1, "chain-b")
+	if _, err := others[0].Network.Connect(nodes[0].Network.Addr()); !errors.Is(err, adapters.ErrHandshake) {
+		t.Fatalf("expected handshake failure, got %v", err)
+	}
+}
+
+func TestTCPNetworkSyncsLateJoiner(t *testing.T) {
+	nodes, _ := newNetworkTestNodes(t, 2, "sync-test")
+	a, b := nodes[0], nodes[1]
+	for i := 0; i < 30; i++ {
+		if _, err := a.ProduceBlock(nil); err != nil {
+			t.Fatalf("produce: %v", err)
+		}
+	}
+	if _, err := b.Network.Connect(a.Network.Addr()); err != nil {
+		t.Fatalf("connect b->a: %v", err)
+	}
+	b.Sync.Trigger()
+	tip := a.Chain.CanonicalTipHash()
+	waitFor(t, "sync to tip", func() bool { return b.Handler.ChainStatus().TipHash == tip })
+	waitFor(t, "gossip mode", func() bool {
+		b.Sync.Trigger()
+		return !b.Sync.Status().Syncing
+	})
+}
+
+func TestTCPNetworkDiscoversPeersAndBansMisbehaviour(t *testing.T) {
+	nodes, _ := newNetworkTestNodes(t, 3, "pex-test")
+	a, b, c := nodes[0], nodes[1], nodes[2]
+	if _, err := b.Network.Connect(a.Network.Addr()); err != nil {
+		t.Fatalf("connect b->a: %v", err)
+	}
+	waitFor(t, "a sees b", func() bool { return len(a.Network.Peers()) == 1 })
+	// c only knows a; it learns b's address through peer exchange.
+	if _, err := c.Network.Connect(a.Network.Addr()); err != nil {
+		t.Fatalf("connect c->a: %v", err)
+	}
+	waitFor(t, "b address learned", func() bool {
+		for _, e := range c.Network.PeerManager().Addresses() {
+			if e.Addr == b.Network.Addr() {
+				return true
+			}
+		}
+		return false
+	})
+
+	block, err := a.ProduceBlock(nil)
+	if err != nil {
+		t.Fatalf("produce: %v", err)
+	}
+	for i := uint64(1); i <= 2; i++ {
+		forged := block
+		forged.Tick += i
+		forged.Hash = consensus.HashBlock(forged.Index, forged.PrevHash, forged.Slot, forged.Tick, forged.Validator, forged.TxRoot, forged.StateRoot, forged.PoHHash)
+		if err := b.Network.BroadcastBlock(forged); err != nil {
+			t.Fatalf("broadcast forged: %v", err)
+		}
+	}
+	waitFor(t, "b banned by a", func() bool { return a.Network.PeerManager().IsBanned(b.Network.ID()) })
+	_, _ = b.Network.Connect(a.Network.Addr())
+	waitFor(t, "banned peer dropped", func() bool {
+		for _, p := range a.Network.Peers() {
+			if p.ID == b.Network.ID() {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+type announceRecorder struct {
+	announced []string
+}
+
+func (r *announceRecorder) BroadcastBlock(domain.Block) error    { return nil }
+func (r *announceRecorder) BroadcastTx(domain.Transaction) error { return nil }
+func (r *announceRecorder) AnnounceTxs(txs []domain.Transaction) error {
+	for _, tx := range txs {
+		r.announced = append(r.announced, tx.Hash)
+	}
+	return nil
+}
+
+func TestAnnounceRequeuedOnlyAnnouncesReorgedTxs(t *testing.T) {
+	r := &announceRecorder{}
+	announce := announceRequeued(r)
+	announce(core.TxPending{Tx: domain.Transaction{Hash: "fresh"}})
+	announce(core.TxPending{Tx: domain.Transaction{Hash: "reorged"}, Requeued: true})
+	announce(core.TxDropped{Tx: domain.Transaction{Hash: "dropped"}, Reason: core.DropReorged})
+	if len(r.announced) != 1 || r.announced[0] != "reorged" {
+		t.Fatalf("expected only the requeued tx to be announced, got %v", r.announced)
+	}
+}
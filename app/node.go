@@ -1,20 +1,71 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
+
 	"xenium/adapters"
+	"xenium/chainsync"
 	"xenium/core"
+	"xenium/indexer"
+	"xenium/metrics"
 	"xenium/ports"
+	"xenium/rpc"
 )
 
 type Node struct {
-	Chain *core.Blockchain
+	Chain   *core.Blockchain
+	ChainID string
+	DataDir string
+	Meta    adapters.StorageMeta
+	Handler *ChainHandler
+
+	NetConfig NetworkConfig
+	Network   *adapters.TCPNetwork
+	Sync      *chainsync.Syncer
+	Driver    *SlotDriver
+	RPC       *rpc.Server
+	Index     *indexer.Indexer
+	Metrics   *metrics.Registry
+	Services  *Services
+	Logger    ports.Logger // root; components use per-module loggers
+
+	stopSync    context.CancelFunc
+	syncDone    chan struct{}
+	announceSub int
+	stopDriver  context.CancelFunc
+	driverDone  chan struct{}
 }
 
 func NewNode(cfg Config, clock ports.Clock, logger ports.Logger) (*Node, error) {
-	chain := core.NewBlockchain(cfg.Chain, clock, logger)
-	node := &Node{Chain: chain}
+	if logger == nil {
+		logger = adapters.NopLogger{}
+	}
+	chain := core.NewBlockchain(cfg.Chain, clock, ports.ForModule(logger, ports.ModuleCore))
+	node := &Node{Chain: chain, ChainID: cfg.ChainID, DataDir: cfg.DataDir, NetConfig: cfg.Network, Handler: NewChainHandler(chain), Services: NewServices(), Logger: logger}
 
 	if cfg.DataDir != "" {
+		genesis, ok, err := LoadGenesisFile(filepath.Join(cfg.DataDir, genesisFileName))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if err := chain.ApplyGenesis(genesis); err != nil {
+				return nil, err
+			}
+		}
+		expect := adapters.StorageMeta{ChainID: cfg.ChainID}
+		if ok {
+			expect.GenesisHash = genesis.Block.Hash
+		} else if cfg.Chain.DeterministicPoH {
+			first, _ := chain.GetBlockByHeight(0)
+			expect.GenesisHash = first.Hash
+		}
+		meta, err := adapters.OpenDataDir(cfg.DataDir, expect, node.moduleLogger(ports.ModuleStorage))
+		if err != nil {
+			return nil, err
+		}
 		blockStore, err := adapters.NewFileBlockStore(cfg.DataDir)
 		if err != nil {
 			return nil, err
@@ -27,7 +78,35 @@ func NewNode(cfg Config, clock ports.Clock, logger ports.Logger) (*Node, error)
 		if err := chain.RestoreFromStorage(blockStore, snapshotStore); err != nil {
 			return nil, err
 		}
+		if ok {
+			if stored, err := chain.GenesisDocument(); err == nil && stored.Block.Hash != genesis.Block.Hash {
+				return nil, errors.New("stored genesis block does not match " + genesisFileName)
+			}
+		}
+		node.Meta = meta
 	}
 
 	return node, nil
 }
+
+// Start starts the registered services in order.
+func (n *Node) Start(ctx context.Context) error {
+	return n.Services.Start(ctx)
+}
+
+// Shutdown stops services in reverse order, then anything started directly
+// through StartProducing or StartNetwork, and flushes storage last.
+func (n *Node) Shutdown(ctx context.Context) error {
+	err := n.Services.Stop(ctx)
+	n.stopProducing()
+	err = errors.Join(err, n.stopNetwork())
+	return errors.Join(err, n.Chain.FlushStorage())
+}
+
+func (n *Node) Close() error {
+	return n.Shutdown(context.Background())
+}
+
+func (n *Node) moduleLogger(name string) ports.Logger {
+	return ports.ForModule(n.Logger, name)
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Service is a long-running node component. Start must return once the
+// service is running; work continues in the background until Stop or until
+// ctx is cancelled, as background loops run on a context derived from it.
+// Stop must still be called to release listeners and flush state.
+type Service interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// HealthChecker is implemented by services that can report a problem while
+// running.
+type HealthChecker interface {
+	Health() error
+}
+
+// ReadinessChecker is implemented by services that run before they are
+// ready to serve, e.g. a network that is still syncing.
+type ReadinessChecker interface {
+	Ready() error
+}
+
+type ServiceState string
+
+const (
+	ServiceStopped  ServiceState = "stopped"
+	ServiceStarting ServiceState = "starting"
+	ServiceRunning  ServiceState = "running"
+	ServiceStopping ServiceState = "stopping"
+	ServiceFailed   ServiceState = "failed"
+)
+
+var (
+	ErrServiceExists  = errors.New("service already registered")
+	ErrServicesActive = errors.New("services already started")
+)
+
+type ServiceStatus struct {
+	Name    string       `json:"name"`
+	State   ServiceState `json:"state"`
+	Healthy bool         `json:"healthy"`
+	Ready   bool         `json:"ready"`
+	Error   string       `json:"error,omitempty"`
+}
+
+type Health struct {
+	Healthy  bool            `json:"healthy"`
+	Ready    bool            `json:"ready"`
+	Services []ServiceStatus `json:"services"`
+}
+
+// Services starts registered services in registration order and stops
+// them in reverse, so later services may depend on earlier ones.
+type Services struct {
+	mu      sync.Mutex
+	entries []*serviceEntry
+	started bool
+}
+
+type serviceEntry struct {
+	svc   Service
+	state ServiceState
+	err   error
+}
+
+func NewServices() *Services {
+	return &Services{}
+}
+
+func (s *Services) Register(svc Service) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrServicesActive
+	}
+	for _, e := range s.entries {
+		if e.svc.Name() == svc.Name() {
+			return fmt.Errorf("%w: %s", ErrServiceExists, svc.Name())
+		}
+	}
+	s.entries = append(s.entries, &serviceEntry{svc: svc, state: ServiceStopped})
+	return nil
+}
+
+func (s *Services) Get(name string) (Service, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.svc.Name() == name {
+			return e.svc, true
+		}
+	}
+	return nil, false
+}
+
+// Start starts every service. If one fails, those already started are
+// stopped again and the error is returned.
+func (s *Services) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return ErrServicesActive
+	}
+	s.started = true
+	entries := append([]*serviceEntry(nil), s.entries...)
+	s.mu.Unlock()
+
+	for i, e := range entries {
+		s.setState(e, ServiceStarting, nil)
+		if err := e.svc.Start(ctx); err != nil {
+			s.setState(e, ServiceFailed, err)
+			stopErr := s.stop(ctx, entries[:i])
+			return errors.Join(fmt.Errorf("start %s: %w", e.svc.Name(), err), stopErr)
+		}
+		s.setState(e, ServiceRunning, nil)
+	}
+	return nil
+}
+
+// Stop stops running services in reverse order. Every service is asked to
+// stop even if an earlier one fails; all errors are returned joined.
+func (s *Services) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	entries := append([]*serviceEntry(nil), s.entries...)
+	s.mu.Unlock()
+	return s.stop(ctx, entries)
+}
+
+func (s *Services) stop(ctx context.Context, entries []*serviceEntry) error {
+	var errs []error
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		s.mu.Lock()
+		running := e.state == ServiceRunning
+		s.mu.Unlock()
+		if !running {
+			continue
+		}
+		s.setState(e, ServiceStopping, nil)
+		if err := e.svc.Stop(ctx); err != nil {
+			s.setState(e, ServiceFailed, err)
+			errs = append(errs, fmt.Errorf("stop %s: %w", e.svc.Name(), err))
+			continue
+		}
+		s.setState(e, ServiceStopped, nil)
+	}
+	s.mu.Lock()
+	s.started = false
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+func (s *Services) setState(e *serviceEntry, state ServiceState, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.state = state
+	e.err = err
+}
+
+// Health reports every service. The node is healthy when no service has
+// failed or reports a problem, and ready when every service is running
+// and ready.
+func (s *Services) Health() Health {
+	s.mu.Lock()
+	entries := make([]serviceEntry, len(s.entries))
+	for i, e := range s.entries {
+		entries[i] = *e
+	}
+	s.mu.Unlock()
+
+	h := Health{Healthy: true, Ready: true, Services: make([]ServiceStatus, 0, len(entries))}
+	for _, e := range entries {
+		st := ServiceStatus{Name: e.svc.Name(), State: e.state, Healthy: e.state != ServiceFailed}
+		if e.err != nil {
+			st.Error = e.err.Error()
+		}
+		if e.state == ServiceRunning {
+			st.Ready = true
+			if c, ok := e.svc.(HealthChecker); ok {
+				if err := c.Health(); err != nil {
+					st.Healthy = false
+					st.Error = err.Error()
+				}
+			}
+			if c, ok := e.svc.(ReadinessChecker); ok {
+				if err := c.Ready(); err != nil {
+					st.Ready = false
+					if st.Error == "" {
+						st.Error = err.Error()
+					}
+				}
+			}
+		}
+		h.Healthy = h.Healthy && st.Healthy
+		h.Ready = h.Ready && st.Ready
+		h.Services = append(h.Services, st)
+	}
+	return h
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"xenium/domain"
+)
+
+type fakeService struct {
+	name     string
+	log      *[]string
+	startErr error
+	ready    error
+}
+
+func (s *fakeService) Name() string { return s.name }
+
+func (s *fakeService) Start(ctx context.Context) error {
+	*s.log = append(*s.log, "start "+s.name)
+	return s.startErr
+}
+
+func (s *fakeService) Stop(ctx context.Context) error {
+	*s.log = append(*s.log, "stop "+s.name)
+	return nil
+}
+
+func (s *fakeService) Ready() error { return s.ready }
+
+func TestServicesStartInOrderAndStopInReverse(t *testing.T) {
+	var log []string
+	services := NewServices()
+	storage := &fakeService{name: "storage", log: &log}
+	network := &fakeService{name: "network", log: &log, ready: errors.New("syncing")}
+	for _, svc := range []Service{storage, network} {
+		if err := services.Register(svc); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+	}
+	if err := services.Register(&fakeService{name: "network", log: &log}); !errors.Is(err, ErrServiceExists) {
+		t.Fatalf("expected duplicate registration to fail, got %v", err)
+	}
+	if err := services.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	h := services.Health()
+	if !h.Healthy || h.Ready || h.Services[1].Ready || h.Services[1].Error != "syncing" {
+		t.Fatalf("unexpected health while syncing: %+v", h)
+	}
+	network.ready = nil
+	if h := services.Health(); !h.Ready {
+		t.Fatalf("expected ready after sync: %+v", h)
+	}
+	if err := services.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	want := []string{"start storage", "start network", "stop network", "stop storage"}
+	if !reflect.DeepEqual(log, want) {
+		t.Fatalf("lifecycle order %v, want %v", log, want)
+	}
+	if h := services.Health(); h.Ready || h.Services[0].State != ServiceStopped {
+		t.Fatalf("expected stopped services: %+v", h)
+	}
+}
+
+func TestServicesStartFailureStopsStartedServices(t *testing.T) {
+	var log []string
+	services := NewServices()
+	_ = services.Register(&fakeService{name: "storage", log: &log})
+	_ = services.Register(&fakeService{name: "rpc", log: &log, startErr: errors.New("address in use")})
+	_ = services.Register(&fakeService{name: "producer", log: &log})
+	if err := services.Start(context.Background()); err == nil {
+		t.Fatalf("expected start failure")
+	}
+	want := []string{"start storage", "start rpc", "stop storage"}
+	if !reflect.DeepEqual(log, want) {
+		t.Fatalf("lifecycle order %v, want %v", log, want)
+	}
+	h := services.Health()
+	if h.Healthy || h.Services[1].State != ServiceFailed {
+		t.Fatalf("expected failed rpc service: %+v", h)
+	}
+}
+
+func TestNodeServicesProduceAndShutDown(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DataDir = t.TempDir()
+	cfg.Chain.DeterministicPoH = true
+	node, err := NewNode(cfg, nil, nil)
+	if err != nil {
+		t.Fatalf("node: %v", err)
+	}
+	alice, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	if err := node.Chain.AddValidator("Alice", 100, alice.PublicKey, alice.PrivateKey); err != nil {
+		t.Fatalf("validator: %v", err)
+	}
+	if err := node.RegisterServices(ServicesConfig{Produce: true}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := node.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if h := node.Services.Health(); !h.Healthy || !h.Ready || len(h.Services) != 3 {
+		t.Fatalf("unexpected health: %+v", h)
+	}
+	if err := node.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	for _, st := range node.Services.Health().Services {
+		if st.State != ServiceStopped {
+			t.Fatalf("service %s not stopped: %s", st.Name, st.State)
+		}
+	}
+}
+
+func TestCancelledStartContextStopsBackgroundLoops(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DataDir = t.TempDir()
+	cfg.Chain.DeterministicPoH = true
+	node, err := NewNode(cfg, nil, nil)
+	if err != nil {
+		t.Fatalf("node: %v", err)
+	}
+	if err := node.RegisterServices(ServicesConfig{Produce: true}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := node.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer node.Close()
+	done := []<-chan struct{}{node.driverDone}
+	for _, e := range node.Services.entries {
+		if j, ok := e.svc.(*janitorService); ok {
+			done = append(done, j.done)
+		}
+	}
+	if len(done) != 2 {
+		t.Fatalf("expected the slot driver and janitor loops, got %d", len(done))
+	}
+	cancel()
+	for _, ch := range done {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("background loop still running after the start context was cancelled")
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"xenium/explorer"
+	"xenium/indexer"
+	"xenium/metrics"
+	"xenium/ports"
+	"xenium/rpc"
+)
+
+// RegisterServices adds the standard node services. Storage is registered
+// first so that it is stopped, and flushed, after everything writing to it.
+func (n *Node) RegisterServices(cfg ServicesConfig) error {
+	services := []Service{&storageService{node: n}}
+	if cfg.ListenAddr != "" {
+		services = append(services, &networkService{node: n, listenAddr: cfg.ListenAddr})
+	}
+	services = append(services, &janitorService{node: n, interval: cfg.JanitorInterval})
+	if cfg.TxIndex {
+		services = append(services, &indexerService{node: n})
+	}
+	if cfg.RPCAddr != "" {
+		services = append(services, &rpcService{node: n, addr: cfg.RPCAddr})
+	}
+	if cfg.MetricsAddr != "" {
+		services = append(services, &metricsService{node: n, addr: cfg.MetricsAddr})
+	}
+	if cfg.ExplorerAddr != "" {
+		services = append(services, &explorerService{node: n, addr: cfg.ExplorerAddr})
+	}
+	if cfg.Produce {
+		services = append(services, &producerService{node: n})
+	}
+	for _, svc := range services {
+		if err := n.Services.Register(svc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type storageService struct {
+	node *Node
+}
+
+func (s *storageService) Name() string { return "storage" }
+
+func (s *storageService) Start(ctx context.Context) error { return nil }
+
+func (s *storageService) Stop(ctx context.Context) error {
+	return s.node.Chain.FlushStorage()
+}
+
+type networkService struct {
+	node       *Node
+	listenAddr string
+}
+
+func (s *networkService) Name() string { return "network" }
+
+func (s *networkService) Start(ctx context.Context) error {
+	return s.node.StartNetwork(ctx, s.listenAddr)
+}
+
+func (s *networkService) Stop(ctx context.Context) error {
+	return s.node.stopNetwork()
+}
+
+func (s *networkService) Ready() error {
+	if s.node.Sync == nil {
+		return nil
+	}
+	if st := s.node.Sync.Status(); st.Syncing {
+		return fmt.Errorf("syncing to height %d", st.Target)
+	}
+	return nil
+}
+
+// indexerService maintains the transaction index. It is registered before
+// the RPC and explorer services, which serve queries from it.
+type indexerService struct {
+	node *Node
+}
+
+func (s *indexerService) Name() string { return "indexer" }
+
+func (s *indexerService) Start(ctx context.Context) error {
+	ix, err := indexer.Open(indexer.Config{Chain: s.node.Chain, Dir: s.node.DataDir, Logger: s.node.moduleLogger(ports.ModuleStorage)})
+	if err != nil {
+		return err
+	}
+	s.node.Index = ix
+	s.node.Chain.Logger().Infof("Transaction index at height %d", ix.Height())
+	return nil
+}
+
+func (s *indexerService) Stop(ctx context.Context) error {
+	return s.node.Index.Close()
+}
+
+type rpcService struct {
+	node   *Node
+	addr   string
+	server *rpc.Server
+}
+
+func (s *rpcService) Name() string { return "rpc" }
+
+func (s *rpcService) Start(ctx context.Context) error {
+	s.server = rpc.New(rpc.Config{Chain: s.node.Chain, SubmitTx: s.node.SubmitTx, Index: s.node.Index, Logger: s.node.moduleLogger(ports.ModuleRPC)})
+	if err := s.server.Start(s.addr); err != nil {
+		return err
+	}
+	s.node.RPC = s.server
+	s.node.Chain.Logger().Infof("JSON-RPC listening on %s", s.server.Addr())
+	return nil
+}
+
+func (s *rpcService) Stop(ctx context.Context) error {
+	return s.server.Close(ctx)
+}
+
+// metricsService serves the node's metrics registry on /metrics.
+type metricsService struct {
+	node   *Node
+	addr   string
+	chain  *metrics.ChainMetrics
+	server *http.Server
+	done   chan struct{}
+}
+
+func (s *metricsService) Name() string { return "metrics" }
+
+func (s *metricsService) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	reg := metrics.NewRegistry()
+	s.chain = metrics.NewChainMetrics(reg, s.node.Chain)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", reg)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.node.Chain.Logger().Errorf("Metrics server stopped: %v", err)
+		}
+	}()
+	s.node.Metrics = reg
+	s.node.Chain.Logger().Infof("Metrics listening on %s/metrics", ln.Addr())
+	return nil
+}
+
+func (s *metricsService) Stop(ctx context.Context) error {
+	s.chain.Close()
+	err := s.server.Shutdown(ctx)
+	<-s.done
+	return err
+}
+
+// explorerService serves the read-only block explorer.
+type explorerService struct {
+	node   *Node
+	addr   string
+	server *http.Server
+	done   chan struct{}
+}
+
+func (s *explorerService) Name() string { return "explorer" }
+
+func (s *explorerService) Start(ctx context.Context) error {
+	cfg := explorer.Config{Chain: s.node.Chain, Logger: s.node.moduleLogger(ports.ModuleRPC)}
+	if ix := s.node.Index; ix != nil {
+		cfg.History = func(address string, limit int) []explorer.TxRecord {
+			var out []explorer.TxRecord
+			for _, r := range ix.History(address, 0, limit) {
+				out = append(out, explorer.TxRecord{Tx: r.Tx, BlockHash: r.BlockHash, Height: r.Height, Slot: r.Slot})
+			}
+			return out
+		}
+	}
+	handler, err := explorer.New(cfg)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.server = &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.node.Chain.Logger().Errorf("Explorer server stopped: %v", err)
+		}
+	}()
+	s.node.Chain.Logger().Infof("Explorer listening on http://%s/", ln.Addr())
+	return nil
+}
+
+func (s *explorerService) Stop(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	<-s.done
+	return err
+}
+
+type producerService struct {
+	node *Node
+}
+
+func (s *producerService) Name() string { return "producer" }
+
+func (s *producerService) Start(ctx context.Context) error {
+	return s.node.StartProducing(ctx)
+}
+
+func (s *producerService) Stop(ctx context.Context) error {
+	s.node.stopProducing()
+	return nil
+}
+
+func (s *producerService) Health() error {
+	if s.node.Driver == nil {
+		return nil
+	}
+	return s.node.Driver.Health()
+}
+
+// janitorService periodically drops mempool transactions whose nonce has
+// already been used on the canonical chain.
+type janitorService struct {
+	node     *Node
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func (s *janitorService) Name() string { return "mempool-janitor" }
+
+func (s *janitorService) Start(ctx context.Context) error {
+	interval := s.interval
+	if interval <= 0 {
+		interval = DefaultConfig().Node.JanitorInterval
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep()
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *janitorService) Stop(ctx context.Context) error {
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sweep prunes the mempool once and returns the number of dropped txs.
+func (s *janitorService) Sweep() int {
+	removed := s.node.Chain.PruneMempool()
+	if removed > 0 {
+		s.node.Chain.Logger().Infof("Mempool janitor dropped %d stale transactions", removed)
+	}
+	return removed
+}
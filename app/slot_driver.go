@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"xenium/adapters"
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+type SlotResult struct {
+	Slot     uint64
+	Tick     uint64
+	Leader   string
+	Produced bool
+	Skipped  bool
+	Block    domain.Block
+}
+
+type SlotDriverStats struct {
+	Produced uint64
+	Skipped  uint64
+	Failed   uint64
+}
+
+// SlotDriver maps wall-clock time to PoH ticks and slots from the chain's
+// genesis time and produces a block whenever a locally held validator is
+// the slot leader. Each Step reads the clock exactly once, so a run driven
+// by a SimulatedClock is fully deterministic.
+type SlotDriver struct {
+	node  *Node
+	clock ports.Clock
+	// Wait blocks until the next slot is due; it returns false when ctx is
+	// done. Simulations can replace it to avoid sleeping.
+	Wait func(ctx context.Context, d time.Duration) bool
+
+	lastSlot uint64
+	lastNow  int64
+
+	mu      sync.Mutex
+	stats   SlotDriverStats
+	lastErr error
+}
+
+func NewSlotDriver(node *Node, clock ports.Clock) *SlotDriver {
+	if clock == nil {
+		clock = adapters.SystemClock{}
+	}
+	return &SlotDriver{node: node, clock: clock, Wait: sleepContext}
+}
+
+func (d *SlotDriver) Stats() SlotDriverStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// Health returns the error of the last step, if it failed.
+func (d *SlotDriver) Health() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lastErr
+}
+
+// Step handles the slot that is current according to the clock. A slot is
+// handled at most once; later steps within the same slot do nothing.
+func (d *SlotDriver) Step() (SlotResult, error) {
+	now := d.clock.UnixNano()
+	d.lastNow = now
+	chain := d.node.Chain
+	genesis := chain.GenesisTime()
+	if genesis == 0 {
+		// Bind an unbound chain so that its current tip maps to now.
+		genesis = now - int64(chain.Tip().Tick)*(1e9/consensus.TicksPerSecond)
+		chain.SetGenesisTime(genesis)
+	}
+	var res SlotResult
+	res.Tick = consensus.TickAt(genesis, now)
+	res.Slot = res.Tick / consensus.TicksPerSlot
+	if res.Slot <= d.lastSlot {
+		return res, nil
+	}
+	d.lastSlot = res.Slot
+	block, err := chain.ProduceBlockAt(res.Tick, nil)
+	res.Leader = block.Validator
+	switch {
+	case errors.Is(err, core.ErrNotLeader):
+		res.Leader, _ = chain.LocalLeader(res.Slot)
+		res.Skipped, err = true, nil
+	case errors.Is(err, core.ErrSlotTaken):
+		err = nil
+	case errors.Is(err, core.ErrEquivocation):
+		res.Produced, res.Block, err = true, block, nil
+	case err == nil:
+		res.Produced, res.Block = true, block
+	}
+	d.mu.Lock()
+	d.lastErr = err
+	switch {
+	case err != nil:
+		d.stats.Failed++
+	case res.Produced:
+		d.stats.Produced++
+	case res.Skipped:
+		d.stats.Skipped++
+	}
+	d.mu.Unlock()
+	if err != nil {
+		return res, err
+	}
+	if res.Produced && d.node.Network != nil {
+		if err := d.node.Network.BroadcastBlock(res.Block); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// Run steps once per slot until ctx is done.
+func (d *SlotDriver) Run(ctx context.Context) {
+	logger := d.node.Chain.Logger()
+	for ctx.Err() == nil {
+		if _, err := d.Step(); err != nil {
+			logger.Warnf("Slot driver: %v", err)
+		}
+		next := consensus.SlotStartTime(d.node.Chain.GenesisTime(), d.lastSlot+1)
+		if !d.Wait(ctx, time.Duration(next-d.lastNow)) {
+			return
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// StartProducing runs a SlotDriver on the chain's clock until ctx is
+// cancelled or the node is closed.
+func (n *Node) StartProducing(ctx context.Context) error {
+	if n.stopDriver != nil {
+		return errors.New("slot driver already started")
+	}
+	n.Driver = NewSlotDriver(n, n.Chain.Clock())
+	ctx, cancel := context.WithCancel(ctx)
+	n.stopDriver = cancel
+	n.driverDone = make(chan struct{})
+	go func() {
+		defer close(n.driverDone)
+		n.Driver.Run(ctx)
+	}()
+	return nil
+}
+
+func (n *Node) stopProducing() {
+	if n.stopDriver != nil {
+		n.stopDriver()
+		<-n.driverDone
+		n.stopDriver = nil
+	}
+}
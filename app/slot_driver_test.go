@@ -0,0 +1,71 @@
+package app
+
+import (
+	"testing"
+
+	"xenium/adapters"
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+func runSlotDriver(t *testing.T, alice *domain.Wallet, bob *domain.Wallet, steps int) (*Node, SlotDriverStats) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.DataDir = ""
+	cfg.Chain.DeterministicPoH = true
+	cfg.Chain.PoHSeed = 7
+	// Half a slot per clock read.
+	clock := adapters.NewSimulatedClock(0, int64(consensus.TicksPerSlot)*(1e9/consensus.TicksPerSecond)/2)
+	node, err := NewNode(cfg, clock, nil)
+	if err != nil {
+		t.Fatalf("node: %v", err)
+	}
+	if err := node.Chain.AddValidator("Alice", 100, alice.PublicKey, alice.PrivateKey); err != nil {
+		t.Fatalf("add alice: %v", err)
+	}
+	if err := node.Chain.AddValidator("Bob", 100, bob.PublicKey, nil); err != nil {
+		t.Fatalf("add bob: %v", err)
+	}
+	node.Chain.SetGenesisTime(1)
+	driver := NewSlotDriver(node, clock)
+	for i := 0; i < steps; i++ {
+		res, err := driver.Step()
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if res.Produced && res.Block.Validator != "Alice" {
+			t.Fatalf("produced a block for non-local leader %s", res.Block.Validator)
+		}
+	}
+	return node, driver.Stats()
+}
+
+func TestSlotDriverProducesOnlyLocalLeaderSlots(t *testing.T) {
+	alice, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	bob, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	// 80 half-slot reads cover slots 0..39; slot 0 is genesis.
+	node, stats := runSlotDriver(t, alice, bob, 80)
+	if stats.Produced == 0 || stats.Skipped == 0 {
+		t.Fatalf("expected both produced and skipped slots, got %+v", stats)
+	}
+	if stats.Produced+stats.Skipped != 39 {
+		t.Fatalf("expected one decision per slot after genesis over 39 slots, got %+v", stats)
+	}
+	if err := node.Chain.VerifyChain(); err != nil {
+		t.Fatalf("verify chain: %v", err)
+	}
+	if node.Chain.LastProcessedSlot() == 0 {
+		t.Fatalf("skipped slots were not processed")
+	}
+
+	again, againStats := runSlotDriver(t, alice, bob, 80)
+	if again.Chain.CanonicalTipHash() != node.Chain.CanonicalTipHash() || againStats != stats {
+		t.Fatalf("simulated run is not deterministic")
+	}
+}
@@ -0,0 +1,515 @@
+package chainsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"xenium/consensus"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+var ErrNoPeers = errors.New("no usable sync peers")
+
+// errNoHeaders means a peer advertised a heavier chain and then served no
+// headers for it.
+var errNoHeaders = errors.New("peer served no headers for its advertised chain")
+
+// Chain is the local chain as seen by the syncer. Implementations must be
+// safe to call from the syncer goroutine.
+type Chain interface {
+	ChainStatus() ports.ChainStatus
+	HasBlock(hash string) bool
+	Header(hash string) (domain.BlockHeader, bool)
+	ValidatorPubKey(name string) (string, bool)
+	// LeaderForSlot reports the expected leader if the epoch snapshot for
+	// slot is already known locally.
+	LeaderForSlot(slot uint64) (string, bool)
+	ImportBlock(block domain.Block) error
+}
+
+type Config struct {
+	HeaderBatch    int
+	BodyBatch      int
+	RequestTimeout time.Duration
+	MaxRetries     int
+	BanDuration    time.Duration
+	PollInterval   time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		HeaderBatch:    128,
+		BodyBatch:      32,
+		RequestTimeout: 5 * time.Second,
+		MaxRetries:     3,
+		BanDuration:    10 * time.Minute,
+		PollInterval:   2 * time.Second,
+	}
+}
+
+// Syncer catches the local chain up with the best peer. Headers are fetched
+// and fully validated first (link, hash, PoH, signature and, where the epoch
+// snapshot is known, leader), then bodies are fetched in parallel from all
+// usable peers and checked against the header TxRoot. Blocks are imported in
+// order, which validates StateRoot. Peers serving invalid data or stalling
+// are banned and the work is retried elsewhere.
+type Syncer struct {
+	cfg     Config
+	chain   Chain
+	net     ports.SyncNetwork
+	logger  ports.Logger
+	trigger chan struct{}
+
+	mu      sync.Mutex
+	bans    map[string]time.Time
+	syncing bool
+	target  uint64
+}
+
+type Status struct {
+	Syncing bool
+	Target  uint64
+	Banned  []string
+}
+
+func New(cfg Config, chain Chain, net ports.SyncNetwork, logger ports.Logger) *Syncer {
+	def := DefaultConfig()
+	if cfg.HeaderBatch <= 0 {
+		cfg.HeaderBatch = def.HeaderBatch
+	}
+	if cfg.BodyBatch <= 0 {
+		cfg.BodyBatch = def.BodyBatch
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = def.RequestTimeout
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = def.MaxRetries
+	}
+	if cfg.BanDuration <= 0 {
+		cfg.BanDuration = def.BanDuration
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	return &Syncer{
+		cfg:     cfg,
+		chain:   chain,
+		net:     net,
+		logger:  logger,
+		trigger: make(chan struct{}, 1),
+		bans:    make(map[string]time.Time),
+	}
+}
+
+func (s *Syncer) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Status{Syncing: s.syncing, Target: s.target}
+	now := time.Now()
+	for peer, until := range s.bans {
+		if now.Before(until) {
+			st.Banned = append(st.Banned, peer)
+		}
+	}
+	sort.Strings(st.Banned)
+	return st
+}
+
+// Trigger asks the run loop to look for a better chain now, e.g. after a
+// gossiped block arrived with an unknown parent.
+func (s *Syncer) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run syncs until caught up, then stays in gossip mode and only syncs again
+// when triggered or when a poll finds a heavier peer.
+func (s *Syncer) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-s.trigger:
+		}
+		before := s.chain.ChainStatus().TipHash
+		behind, err := s.SyncOnce(ctx)
+		if err != nil && !errors.Is(err, ErrNoPeers) && ctx.Err() == nil {
+			s.logger.Warnf("Sync round failed: %v", err)
+		}
+		// Keep going while rounds make progress; a failed round retries
+		// against other peers after the poll interval.
+		wait := s.cfg.PollInterval
+		if behind && err == nil && s.chain.ChainStatus().TipHash != before {
+			wait = 0
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// SyncOnce runs one sync round against the heaviest peer, moving on to the
+// next heaviest when a peer cannot back its status with headers. It reports
+// whether the local chain was behind at the start of the round.
+func (s *Syncer) SyncOnce(ctx context.Context) (bool, error) {
+	local := s.chain.ChainStatus()
+	candidates, err := s.heavierPeers(ctx, local)
+	if err != nil {
+		return false, err
+	}
+	if len(candidates) == 0 {
+		s.setSyncing(false, 0)
+		return false, nil
+	}
+	for _, c := range candidates {
+		local = s.chain.ChainStatus()
+		if !heavier(c.status, local) {
+			continue
+		}
+		err = s.syncFrom(ctx, c.peer, local, c.status)
+		if !errors.Is(err, errNoHeaders) {
+			return true, err
+		}
+		s.ban(c.peer, err.Error())
+	}
+	return true, err
+}
+
+func (s *Syncer) syncFrom(ctx context.Context, peer string, local ports.ChainStatus, remote ports.ChainStatus) error {
+	s.setSyncing(true, remote.TipHeight)
+	s.logger.Infof("Sync started peer=%s local_height=%d target_height=%d", peer, local.TipHeight, remote.TipHeight)
+
+	ancestor, err := s.commonAncestor(ctx, peer, local, remote)
+	if err != nil {
+		return err
+	}
+	prev, ok := s.chain.Header(ancestor.Hash)
+	if !ok {
+		return fmt.Errorf("common ancestor %s missing locally", ancestor.Hash)
+	}
+	next := prev.Index + 1
+	for next <= remote.TipHeight {
+		headers, err := s.fetchHeaders(ctx, peer, next)
+		if err != nil {
+			return err
+		}
+		if len(headers) == 0 {
+			return fmt.Errorf("%w: height %d of %d", errNoHeaders, next, remote.TipHeight)
+		}
+		if err := s.validateHeaders(prev, headers); err != nil {
+			s.ban(peer, "invalid headers: "+err.Error())
+			return err
+		}
+		if err := s.fetchAndImport(ctx, peer, headers); err != nil {
+			return err
+		}
+		prev = headers[len(headers)-1]
+		next = prev.Index + 1
+	}
+	s.logger.Infof("Sync round done peer=%s height=%d", peer, prev.Index)
+	return nil
+}
+
+func (s *Syncer) setSyncing(syncing bool, target uint64) {
+	s.mu.Lock()
+	s.syncing = syncing
+	s.target = target
+	s.mu.Unlock()
+}
+
+func (s *Syncer) usablePeers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var out []string
+	for _, p := range s.net.SyncPeers() {
+		if until, ok := s.bans[p]; ok {
+			if now.Before(until) {
+				continue
+			}
+			delete(s.bans, p)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func (s *Syncer) ban(peer string, reason string) {
+	s.mu.Lock()
+	s.bans[peer] = time.Now().Add(s.cfg.BanDuration)
+	s.mu.Unlock()
+	s.logger.Warnf("Sync banned peer %s: %s", peer, reason)
+	s.net.DisconnectPeer(peer, reason)
+}
+
+type peerStatus struct {
+	peer   string
+	status ports.ChainStatus
+}
+
+// heavierPeers returns the peers whose chain is heavier than local,
+// heaviest first.
+func (s *Syncer) heavierPeers(ctx context.Context, local ports.ChainStatus) ([]peerStatus, error) {
+	peers := s.usablePeers()
+	if len(peers) == 0 {
+		return nil, ErrNoPeers
+	}
+	var out []peerStatus
+	for _, p := range peers {
+		rctx, cancel := context.WithTimeout(ctx, s.cfg.RequestTimeout)
+		st, err := s.net.RequestStatus(rctx, p)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				s.ban(p, "status request stalled")
+			}
+			continue
+		}
+		if st.GenesisHash != local.GenesisHash {
+			s.ban(p, "different genesis")
+			continue
+		}
+		if heavier(st, local) {
+			out = append(out, peerStatus{peer: p, status: st})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return heavier(out[i].status, out[j].status) })
+	return out, nil
+}
+
+func heavier(a ports.ChainStatus, b ports.ChainStatus) bool {
+	if a.Weight != b.Weight {
+		return a.Weight > b.Weight
+	}
+	return a.TipSlot > b.TipSlot
+}
+
+// commonAncestor probes the peer's canonical chain backwards from the lower
+// of both tips with a doubling step until it finds a block we have.
+func (s *Syncer) commonAncestor(ctx context.Context, peer string, local ports.ChainStatus, remote ports.ChainStatus) (domain.BlockHeader, error) {
+	height := local.TipHeight
+	if remote.TipHeight < height {
+		height = remote.TipHeight
+	}
+	step := uint64(1)
+	for {
+		headers, err := s.requestHeaders(ctx, peer, height, 1)
+		if err != nil {
+			return domain.BlockHeader{}, err
+		}
+		if len(headers) != 1 || headers[0].Index != height {
+			s.ban(peer, "bad ancestor response")
+			return domain.BlockHeader{}, fmt.Errorf("peer returned no header at height %d", height)
+		}
+		if s.chain.HasBlock(headers[0].Hash) {
+			return headers[0], nil
+		}
+		if height == 0 {
+			s.ban(peer, "no common ancestor")
+			return domain.BlockHeader{}, errors.New("no common ancestor with peer " + peer)
+		}
+		if step > height {
+			height = 0
+		} else {
+			height -= step
+		}
+		step *= 2
+	}
+}
+
+func (s *Syncer) fetchHeaders(ctx context.Context, peer string, from uint64) ([]domain.BlockHeader, error) {
+	headers, err := s.requestHeaders(ctx, peer, from, s.cfg.HeaderBatch)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) > s.cfg.HeaderBatch {
+		s.ban(peer, "oversized header response")
+		return nil, errors.New("oversized header response")
+	}
+	return headers, nil
+}
+
+func (s *Syncer) requestHeaders(ctx context.Context, peer string, from uint64, count int) ([]domain.BlockHeader, error) {
+	rctx, cancel := context.WithTimeout(ctx, s.cfg.RequestTimeout)
+	defer cancel()
+	headers, err := s.net.RequestHeaders(rctx, peer, from, count)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		s.ban(peer, "header request stalled")
+	}
+	return headers, err
+}
+
+// validateHeaders checks a batch against its predecessor without touching
+// chain state. The leader check needs the epoch snapshot; for epochs not
+// known yet it is left to ImportBlock, which runs after the snapshot for
+// earlier blocks has been taken.
+func (s *Syncer) validateHeaders(prev domain.BlockHeader, headers []domain.BlockHeader) error {
+	prevPoH, err := consensus.ParsePoHHashHex(prev.PoHHash)
+	if err != nil {
+		return err
+	}
+	prevTick := prev.Tick
+	for _, h := range headers {
+		block := h.WithBody(nil)
+		if h.Index != prev.Index+1 {
+			return fmt.Errorf("header %d does not follow %d", h.Index, prev.Index)
+		}
+		if err := consensus.VerifyBlockLink(prev.WithBody(nil), block); err != nil {
+			return err
+		}
+		if err := consensus.VerifyBlockHash(block); err != nil {
+			return err
+		}
+		prevPoH, prevTick, err = consensus.VerifyPoH(prevPoH, prevTick, block)
+		if err != nil {
+			return err
+		}
+		pubKey, ok := s.chain.ValidatorPubKey(h.Validator)
+		if !ok {
+			return fmt.Errorf("unknown validator %s at index %d", h.Validator, h.Index)
+		}
+		if err := consensus.VerifyBlockSignature(block, pubKey); err != nil {
+			return err
+		}
+		if leader, ok := s.chain.LeaderForSlot(h.Slot); ok && leader != h.Validator {
+			return fmt.Errorf("wrong leader at slot %d", h.Slot)
+		}
+		prev = h
+	}
+	return nil
+}
+
+func (s *Syncer) fetchAndImport(ctx context.Context, headerPeer string, headers []domain.BlockHeader) error {
+	var missing []domain.BlockHeader
+	for _, h := range headers {
+		if !s.chain.HasBlock(h.Hash) {
+			missing = append(missing, h)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	bodies, err := s.fetchBodies(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for i, h := range missing {
+		err := s.chain.ImportBlock(h.WithBody(bodies[i]))
+		if err != nil && !s.chain.HasBlock(h.Hash) {
+			s.ban(headerPeer, "block failed import: "+err.Error())
+			return fmt.Errorf("import block %d: %w", h.Index, err)
+		}
+	}
+	return nil
+}
+
+// fetchBodies splits headers into chunks and downloads them concurrently,
+// each chunk starting at a different peer and moving on to the next peer
+// when one fails.
+func (s *Syncer) fetchBodies(ctx context.Context, headers []domain.BlockHeader) ([][]domain.Transaction, error) {
+	peers := s.usablePeers()
+	if len(peers) == 0 {
+		return nil, ErrNoPeers
+	}
+	out := make([][]domain.Transaction, len(headers))
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var firstErr error
+	for start, chunk := 0, 0; start < len(headers); start, chunk = start+s.cfg.BodyBatch, chunk+1 {
+		end := start + s.cfg.BodyBatch
+		if end > len(headers) {
+			end = len(headers)
+		}
+		wg.Add(1)
+		go func(start int, end int, chunk int) {
+			defer wg.Done()
+			bodies, err := s.fetchChunk(ctx, headers[start:end], peers, chunk)
+			if err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				errMu.Unlock()
+				return
+			}
+			copy(out[start:end], bodies)
+		}(start, end, chunk)
+	}
+	wg.Wait()
+	return out, firstErr
+}
+
+func (s *Syncer) fetchChunk(ctx context.Context, headers []domain.BlockHeader, peers []string, offset int) ([][]domain.Transaction, error) {
+	hashes := make([]string, len(headers))
+	for i, h := range headers {
+		hashes[i] = h.Hash
+	}
+	var lastErr error
+	attempts := s.cfg.MaxRetries
+	if attempts < len(peers) {
+		attempts = len(peers)
+	}
+	for attempt := 0; attempt < attempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		peer := peers[(offset+attempt)%len(peers)]
+		if s.isBanned(peer) {
+			continue
+		}
+		rctx, cancel := context.WithTimeout(ctx, s.cfg.RequestTimeout)
+		bodies, err := s.net.RequestBodies(rctx, peer, hashes)
+		cancel()
+		if err != nil {
+			lastErr = err
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				s.ban(peer, "body request stalled")
+			}
+			continue
+		}
+		if err := checkBodies(headers, bodies); err != nil {
+			lastErr = err
+			s.ban(peer, "invalid bodies: "+err.Error())
+			continue
+		}
+		return bodies, nil
+	}
+	if lastErr == nil {
+		lastErr = ErrNoPeers
+	}
+	return nil, fmt.Errorf("bodies from %d: %w", headers[0].Index, lastErr)
+}
+
+func (s *Syncer) isBanned(peer string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	until, ok := s.bans[peer]
+	return ok && time.Now().Before(until)
+}
+
+func checkBodies(headers []domain.BlockHeader, bodies [][]domain.Transaction) error {
+	if len(bodies) != len(headers) {
+		return fmt.Errorf("got %d bodies for %d headers", len(bodies), len(headers))
+	}
+	for i, h := range headers {
+		if consensus.TxRoot(bodies[i]) != h.TxRoot {
+			return fmt.Errorf("tx root mismatch at index %d", h.Index)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package chainsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+type testChain struct {
+	bc *core.Blockchain
+}
+
+func (c testChain) ChainStatus() ports.ChainStatus {
+	tip := c.bc.Tip()
+	genesis, _ := c.bc.GenesisDocument()
+	return ports.ChainStatus{
+		GenesisHash: genesis.Block.Hash,
+		TipHash:     tip.Hash,
+		TipHeight:   tip.Index,
+		TipSlot:     tip.Slot,
+		Weight:      c.bc.ScoreTip(tip.Hash).CumulativeWeight,
+	}
+}
+
+func (c testChain) HasBlock(hash string) bool {
+	return c.bc.HasBlock(hash)
+}
+
+func (c testChain) Header(hash string) (domain.BlockHeader, bool) {
+	b, err := c.bc.GetBlockByHash(hash)
+	return b.Header(), err == nil
+}
+
+func (c testChain) ValidatorPubKey(name string) (string, bool) {
+	v, ok := c.bc.Validator(name)
+	if !ok {
+		return "", false
+	}
+	return v.PubKey, true
+}
+
+func (c testChain) LeaderForSlot(slot uint64) (string, bool) {
+	snap, ok := c.bc.PeekEpochSnapshot(slot)
+	if !ok {
+		return "", false
+	}
+	return consensus.LeaderFromSnapshot(slot, snap.Validators), true
+}
+
+func (c testChain) ImportBlock(block domain.Block) error {
+	err := c.bc.ImportBlock(block)
+	if errors.Is(err, core.ErrKnownBlock) {
+		return nil
+	}
+	return err
+}
+
+type testPeer struct {
+	chain       testChain
+	badBodies   bool
+	stallBodies bool
+	// withholdHeaders claims a heavier chain but only answers ancestor
+	// probes, never header batches.
+	withholdHeaders bool
+}
+
+// testNetwork serves requests straight from in-memory peers.
+type testNetwork struct {
+	mu           sync.Mutex
+	peers        map[string]*testPeer
+	disconnected map[string]string
+}
+
+func (n *testNetwork) SyncPeers() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	out := make([]string, 0, len(n.peers))
+	for id := range n.peers {
+		if _, gone := n.disconnected[id]; !gone {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (n *testNetwork) RequestStatus(ctx context.Context, peer string) (ports.ChainStatus, error) {
+	st := n.peers[peer].chain.ChainStatus()
+	if n.peers[peer].withholdHeaders {
+		st.Weight *= 2
+	}
+	return st, nil
+}
+
+func (n *testNetwork) RequestHeaders(ctx context.Context, peer string, from uint64, count int) ([]domain.BlockHeader, error) {
+	if n.peers[peer].withholdHeaders && count > 1 {
+		return nil, nil
+	}
+	var out []domain.BlockHeader
+	for h := from; h < from+uint64(count); h++ {
+		b, err := n.peers[peer].chain.bc.GetBlockByHeight(h)
+		if err != nil {
+			break
+		}
+		out = append(out, b.Header())
+	}
+	return out, nil
+}
+
+func (n *testNetwork) RequestBodies(ctx context.Context, peer string, hashes []string) ([][]domain.Transaction, error) {
+	p := n.peers[peer]
+	if p.stallBodies {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	out := make([][]domain.Transaction, 0, len(hashes))
+	for _, hash := range hashes {
+		b, err := p.chain.bc.GetBlockByHash(hash)
+		if err != nil {
+			return nil, fmt.Errorf("unknown block %s", hash)
+		}
+		txs := b.Transactions
+		if p.badBodies {
+			txs = append(txs, domain.Transaction{To: "mallory", Amount: 1})
+		}
+		out = append(out, txs)
+	}
+	return out, nil
+}
+
+func (n *testNetwork) DisconnectPeer(peer string, reason string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.disconnected[peer] = reason
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...any)      {}
+func (nopLogger) Infof(string, ...any)       {}
+func (nopLogger) Warnf(string, ...any)       {}
+func (nopLogger) Errorf(string, ...any)      {}
+func (nopLogger) Criticalf(string, ...any)   {}
+func (l nopLogger) With(...any) ports.Logger { return l }
+
+func newSyncTestChain(t *testing.T, w *domain.Wallet, genesis *core.GenesisDoc) *core.Blockchain {
+	t.Helper()
+	cfg := core.ChainConfig{
+		MaxReorgDepth:        2,
+		FinalitySlots:        2,
+		MinReorgWeightDeltaP: 10,
+		EpochLength:          5,
+		DeterministicPoH:     true,
+		PoHSeed:              1,
+	}
+	bc := core.NewBlockchain(cfg, nil, nil)
+	if genesis != nil {
+		if err := bc.ApplyGenesis(*genesis); err != nil {
+			t.Fatalf("apply genesis: %v", err)
+		}
+		return bc
+	}
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	bc.SetBalance(w.Address, 1000)
+	return bc
+}
+
+func TestSyncCatchesUpAndBansBadPeers(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	source := newSyncTestChain(t, w, nil)
+	genesis, err := source.GenesisDocument()
+	if err != nil {
+		t.Fatalf("genesis: %v", err)
+	}
+	for i := 0; i < 40; i++ {
+		if err := source.AddBlock(nil); err != nil {
+			t.Fatalf("add block %d: %v", i, err)
+		}
+	}
+	local := newSyncTestChain(t, w, &genesis)
+
+	net := &testNetwork{
+		peers: map[string]*testPeer{
+			"good":    {chain: testChain{source}},
+			"corrupt": {chain: testChain{source}, badBodies: true},
+			"stalled": {chain: testChain{source}, stallBodies: true},
+		},
+		disconnected: make(map[string]string),
+	}
+	cfg := DefaultConfig()
+	cfg.HeaderBatch = 16
+	cfg.BodyBatch = 4
+	cfg.RequestTimeout = 50 * time.Millisecond
+	syncer := New(cfg, testChain{local}, net, nopLogger{})
+
+	for round := 0; round < 5 && local.CanonicalTipHash() != source.CanonicalTipHash(); round++ {
+		_, _ = syncer.SyncOnce(context.Background())
+	}
+	if local.CanonicalTipHash() != source.CanonicalTipHash() {
+		t.Fatalf("sync did not reach source tip")
+	}
+	if err := local.VerifyChain(); err != nil {
+		t.Fatalf("verify synced chain: %v", err)
+	}
+	if _, ok := net.disconnected["corrupt"]; !ok {
+		t.Fatalf("peer serving bad bodies was not banned")
+	}
+	if _, ok := net.disconnected["stalled"]; !ok {
+		t.Fatalf("stalled peer was not banned")
+	}
+	if _, ok := net.disconnected["good"]; ok {
+		t.Fatalf("good peer was banned: %s", net.disconnected["good"])
+	}
+	behind, err := syncer.SyncOnce(context.Background())
+	if err != nil || behind {
+		t.Fatalf("expected gossip mode at tip, behind=%v err=%v", behind, err)
+	}
+	if syncer.Status().Syncing {
+		t.Fatalf("syncer still reports syncing at tip")
+	}
+}
+
+func TestSyncMovesOnFromPeerWithoutHeaders(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	source := newSyncTestChain(t, w, nil)
+	genesis, err := source.GenesisDocument()
+	if err != nil {
+		t.Fatalf("genesis: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := source.AddBlock(nil); err != nil {
+			t.Fatalf("add block %d: %v", i, err)
+		}
+	}
+	local := newSyncTestChain(t, w, &genesis)
+	net := &testNetwork{
+		peers: map[string]*testPeer{
+			"good": {chain: testChain{source}},
+			"liar": {chain: testChain{source}, withholdHeaders: true},
+		},
+		disconnected: make(map[string]string),
+	}
+	syncer := New(DefaultConfig(), testChain{local}, net, nopLogger{})
+
+	behind, err := syncer.SyncOnce(context.Background())
+	if err != nil || !behind {
+		t.Fatalf("sync round: behind=%v err=%v", behind, err)
+	}
+	if local.CanonicalTipHash() != source.CanonicalTipHash() {
+		t.Fatalf("sync did not move on to the next peer in the same round")
+	}
+	if _, ok := net.disconnected["liar"]; !ok {
+		t.Fatalf("peer without headers for its status was not banned")
+	}
+	if _, ok := net.disconnected["good"]; ok {
+		t.Fatalf("good peer was banned: %s", net.disconnected["good"])
+	}
+}
+
+func TestSyncRejectsTamperedHeaders(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	source := newSyncTestChain(t, w, nil)
+	genesis, _ := source.GenesisDocument()
+	for i := 0; i < 5; i++ {
+		if err := source.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	local := newSyncTestChain(t, w, &genesis)
+	syncer := New(DefaultConfig(), testChain{local}, &testNetwork{disconnected: map[string]string{}}, nopLogger{})
+
+	headers := make([]domain.BlockHeader, 0, 5)
+	for h := uint64(1); h <= 5; h++ {
+		b, _ := source.GetBlockByHeight(h)
+		headers = append(headers, b.Header())
+	}
+	prev, _ := testChain{local}.Header(genesis.Block.Hash)
+	if err := syncer.validateHeaders(prev, headers); err != nil {
+		t.Fatalf("valid headers rejected: %v", err)
+	}
+	tampered := append([]domain.BlockHeader(nil), headers...)
+	tampered[2].PoHHash = tampered[1].PoHHash
+	tampered[2].Hash = consensus.HashBlock(tampered[2].Index, tampered[2].PrevHash, tampered[2].Slot, tampered[2].Tick, tampered[2].Validator, tampered[2].TxRoot, tampered[2].StateRoot, tampered[2].PoHHash)
+	if err := syncer.validateHeaders(prev, tampered); err == nil {
+		t.Fatalf("expected tampered poh to be rejected")
+	}
+	unsigned := append([]domain.BlockHeader(nil), headers...)
+	unsigned[0].Signature = nil
+	if err := syncer.validateHeaders(prev, unsigned); err == nil {
+		t.Fatalf("expected missing signature to be rejected")
+	}
+}
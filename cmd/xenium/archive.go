@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"xenium/adapters"
+	"xenium/app"
+)
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	dataDir := fs.String("data-dir", app.DefaultConfig().DataDir, "data directory to export from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: xenium export [-data-dir dir] <archive>")
+	}
+	cfg := app.DefaultConfig()
+	cfg.DataDir = *dataDir
+	node, err := app.NewNode(cfg, adapters.SystemClock{}, adapters.StdLogger{})
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	header, err := app.ExportArchive(node.Chain, f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d blocks and %d snapshots (tip height=%d hash=%s) to %s\n",
+		header.Blocks, header.Snapshots, header.TipHeight, header.TipHash, fs.Arg(0))
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dataDir := fs.String("data-dir", app.DefaultConfig().DataDir, "data directory to import into")
+	every := fs.Int("progress", 100, "report progress every N blocks")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: xenium import [-data-dir dir] [-progress n] <archive>")
+	}
+	cfg := app.DefaultConfig()
+	cfg.DataDir = *dataDir
+	node, err := app.NewNode(cfg, adapters.SystemClock{}, adapters.StdLogger{})
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	result, err := app.ImportArchive(node.Chain, f, func(p app.ImportProgress) {
+		if *every > 0 && (p.Done%*every == 0 || p.Done == p.Total) {
+			fmt.Printf("Import progress %d/%d (imported=%d skipped=%d)\n", p.Done, p.Total, p.Imported, p.Skipped)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := node.SaveGenesis(); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d blocks, skipped %d known, tip=%s\n", result.Imported, result.Skipped, result.TipHash)
+	return nil
+}
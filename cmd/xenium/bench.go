@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"xenium/adapters"
+	"xenium/app"
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+// benchStats follows the canonical chain through the event bus. Blocks and
+// transactions reverted by a reorg are taken back out.
+type benchStats struct {
+	mu             sync.Mutex
+	blocks         int
+	txs            int
+	slots          uint64
+	reorgs         int
+	maxDepth       int
+	rejectedReorgs int
+}
+
+func (s *benchStats) onEvent(ev core.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch e := ev.(type) {
+	case core.CanonicalTipChanged:
+		for _, b := range e.Reverted {
+			s.blocks--
+			s.txs -= len(b.Transactions)
+		}
+		for _, b := range e.Applied {
+			s.blocks++
+			s.txs += len(b.Transactions)
+		}
+		s.slots = e.Head.Slot
+		if e.Depth > 0 {
+			s.reorgs++
+			s.maxDepth = max(s.maxDepth, e.Depth)
+		}
+	case core.ReorgRejected:
+		s.rejectedReorgs++
+	}
+}
+
+// competingProducer stands in for a producer that missed the latest blocks.
+// It branches off an ancestor up to maxBack blocks below the tip with a
+// branch that either ties the canonical one or outweighs it by a block, so
+// fork choice sees both rejected and winning candidates.
+type competingProducer struct {
+	chain   *core.Blockchain
+	rng     *rand.Rand
+	rate    float64
+	maxBack int
+}
+
+// maybeFork forks with probability rate and returns the number of slots
+// the branch used.
+func (c *competingProducer) maybeFork() (int, error) {
+	if c.rate <= 0 || c.rng.Float64() >= c.rate {
+		return 0, nil
+	}
+	back := 1 + c.rng.Intn(c.maxBack)
+	parent := c.chain.Tip()
+	for i := 0; i < back && parent.Index > 0; i++ {
+		var err error
+		if parent, err = c.chain.GetBlockByHash(parent.PrevHash); err != nil {
+			return 0, err
+		}
+	}
+	length := back + c.rng.Intn(2)
+	hash := parent.Hash
+	for i := 0; i < length; i++ {
+		var err error
+		if hash, err = c.chain.AddBlockExternal(hash, nil); err != nil {
+			return i, err
+		}
+	}
+	return length, nil
+}
+
+// runBench floods signed transfers through an in-memory node and reports
+// throughput, block time and reorg frequency. Its validators all run in the
+// one node, so forks only happen with -fork-rate, which adds a competing
+// producer.
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	duration := fs.Duration("duration", 10*time.Second, "how long to submit transactions")
+	validators := fs.Int("validators", 4, "number of local validators")
+	senders := fs.Int("senders", 256, "number of funded sender accounts")
+	workers := fs.Int("workers", 4, "goroutines signing and submitting transfers")
+	blockTxs := fs.Int("block-txs", 500, "maximum transactions per block")
+	backlog := fs.Int("backlog", 1000, "pause submitting while the mempool holds this many transactions")
+	realtime := fs.Bool("realtime", false, "produce on the wall clock (one block per slot) instead of as fast as blocks fill")
+	forkRate := fs.Float64("fork-rate", 0, "probability after each block that a competing producer forks off a recent ancestor")
+	forkDepth := fs.Int("fork-depth", 2, "how many blocks below the tip a competing fork may start")
+	seed := fs.Int64("seed", 1, "seed for the competing producer")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *validators < 1 || *senders < 1 || *workers < 1 || *blockTxs < 1 || *backlog < 1 || *forkDepth < 1 || *duration <= 0 {
+		return errors.New("duration, validators, senders, workers, block-txs, backlog and fork-depth must be positive")
+	}
+	if *forkRate < 0 || *forkRate > 1 {
+		return errors.New("fork-rate must be between 0 and 1")
+	}
+	if *forkRate > 0 && *realtime {
+		return errors.New("fork-rate needs the simulated clock and cannot be used with -realtime")
+	}
+	if *workers > *senders {
+		*workers = *senders
+	}
+
+	cfg := app.DefaultConfig()
+	cfg.DataDir = ""
+	cfg.ChainID = "xenium-bench"
+	cfg.Chain.MaxBlockTxs = *blockTxs
+	var clock ports.Clock = adapters.SystemClock{}
+	var sim *adapters.SimulatedClock
+	if !*realtime {
+		sim = adapters.NewSimulatedClock(time.Now().UnixNano(), 0)
+		clock = sim
+	}
+	node, err := app.NewNode(cfg, clock, nil)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < *validators; i++ {
+		w, err := domain.NewWallet()
+		if err != nil {
+			return err
+		}
+		if err := node.Chain.AddValidator(fmt.Sprintf("V%d", i+1), 100, w.PublicKey, w.PrivateKey); err != nil {
+			return err
+		}
+	}
+	wallets := make([]*domain.Wallet, *senders)
+	for i := range wallets {
+		if wallets[i], err = domain.NewWallet(); err != nil {
+			return err
+		}
+		node.Chain.SetBalance(wallets[i].Address, 1<<40)
+	}
+	sink, err := domain.NewWallet()
+	if err != nil {
+		return err
+	}
+
+	stats := &benchStats{}
+	sub := node.Chain.Subscribe(stats.onEvent)
+	defer node.Chain.Unsubscribe(sub)
+
+	fmt.Printf("Benchmarking %d validators, %d senders, %d workers, %d txs per block for %s (%s)\n",
+		*validators, *senders, *workers, *blockTxs, *duration, benchMode(*realtime, *forkRate))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	var submitted, rejected atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		// Each worker owns every workers-th sender, so nonces never race.
+		var own []*domain.Wallet
+		for i := w; i < len(wallets); i += *workers {
+			own = append(own, wallets[i])
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonces := make([]uint64, len(own))
+			for i := 0; ctx.Err() == nil; i = (i + 1) % len(own) {
+				if node.Chain.MempoolLen() >= *backlog {
+					time.Sleep(time.Millisecond)
+					continue
+				}
+				tx := domain.Transaction{To: sink.Address, Amount: 1, Fee: 1, Nonce: nonces[i] + 1}
+				if err := consensus.SignTransaction(own[i].PrivateKey, &tx); err != nil {
+					rejected.Add(1)
+					continue
+				}
+				if err := node.SubmitTx(tx); err != nil {
+					rejected.Add(1)
+					continue
+				}
+				nonces[i]++
+				submitted.Add(1)
+			}
+		}()
+	}
+
+	start := time.Now()
+	var runErr error
+	if *realtime {
+		if runErr = node.StartProducing(ctx); runErr == nil {
+			<-ctx.Done()
+		}
+	} else {
+		// Step one slot whenever a full block is waiting, so block time is
+		// the node's processing time rather than the slot length. Workers
+		// stop at the backlog, so a block never waits for more than that.
+		// A competing fork takes slots of its own; the clock is moved past
+		// them so the next block does not reuse a slot it signed.
+		driver := app.NewSlotDriver(node, sim)
+		rival := &competingProducer{chain: node.Chain, rng: rand.New(rand.NewSource(*seed)), rate: *forkRate, maxBack: *forkDepth}
+		slot := int64(consensus.TicksPerSlot) * (1e9 / consensus.TicksPerSecond)
+		full := min(*blockTxs, *backlog)
+		for runErr == nil && ctx.Err() == nil {
+			if node.Chain.MempoolLen() < full {
+				time.Sleep(100 * time.Microsecond)
+				continue
+			}
+			sim.Advance(slot)
+			var res app.SlotResult
+			if res, runErr = driver.Step(); runErr != nil || !res.Produced {
+				continue
+			}
+			var used int
+			used, runErr = rival.maybeFork()
+			sim.Advance(int64(used) * slot)
+		}
+	}
+	elapsed := time.Since(start)
+	cancel()
+	wg.Wait()
+	if err := errors.Join(runErr, node.Close()); err != nil {
+		return err
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	fmt.Printf("Submitted:  %d txs (%d rejected), %d left in the mempool\n", submitted.Load(), rejected.Load(), node.Chain.MempoolLen())
+	fmt.Printf("Included:   %s%d txs in %d blocks%s\n", colorGreen, stats.txs, stats.blocks, colorReset)
+	fmt.Printf("Throughput: %s%.1f TPS%s\n", colorCyan, float64(stats.txs)/elapsed.Seconds(), colorReset)
+	if stats.blocks > 0 {
+		fmt.Printf("Block time: %s mean, %.2f slots per block\n",
+			(elapsed / time.Duration(stats.blocks)).Round(time.Microsecond), float64(stats.slots)/float64(stats.blocks))
+	}
+	perHundred := 0.0
+	if stats.blocks > 0 {
+		perHundred = 100 * float64(stats.reorgs) / float64(stats.blocks)
+	}
+	color := colorGreen
+	if stats.reorgs > 0 {
+		color = colorYellow
+	}
+	fmt.Printf("Reorgs:     %s%d (%.2f per 100 blocks, max depth %d)%s, %d rejected\n",
+		color, stats.reorgs, perHundred, stats.maxDepth, colorReset, stats.rejectedReorgs)
+	return nil
+}
+
+func benchMode(realtime bool, forkRate float64) string {
+	mode := "blocks as fast as they fill"
+	if realtime {
+		mode = "wall-clock slots"
+	}
+	if forkRate > 0 {
+		mode += fmt.Sprintf(", competing forks at rate %.2f", forkRate)
+	}
+	return mode
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"xenium/app"
+)
+
+const dbUsage = "usage: xenium db <stats|check|reindex|get-block <hash|height>|rollback <height>|snapshots list> [-data-dir dir]"
+
+// exitStatus makes main exit with a specific code once a command has already
+// reported its outcome on stdout.
+type exitStatus int
+
+func (s exitStatus) Error() string { return fmt.Sprintf("exit status %d", int(s)) }
+
+// runDB writes its result as JSON and returns the process exit code: 1 when
+// the command failed and 2 when check found issues. Usage errors are returned
+// as errors instead.
+func runDB(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, errors.New(dbUsage)
+	}
+	sub := args[0]
+	fs := flag.NewFlagSet("db "+sub, flag.ContinueOnError)
+	dataDir := fs.String("data-dir", app.DefaultConfig().DataDir, "data directory to inspect")
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return 0, err
+	}
+	if _, err := os.Stat(*dataDir); err != nil {
+		return writeDBResult(nil, err)
+	}
+
+	var result any
+	switch sub {
+	case "stats":
+		result, err = app.DBStatsFor(*dataDir)
+	case "check":
+		var report app.DBCheckReport
+		report, err = app.DBCheck(*dataDir)
+		result = report
+		if err == nil && !report.OK {
+			if _, err := writeDBResult(result, nil); err != nil {
+				return 0, err
+			}
+			return 2, nil
+		}
+	case "reindex":
+		result, err = app.DBReindex(*dataDir)
+	case "get-block":
+		if len(positional) != 1 {
+			return 0, errors.New(dbUsage)
+		}
+		result, err = app.DBGetBlock(*dataDir, positional[0])
+	case "rollback":
+		if len(positional) != 1 {
+			return 0, errors.New(dbUsage)
+		}
+		height, perr := strconv.ParseUint(positional[0], 10, 64)
+		if perr != nil {
+			return 0, fmt.Errorf("invalid height %q", positional[0])
+		}
+		result, err = app.DBRollback(*dataDir, height, app.DefaultConfig().Chain.EpochLength)
+	case "snapshots":
+		if len(positional) != 1 || positional[0] != "list" {
+			return 0, errors.New(dbUsage)
+		}
+		result, err = app.DBSnapshots(*dataDir)
+	default:
+		return 0, errors.New(dbUsage)
+	}
+	return writeDBResult(result, err)
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments and returns the positional ones in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func writeDBResult(result any, err error) (int, error) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err != nil {
+		if encErr := enc.Encode(map[string]string{"error": err.Error()}); encErr != nil {
+			return 0, encErr
+		}
+		return 1, nil
+	}
+	return 0, enc.Encode(result)
+}
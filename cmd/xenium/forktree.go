@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"xenium/app"
+	"xenium/core"
+)
+
+// runForkTree writes the block tree as Graphviz DOT or JSON, e.g.
+// xenium forktree | dot -Tsvg > tree.svg. The data dir is read without
+// starting a node; -rpc reads a running node's tree instead.
+func runForkTree(args []string) error {
+	fs := flag.NewFlagSet("forktree", flag.ContinueOnError)
+	dataDir := fs.String("data-dir", app.DefaultConfig().DataDir, "data directory to read")
+	format := fs.String("format", "dot", "output format: dot or json")
+	out := fs.String("o", "", "output file (default stdout)")
+	rpcAddr := fs.String("rpc", "", "read the tree from a running node's JSON-RPC address instead of the data dir")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("usage: xenium forktree [-data-dir dir | -rpc addr] [-format dot|json] [-o file]")
+	}
+	if *format != "dot" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	var tree core.BlockTree
+	if *rpcAddr != "" {
+		var err error
+		if tree, err = fetchBlockTree(*rpcAddr); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if tree, err = app.DBBlockTree(*dataDir, app.DefaultConfig().Chain); err != nil {
+			return err
+		}
+	}
+	if *out == "" {
+		return writeBlockTree(os.Stdout, tree, *format)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := writeBlockTree(f, tree, *format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func fetchBlockTree(addr string) (core.BlockTree, error) {
+	var tree core.BlockTree
+	body := []byte(`{"jsonrpc":"2.0","method":"getBlockTree","id":1}`)
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post("http://"+addr, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return tree, err
+	}
+	defer resp.Body.Close()
+	var reply struct {
+		Result *core.BlockTree `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return tree, fmt.Errorf("decode response: %w", err)
+	}
+	if reply.Error != nil {
+		return tree, fmt.Errorf("rpc error %d: %s", reply.Error.Code, reply.Error.Message)
+	}
+	if reply.Result == nil {
+		return tree, errors.New("empty response")
+	}
+	return *reply.Result, nil
+}
+
+func writeBlockTree(w io.Writer, tree core.BlockTree, format string) error {
+	if format == "json" {
+		return tree.WriteJSON(w)
+	}
+	return tree.WriteDOT(w)
+}
+
+func writeBlockTreeDOT(path string, chain *core.Blockchain) {
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Write block tree: %v\n", err)
+		return
+	}
+	err = chain.ExportBlockTree().WriteDOT(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Printf("Write block tree: %v\n", err)
+		return
+	}
+	fmt.Printf("Wrote block tree DOT: %s\n", path)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"sort"
@@ -22,6 +24,60 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			var status exitStatus
+			if errors.As(err, &status) {
+				os.Exit(int(status))
+			}
+			fmt.Fprintf(os.Stderr, "xenium %s: %v\n", os.Args[1], err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := runSimulation(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "xenium: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runCommand(name string, args []string) error {
+	switch name {
+	case "export":
+		return runExport(args)
+	case "import":
+		return runImport(args)
+	case "db":
+		code, err := runDB(args)
+		if err == nil && code != 0 {
+			return exitStatus(code)
+		}
+		return err
+	case "run":
+		return runNode(args)
+	case "forktree":
+		return runForkTree(args)
+	case "simulate":
+		return runSimulate(args)
+	case "bench":
+		return runBench(args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// runSimulation runs the demo. It writes the block tree only when asked
+// with -dot; the forktree command exports it from a data dir.
+func runSimulation(args []string) error {
+	fs := flag.NewFlagSet("xenium", flag.ContinueOnError)
+	dotPath := fs.String("dot", "", "write the final block tree as Graphviz DOT to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
 	cfg := app.DefaultConfig()
 	cfg.Chain.DeterministicPoH = true
 	cfg.Chain.PoHSeed = 1
@@ -58,6 +114,9 @@ func main() {
 	xenium.SetBalance(alice.Address, 200)
 	xenium.SetBalance(bob.Address, 100)
 	xenium.SetBalance(charlie.Address, 80)
+	if err := node.SaveGenesis(); err != nil {
+		panic(err)
+	}
 
 	printStakeSummary("Stake (initial)", xenium)
 
@@ -80,14 +139,14 @@ func main() {
 	}
 
 	// Fork simulation: build on block 1 instead of tip.
-	if len(xenium.Chain) < 3 {
+	oldChain := xenium.CanonicalChain()
+	if len(oldChain) < 3 {
 		panic("chain too short for fork simulation")
 	}
 	beforeTip := xenium.CanonicalTipHash()
 	beforeScore := xenium.ScoreTip(beforeTip)
-	oldChain := append([]domain.Block(nil), xenium.Chain...)
 
-	parentHash := xenium.Chain[1].Hash
+	parentHash := oldChain[1].Hash
 	tx3 := makeTx(charlie, alice.Address, 10, 1, nonces)
 	if err := consensus.VerifyTransactionSignature(tx3); err != nil {
 		panic(err)
@@ -107,16 +166,20 @@ func main() {
 		panic(err)
 	}
 
-	// Additional forks for multi-candidate evaluation.
-	_, err = xenium.AddBlockExternal(parentHash, []domain.Transaction{makeTx(bob, alice.Address, 2, 1, nonces)})
+	// Additional forks for multi-candidate evaluation. Each branches off
+	// block 1, where only Alice has sent a transaction, so each counts its
+	// own nonces.
+	branchNonces := func() map[string]uint64 { return map[string]uint64{alice.Address: 1} }
+	_, err = xenium.AddBlockExternal(parentHash, []domain.Transaction{makeTx(bob, alice.Address, 2, 1, branchNonces())})
 	if err != nil {
 		panic(err)
 	}
-	forkB, err := xenium.AddBlockExternal(parentHash, []domain.Transaction{makeTx(alice, bob.Address, 3, 1, nonces)})
+	forkBNonces := branchNonces()
+	forkB, err := xenium.AddBlockExternal(parentHash, []domain.Transaction{makeTx(alice, bob.Address, 3, 1, forkBNonces)})
 	if err != nil {
 		panic(err)
 	}
-	_, err = xenium.AddBlockExternal(forkB, []domain.Transaction{makeTx(bob, charlie.Address, 1, 1, nonces)})
+	_, err = xenium.AddBlockExternal(forkB, []domain.Transaction{makeTx(bob, charlie.Address, 1, 1, forkBNonces)})
 	if err != nil {
 		panic(err)
 	}
@@ -124,7 +187,7 @@ func main() {
 	forkScore := xenium.ScoreTip(forkHash)
 	afterTip := xenium.CanonicalTipHash()
 	afterScore := xenium.ScoreTip(afterTip)
-	newChain := xenium.Chain
+	newChain := xenium.CanonicalChain()
 	reorgDepth := computeReorgDepth(oldChain, newChain)
 	reorged := beforeTip != afterTip
 	requiredDelta, actualDelta := xenium.WeightDeltaRequired(beforeScore.CumulativeWeight, forkScore.CumulativeWeight)
@@ -213,7 +276,7 @@ func main() {
 	}
 	fmt.Println("=========================")
 
-	for _, block := range xenium.Chain {
+	for _, block := range xenium.CanonicalChain() {
 		fmt.Printf("Index: %d\n", block.Index)
 		fmt.Printf("Hash: %s\n", block.Hash)
 		fmt.Printf("PrevHash: %s\n", block.PrevHash)
@@ -228,7 +291,11 @@ func main() {
 
 	printStakeSummary("Stake (final)", xenium)
 	writeEpochSnapshotCSV("epoch_snapshots.csv", xenium)
+	if *dotPath != "" {
+		writeBlockTreeDOT(*dotPath, xenium)
+	}
 	printForkTimeline(xenium)
+	return nil
 }
 
 func computeReorgDepth(oldChain []domain.Block, newChain []domain.Block) int {
@@ -248,13 +315,14 @@ func computeReorgDepth(oldChain []domain.Block, newChain []domain.Block) int {
 
 func printStakeSummary(label string, chain *core.Blockchain) {
 	fmt.Println(label)
-	names := make([]string, 0, len(chain.Validators))
-	for name := range chain.Validators {
+	validators := chain.Validators()
+	names := make([]string, 0, len(validators))
+	for name := range validators {
 		names = append(names, name)
 	}
 	sort.Strings(names)
 	for _, name := range names {
-		fmt.Printf("%s: %d\n", name, chain.Validators[name].Stake)
+		fmt.Printf("%s: %d\n", name, validators[name].Stake)
 	}
 	fmt.Println()
 }
@@ -291,7 +359,7 @@ func printForkTimeline(chain *core.Blockchain) {
 	for _, tips := range tipsBySlot {
 		sort.Strings(tips)
 	}
-	for _, block := range chain.Chain {
+	for _, block := range chain.CanonicalChain() {
 		line := fmt.Sprintf("slot %d canonical=%s", block.Slot, block.Hash)
 		if tips, ok := tipsBySlot[block.Slot]; ok && len(tips) > 0 {
 			line += " tips=" + strings.Join(tips, ",")
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"xenium/adapters"
+	"xenium/app"
+	"xenium/domain"
+)
+
+// runNode starts a long-running node and shuts it down in order on SIGINT
+// or SIGTERM.
+func runNode(args []string) error {
+	def := app.DefaultConfig()
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	dataDir := fs.String("data-dir", def.DataDir, "data directory")
+	listen := fs.String("listen", "", "p2p listen address, e.g. :30333 (empty disables networking)")
+	rpcAddr := fs.String("rpc", "", "JSON-RPC listen address, e.g. 127.0.0.1:8545 (empty disables RPC)")
+	metricsAddr := fs.String("metrics", "", "Prometheus metrics listen address, e.g. 127.0.0.1:9100 (empty disables metrics)")
+	explorerAddr := fs.String("explorer", "", "block explorer listen address, e.g. 127.0.0.1:8080 (empty disables the explorer)")
+	txIndex := fs.Bool("txindex", false, "maintain a transaction and address index for getTransaction and getAccountHistory")
+	bootnodes := fs.String("bootnodes", "", "comma-separated peer addresses dialed on startup")
+	produce := fs.Bool("produce", false, "produce blocks in slots led by a locally held validator key")
+	dev := fs.Bool("dev", false, "in-memory chain with a single fresh local validator; implies -produce")
+	healthEvery := fs.Duration("health-interval", 0, "print service health at this interval (0 disables)")
+	logLevel := fs.String("log-level", def.Log.Level, "log level: debug, info, warn, error or critical")
+	logFormat := fs.String("log-format", def.Log.Format, "log format: json or text")
+	logFile := fs.String("log-file", "", "write logs to this file, rotated by size (empty logs to stdout)")
+	logModules := fs.String("log-modules", "", "per-module levels, e.g. core=debug,network=warn")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := def
+	cfg.DataDir = *dataDir
+	cfg.Node.ListenAddr = *listen
+	cfg.Node.RPCAddr = *rpcAddr
+	cfg.Node.MetricsAddr = *metricsAddr
+	cfg.Node.ExplorerAddr = *explorerAddr
+	cfg.Node.TxIndex = *txIndex
+	cfg.Node.Produce = *produce || *dev
+	if *bootnodes != "" {
+		cfg.Network.Bootnodes = strings.Split(*bootnodes, ",")
+	}
+	if *dev {
+		cfg.DataDir = ""
+	}
+	cfg.Log.Level = *logLevel
+	cfg.Log.Format = *logFormat
+	cfg.Log.File = *logFile
+	if *logModules != "" {
+		cfg.Log.Modules = make(map[string]string)
+		for _, kv := range strings.Split(*logModules, ",") {
+			module, level, ok := strings.Cut(kv, "=")
+			if !ok {
+				return fmt.Errorf("invalid -log-modules entry %q, want module=level", kv)
+			}
+			cfg.Log.Modules[strings.TrimSpace(module)] = strings.TrimSpace(level)
+		}
+	}
+	logger, err := adapters.NewSlogLogger(cfg.Log, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+	node, err := app.NewNode(cfg, adapters.SystemClock{}, logger)
+	if err != nil {
+		return err
+	}
+	if *dev {
+		wallet, err := domain.NewWallet()
+		if err != nil {
+			return err
+		}
+		if err := node.Chain.AddValidator("Dev", 100, wallet.PublicKey, wallet.PrivateKey); err != nil {
+			return err
+		}
+	}
+	if err := node.RegisterServices(cfg.Node); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := node.Start(ctx); err != nil {
+		return err
+	}
+	printHealth(node.Services.Health())
+
+	var tick <-chan time.Time
+	if *healthEvery > 0 {
+		ticker := time.NewTicker(*healthEvery)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-tick:
+			printHealth(node.Services.Health())
+		}
+	}
+	stop()
+
+	fmt.Fprintln(os.Stderr, "Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Node.ShutdownTimeout)
+	defer cancel()
+	err = node.Shutdown(shutdownCtx)
+	printHealth(node.Services.Health())
+	return err
+}
+
+func printHealth(h app.Health) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return
+	}
+	fmt.Println(string(data))
+}
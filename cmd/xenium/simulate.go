@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"xenium/adapters"
+	"xenium/ports"
+	"xenium/scenario"
+)
+
+// runSimulate plays scenario files (glob patterns allowed) and reports PASS or FAIL for each.
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "log chain activity while running")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: xenium simulate [-v] <scenario.json>...")
+	}
+	var logger ports.Logger = adapters.NopLogger{}
+	if *verbose {
+		logger = adapters.StdLogger{}
+	}
+	// Patterns are expanded here as well, since not every shell does it.
+	var paths []string
+	for _, arg := range fs.Args() {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			matches = []string{arg}
+		}
+		paths = append(paths, matches...)
+	}
+	failed := 0
+	for _, path := range paths {
+		s, err := scenario.Load(path)
+		if err != nil {
+			return err
+		}
+		report, err := scenario.Run(s, logger)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("%sFAIL%s %s: %v\n", colorRed, colorReset, path, err)
+		case !report.Passed():
+			failed++
+			fmt.Printf("%sFAIL%s %s (%s)\n", colorRed, colorReset, path, s.Name)
+			for _, f := range report.Failures {
+				fmt.Printf("  %s\n", f)
+			}
+		default:
+			fmt.Printf("%sPASS%s %s (%s): %d steps, height %d, slot %d, tip by %s\n",
+				colorGreen, colorReset, path, s.Name, report.Steps, report.Height, report.Slot, report.Tip)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scenarios failed", failed, len(paths))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package consensus
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"xenium/domain"
+)
+
+func BenchmarkHashBlock(b *testing.B) {
+	root := TxRoot(nil)
+	poh := PoHHashHex(HashPoHSeed(1))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		HashBlock(uint64(i), root, uint64(i), uint64(i)*TicksPerSlot, "Alice", root, root, poh)
+	}
+}
+
+func BenchmarkTxRoot(b *testing.B) {
+	for _, n := range []int{100, 10000} {
+		txs := make([]domain.Transaction, n)
+		for i := range txs {
+			txs[i] = domain.Transaction{From: "a" + strconv.Itoa(i%100), To: "b", Amount: 1, Fee: 1, Nonce: uint64(i)}
+		}
+		b.Run(fmt.Sprintf("txs=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				TxRoot(txs)
+			}
+		})
+	}
+}
+
+func BenchmarkStateRoot(b *testing.B) {
+	for _, n := range []int{1000, 100000, 1000000} {
+		state := make(map[string]domain.Account, n)
+		for i := 0; i < n; i++ {
+			state["acct"+strconv.Itoa(i)] = domain.Account{Balance: i, Nonce: uint64(i % 7)}
+		}
+		b.Run(fmt.Sprintf("accounts=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				StateRoot(state)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"xenium/domain"
+)
+
+func totalBalance(state map[string]domain.Account) int {
+	total := 0
+	for _, a := range state {
+		total += a.Balance
+	}
+	return total
+}
+
+// FuzzApplyTransactions decodes the input as a batch of transfers between
+// three funded accounts and a fresh one. Whatever the batch, the input
+// state must not change, and a successful batch must conserve balances
+// (fees go to the producer, or are burned without one) and bump each
+// sender's nonce once per transaction.
+func FuzzApplyTransactions(f *testing.F) {
+	f.Add([]byte{0, 1, 10, 1, 0}, 100, true)
+	f.Add([]byte{0, 1, 10, 1, 0, 0, 2, 20, 0, 0, 1, 0, 5, 2, 0}, 50, false)
+	f.Add([]byte{2, 3, 200, 0, 1}, 10, true)
+	f.Fuzz(func(t *testing.T, data []byte, funds int, withProducer bool) {
+		if funds < 0 || funds > 1<<30 {
+			t.Skip()
+		}
+		addrs := []string{"a", "b", "c", "d"}
+		state := map[string]domain.Account{
+			"a": {Balance: funds},
+			"b": {Balance: funds / 2, Nonce: 3},
+			"c": {Balance: 7},
+		}
+		before := make(map[string]domain.Account, len(state))
+		for k, v := range state {
+			before[k] = v
+		}
+		nonces := map[string]uint64{"a": 0, "b": 3, "c": 0}
+		var txs []domain.Transaction
+		for i := 0; i+5 <= len(data) && len(txs) < 64; i += 5 {
+			from := addrs[int(data[i])%3]
+			// Mostly the next nonce, sometimes a gap or a replay.
+			nonce := nonces[from] + 1
+			switch data[i+4] % 4 {
+			case 1:
+				nonce++
+			case 2:
+				nonce--
+			}
+			nonces[from] = nonce
+			txs = append(txs, domain.Transaction{
+				From:   from,
+				To:     addrs[int(data[i+1])%4],
+				Amount: int(int8(data[i+2])),
+				Fee:    int(int8(data[i+3])) % 8,
+				Nonce:  nonce,
+			})
+		}
+		producer := ""
+		if withProducer {
+			producer = "p"
+		}
+		next, err := ApplyTransactions(state, txs, producer)
+		for k, v := range before {
+			if state[k] != v || len(state) != len(before) {
+				t.Fatalf("input state modified: %v", state)
+			}
+		}
+		if err != nil {
+			return
+		}
+		fees, sent := 0, make(map[string]uint64)
+		for _, tx := range txs {
+			fees += tx.Fee
+			sent[tx.From]++
+		}
+		want := totalBalance(before)
+		if !withProducer {
+			want -= fees
+		}
+		if got := totalBalance(next); got != want {
+			t.Fatalf("balances total %d, want %d", got, want)
+		}
+		for addr, acct := range next {
+			if acct.Balance < 0 {
+				t.Fatalf("negative balance for %s: %d", addr, acct.Balance)
+			}
+			if acct.Nonce != before[addr].Nonce+sent[addr] {
+				t.Fatalf("nonce of %s is %d, want %d", addr, acct.Nonce, before[addr].Nonce+sent[addr])
+			}
+		}
+	})
+}
+
+// FuzzVerifyTransactionSignature checks that a signed transaction verifies,
+// that changing any signed field breaks it, and that arbitrary keys and
+// signatures are rejected without panicking.
+func FuzzVerifyTransactionSignature(f *testing.F) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		f.Fatalf("key: %v", err)
+	}
+	f.Add("bob", 10, 1, uint64(1), "carol", 11, 1, uint64(1), "", "")
+	f.Add("bob", 10, 1, uint64(1), "bob", 10, 2, uint64(1), "04ab", "3006020101020101")
+	f.Add("", -1, 0, uint64(0), "", -1, 0, uint64(2), "zz", "zz")
+	f.Fuzz(func(t *testing.T, to string, amount, fee int, nonce uint64, to2 string, amount2, fee2 int, nonce2 uint64, pubKey, sig string) {
+		tx := domain.Transaction{To: to, Amount: amount, Fee: fee, Nonce: nonce}
+		if err := SignTransaction(priv, &tx); err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		if err := VerifyTransactionSignature(tx); err != nil {
+			t.Fatalf("signed transaction rejected: %v", err)
+		}
+		tampered := tx
+		tampered.To, tampered.Amount, tampered.Fee, tampered.Nonce = to2, amount2, fee2, nonce2
+		if tampered != tx && VerifyTransactionSignature(tampered) == nil {
+			t.Fatalf("tampered transaction verified: %+v", tampered)
+		}
+		forged := tx
+		forged.PubKey = pubKey
+		if pubKey != tx.PubKey && VerifyTransactionSignature(forged) == nil {
+			t.Fatalf("transaction verified under foreign key %q", pubKey)
+		}
+		forged = tx
+		forged.Signature = sig
+		_ = VerifyTransactionSignature(forged)
+	})
+}
+
+// FuzzVerifyPoH builds a block gap ticks past its parent and checks that
+// VerifyPoH accepts it, returns the block's hash and tick, and rejects a
+// wrong slot, a stale tick or a corrupted hash. Gaps are bounded since
+// verification hashes every skipped tick.
+func FuzzVerifyPoH(f *testing.F) {
+	f.Add(int64(1), uint64(0), uint16(20), byte(0), "")
+	f.Add(int64(7), uint64(39), uint16(1), byte(1), "00")
+	f.Add(int64(-3), uint64(1000), uint16(333), byte(5), "not hex")
+	f.Fuzz(func(t *testing.T, seed int64, start uint64, gap uint16, corrupt byte, pohHash string) {
+		if gap == 0 || gap > 4096 || start > 1<<50 {
+			t.Skip()
+		}
+		parent := HashPoHSeed(seed)
+		hash := parent
+		tick := start + uint64(gap)
+		for i := start + 1; i <= tick; i++ {
+			hash = HashPoH(hash, i)
+		}
+		blk := domain.Block{Index: 1, Slot: tick / TicksPerSlot, Tick: tick, PoHHash: PoHHashHex(hash)}
+		gotHash, gotTick, err := VerifyPoH(parent, start, blk)
+		if err != nil || gotHash != hash || gotTick != tick {
+			t.Fatalf("valid block rejected: %v", err)
+		}
+
+		bad := blk
+		switch corrupt % 4 {
+		case 0:
+			bad.Slot++
+		case 1:
+			bad.Tick = start
+		case 2:
+			h := hash
+			h[int(corrupt)%32] ^= 1 + corrupt>>2
+			bad.PoHHash = PoHHashHex(h)
+		case 3:
+			if h, err := ParsePoHHashHex(pohHash); err == nil && h == hash {
+				return
+			}
+			bad.PoHHash = pohHash
+		}
+		if _, _, err := VerifyPoH(parent, start, bad); err == nil {
+			t.Fatalf("corrupted block accepted (mode %d): %+v", corrupt%4, bad)
+		}
+	})
+}
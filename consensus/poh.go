@@ -31,6 +31,19 @@ func (p *PoH) Slot() uint64 {
 	return p.CurrentTick / TicksPerSlot
 }
 
+// TickAt maps a wall-clock time to the PoH tick elapsed since genesis.
+func TickAt(genesisTime int64, now int64) uint64 {
+	if now <= genesisTime {
+		return 0
+	}
+	return uint64((now - genesisTime) / (1e9 / TicksPerSecond))
+}
+
+// SlotStartTime is the wall-clock time at which slot begins.
+func SlotStartTime(genesisTime int64, slot uint64) int64 {
+	return genesisTime + int64(slot*TicksPerSlot)*(1e9/TicksPerSecond)
+}
+
 func HashPoH(prev [32]byte, tick uint64) [32]byte {
 	raw := make([]byte, 0, len(prev)+16)
 	raw = append(raw, prev[:]...)
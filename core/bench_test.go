@@ -0,0 +1,185 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+const (
+	benchWallets     = 1000
+	benchNoncesEach  = 100
+	benchPoolTxs     = benchWallets * benchNoncesEach
+	benchBlockTxs    = 10
+	benchWalletFunds = 1 << 30
+)
+
+var (
+	benchOnce    sync.Once
+	benchErr     error
+	benchTxs     []domain.Transaction // wallet by wallet, nonces 1..benchNoncesEach
+	benchFunding map[string]domain.Account
+
+	benchChainsMu sync.Mutex
+	benchChains   = make(map[[2]int]*Blockchain)
+)
+
+// benchFixture signs benchPoolTxs transfers once per test binary; signing
+// dominates setup, so every benchmark shares them.
+func benchFixture(b *testing.B) ([]domain.Transaction, map[string]domain.Account) {
+	b.Helper()
+	benchOnce.Do(func() {
+		benchFunding = make(map[string]domain.Account, benchWallets)
+		benchTxs = make([]domain.Transaction, 0, benchPoolTxs)
+		for w := 0; w < benchWallets; w++ {
+			wallet, err := domain.NewWallet()
+			if err != nil {
+				benchErr = err
+				return
+			}
+			benchFunding[wallet.Address] = domain.Account{Balance: benchWalletFunds}
+			for n := 1; n <= benchNoncesEach; n++ {
+				tx := domain.Transaction{To: "sink", Amount: 1, Fee: 1, Nonce: uint64(n)}
+				if err := consensus.SignTransaction(wallet.PrivateKey, &tx); err != nil {
+					benchErr = err
+					return
+				}
+				benchTxs = append(benchTxs, tx)
+			}
+		}
+	})
+	if benchErr != nil {
+		b.Fatalf("fixture: %v", benchErr)
+	}
+	return benchTxs, benchFunding
+}
+
+// fillMempool loads txs without going through Add, which would verify and
+// re-sort on every insert.
+func fillMempool(txs []domain.Transaction) *Mempool {
+	m := NewMempool()
+	m.list = append(m.list, txs...)
+	for _, tx := range txs {
+		m.byHash[tx.Hash] = tx
+	}
+	sort.Slice(m.list, func(i, j int) bool {
+		if m.list[i].From != m.list[j].From {
+			return m.list[i].From < m.list[j].From
+		}
+		return m.list[i].Nonce < m.list[j].Nonce
+	})
+	return m
+}
+
+func BenchmarkMempoolAdd(b *testing.B) {
+	txs, _ := benchFixture(b)
+	m := fillMempool(txs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tx := txs[i%len(txs)]
+		b.StopTimer()
+		delete(m.byHash, tx.Hash)
+		for j := range m.list {
+			if m.list[j].Hash == tx.Hash {
+				m.list = append(m.list[:j], m.list[j+1:]...)
+				break
+			}
+		}
+		b.StartTimer()
+		if err := m.Add(tx); err != nil {
+			b.Fatalf("add: %v", err)
+		}
+	}
+}
+
+func BenchmarkMempoolPopForBlock(b *testing.B) {
+	txs, funding := benchFixture(b)
+	m := fillMempool(txs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		popped := m.PopForBlock(funding, 100, "producer")
+		b.StopTimer()
+		if len(popped) != 100 {
+			b.Fatalf("popped %d txs, want 100", len(popped))
+		}
+		// The lowest nonces of the first wallets went out; put them back
+		// in front so every iteration sees the same pool.
+		m.list = append(popped, m.list...)
+		for _, tx := range popped {
+			m.byHash[tx.Hash] = tx
+		}
+		b.StartTimer()
+	}
+}
+
+// newBenchChain builds a single-validator chain of the given length whose
+// blocks carry txsPerBlock fixture transfers each. Only their senders are
+// funded: the chain rebuilds state on every new tip, so idle accounts only
+// slow down setup. Chains are cached since the benchmark runner calls each
+// benchmark several times while it ramps up b.N.
+func newBenchChain(b *testing.B, blocks, txsPerBlock int) *Blockchain {
+	b.Helper()
+	benchChainsMu.Lock()
+	defer benchChainsMu.Unlock()
+	key := [2]int{blocks, txsPerBlock}
+	if bc, ok := benchChains[key]; ok {
+		return bc
+	}
+	var txs []domain.Transaction
+	if txsPerBlock > 0 {
+		txs, _ = benchFixture(b)
+		if blocks*txsPerBlock > len(txs) {
+			b.Fatalf("fixture has %d txs, need %d", len(txs), blocks*txsPerBlock)
+		}
+		txs = txs[:blocks*txsPerBlock]
+	}
+	bc := NewBlockchain(ChainConfig{
+		EpochLength:      consensus.SlotsPerEpoch,
+		DeterministicPoH: true,
+		PoHSeed:          1,
+	}, nil, nil)
+	v, err := domain.NewWallet()
+	if err != nil {
+		b.Fatalf("wallet: %v", err)
+	}
+	if err := bc.AddValidator("Alice", 100, v.PublicKey, v.PrivateKey); err != nil {
+		b.Fatalf("add validator: %v", err)
+	}
+	for _, tx := range txs {
+		bc.SetBalance(tx.From, benchWalletFunds)
+	}
+	for i := 0; i < blocks; i++ {
+		if err := bc.AddBlock(txs[i*txsPerBlock : (i+1)*txsPerBlock]); err != nil {
+			b.Fatalf("block %d: %v", i+1, err)
+		}
+	}
+	benchChains[key] = bc
+	return bc
+}
+
+func BenchmarkScoreTip(b *testing.B) {
+	for _, depth := range []int{1000, 5000} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			bc := newBenchChain(b, depth, 0)
+			tip := bc.CanonicalTipHash()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bc.ScoreTip(tip)
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyChain(b *testing.B) {
+	bc := newBenchChain(b, 1000, benchBlockTxs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := bc.VerifyChain(); err != nil {
+			b.Fatalf("verify: %v", err)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"math/rand"
 	"sort"
+	"sync"
+	"time"
 
 	"xenium/consensus"
 	"xenium/domain"
@@ -26,6 +28,8 @@ type ChainConfig struct {
 	DeterministicPoH     bool
 	PoHSeed              int64
 	MaxBlockTxs          int
+	PruneMode            PruneMode
+	PruneKeepEpochs      uint64
 }
 
 type ReorgMetrics struct {
@@ -48,65 +52,78 @@ type EpochSnapshot struct {
 	Validators map[string]uint64
 }
 
+// Blockchain is the consensus engine. All state is guarded by mu; exported
+// methods lock it themselves and return copies, so a Blockchain is safe for
+// concurrent use. Unexported methods expect the caller to hold the lock.
 type Blockchain struct {
-	Chain             []domain.Block
-	Blocks            map[string]domain.Block
-	Parents           map[string]string
-	CanonicalTip      string
-	Validators        map[string]*domain.Validator
-	Stats             map[string]*domain.ValidatorStats
+	mu                sync.RWMutex
+	chain             []domain.Block
+	blocks            map[string]domain.Block
+	parents           map[string]string
+	canonicalTip      string
+	validators        map[string]*domain.Validator
+	stats             map[string]*domain.ValidatorStats
 	rand              *rand.Rand
 	poh               *consensus.PoH
-	State             map[string]domain.Account
-	Genesis           map[string]domain.Account
-	SlotProduced      map[uint64]string
-	SlotProducers     map[uint64]map[string]string
-	Equivocations     []EquivocationProof
-	LastProcessedSlot uint64
-	FinalizedSlot     uint64
-	Config            ChainConfig
-	ReorgStats        ReorgMetrics
-	Clock             ports.Clock
-	Logger            ports.Logger
+	state             map[string]domain.Account
+	genesis           map[string]domain.Account
+	slotProduced      map[uint64]string
+	slotProducers     map[uint64]map[string]string
+	equivocations     []EquivocationProof
+	lastProcessedSlot uint64
+	finalizedSlot     uint64
+	reorgStats        ReorgMetrics
+	config            ChainConfig
+	clock             ports.Clock
+	logger            ports.Logger
+	mempool           *Mempool
 	currentEpoch      uint64
 	snapshots         map[uint64]*EpochSnapshot
 	blockStore        ports.BlockStore
 	snapshotStore     ports.SnapshotStore
-	Mempool           *Mempool
+	anchor            *domain.PruneAnchor
+	pruned            prunedHashes
+	prunedEpoch       uint64
+	genesisValidators map[string]GenesisValidator
+	genesisTime       int64
+	events            eventBus
 }
 
 func NewBlockchain(cfg ChainConfig, clock ports.Clock, logger ports.Logger) *Blockchain {
 	bc := &Blockchain{
-		Blocks:        make(map[string]domain.Block),
-		Parents:       make(map[string]string),
-		Validators:    make(map[string]*domain.Validator),
-		Stats:         make(map[string]*domain.ValidatorStats),
-		State:         make(map[string]domain.Account),
-		Genesis:       make(map[string]domain.Account),
-		SlotProduced:  make(map[uint64]string),
-		SlotProducers: make(map[uint64]map[string]string),
-		Config:        cfg,
-		Clock:         clock,
-		Logger:        ensureLogger(logger),
+		blocks:        make(map[string]domain.Block),
+		parents:       make(map[string]string),
+		validators:    make(map[string]*domain.Validator),
+		stats:         make(map[string]*domain.ValidatorStats),
+		state:         make(map[string]domain.Account),
+		genesis:       make(map[string]domain.Account),
+		slotProduced:  make(map[uint64]string),
+		slotProducers: make(map[uint64]map[string]string),
+		config:        cfg,
+		clock:         clock,
+		logger:        ensureLogger(logger),
 		snapshots:     make(map[uint64]*EpochSnapshot),
 	}
-	if bc.Config.MaxReorgDepth == 0 {
-		bc.Config.MaxReorgDepth = 2
+	if bc.config.MaxReorgDepth == 0 {
+		bc.config.MaxReorgDepth = 2
 	}
-	if bc.Config.FinalitySlots == 0 {
-		bc.Config.FinalitySlots = 2
+	if bc.config.FinalitySlots == 0 {
+		bc.config.FinalitySlots = 2
 	}
-	if bc.Config.EpochLength == 0 {
-		bc.Config.EpochLength = consensus.SlotsPerEpoch
+	if bc.config.EpochLength == 0 {
+		bc.config.EpochLength = consensus.SlotsPerEpoch
 	}
-	if bc.Config.MaxBlockTxs == 0 {
-		bc.Config.MaxBlockTxs = 100
+	if bc.config.MaxBlockTxs == 0 {
+		bc.config.MaxBlockTxs = 100
+	}
+	if bc.config.PruneMode == "" {
+		bc.config.PruneMode = PruneArchive
 	}
 	seed := int64(0)
-	if bc.Config.DeterministicPoH {
-		seed = bc.Config.PoHSeed
-	} else if bc.Clock != nil {
-		seed = bc.Clock.UnixNano()
+	if bc.config.DeterministicPoH {
+		seed = bc.config.PoHSeed
+	} else if bc.clock != nil {
+		seed = bc.clock.UnixNano()
 	}
 	bc.rand = rand.New(rand.NewSource(seed))
 	genesis := bc.createGenesisBlock()
@@ -114,68 +131,108 @@ func NewBlockchain(cfg ChainConfig, clock ports.Clock, logger ports.Logger) *Blo
 	bc.poh = consensus.NewPoH(pohSeed)
 
 	bc.insertBlock(genesis)
-	bc.CanonicalTip = genesis.Hash
+	bc.canonicalTip = genesis.Hash
 	bc.rebuildCanonicalChain()
 	bc.updateFinality()
-	bc.Mempool = NewMempool()
+	bc.mempool = NewMempool()
 	return bc
 }
 
 func (bc *Blockchain) SetBalance(address string, amount int) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
 	if amount < 0 {
 		return
 	}
-	acct := bc.State[address]
+	acct := bc.state[address]
 	acct.Balance = amount
-	bc.State[address] = acct
-	if len(bc.Chain) <= 1 {
-		bc.Genesis[address] = acct
+	bc.state[address] = acct
+	if len(bc.chain) <= 1 {
+		bc.genesis[address] = acct
 	}
 }
 
 func (bc *Blockchain) AddTx(tx domain.Transaction) error {
-	if bc.Mempool == nil {
-		bc.Mempool = NewMempool()
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.mempool == nil {
+		bc.mempool = NewMempool()
+	}
+	if err := bc.mempool.Add(tx); err != nil {
+		return err
 	}
-	return bc.Mempool.Add(tx)
+	bc.publish(TxPending{Tx: tx})
+	return nil
 }
 
 func (bc *Blockchain) SelectTxsForBlock(max int, producerAddr string) []domain.Transaction {
-	if bc.Mempool == nil {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if bc.mempool == nil {
 		return nil
 	}
-	return bc.Mempool.PopForBlock(bc.State, max, producerAddr)
+	return bc.mempool.PopForBlock(bc.state, max, producerAddr)
+}
+
+// PruneMempool drops mempool transactions whose nonce has already been used
+// at the canonical tip and returns how many were removed.
+func (bc *Blockchain) PruneMempool() int {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	dropped := bc.mempool.prune(bc.state)
+	for _, tx := range dropped {
+		bc.publish(TxDropped{Tx: tx, Reason: DropStaleNonce})
+	}
+	return len(dropped)
 }
 
 func (bc *Blockchain) AddValidator(name string, stake int, pubKey string, priv *ecdsa.PrivateKey) error {
-	return consensus.AddValidator(bc.Validators, bc.Stats, name, stake, pubKey, priv)
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if err := consensus.AddValidator(bc.validators, bc.stats, name, stake, pubKey, priv); err != nil {
+		return err
+	}
+	if len(bc.chain) <= 1 {
+		bc.recordGenesisValidator(name, stake, pubKey)
+	}
+	return nil
 }
 
 func (bc *Blockchain) AddBlock(txs []domain.Transaction) error {
-	if len(bc.Validators) == 0 {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if len(bc.validators) == 0 {
 		return errors.New("no validators available")
 	}
 	if bc.poh == nil {
 		return errors.New("poh not initialized")
 	}
-	prev := bc.Blocks[bc.CanonicalTip]
+	prev := bc.blocks[bc.canonicalTip]
 	_, _ = bc.poh.Tick(consensus.TicksPerSlot)
 	slot := bc.poh.Slot()
 	bc.ensureSnapshotForSlot(slot)
 	validator := bc.leaderForSlot(slot)
+	_, err := bc.produceOnTip(prev, slot, validator, txs)
+	return err
+}
+
+// produceOnTip builds, signs and accepts a block for slot on top of prev,
+// using the current PoH state as the block's tick and hash.
+func (bc *Blockchain) produceOnTip(prev domain.Block, slot uint64, validator string, txs []domain.Transaction) (domain.Block, error) {
+	start := time.Now()
 	producerAddr := bc.validatorRewardAddress(validator)
 
-	if len(txs) == 0 && bc.Mempool != nil {
-		txs = bc.SelectTxsForBlock(bc.Config.MaxBlockTxs, producerAddr)
+	if len(txs) == 0 && bc.mempool != nil {
+		txs = bc.mempool.PopForBlock(bc.state, bc.config.MaxBlockTxs, producerAddr)
 	}
 	if err := consensus.VerifyTransactions(txs); err != nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
-		return err
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
+		return domain.Block{}, err
 	}
-	nextState, err := consensus.ApplyTransactions(bc.State, txs, producerAddr)
+	nextState, err := consensus.ApplyTransactions(bc.state, txs, producerAddr)
 	if err != nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
-		return err
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
+		return domain.Block{}, err
 	}
 
 	txRoot := consensus.TxRoot(txs)
@@ -194,43 +251,48 @@ func (bc *Blockchain) AddBlock(txs []domain.Transaction) error {
 		Transactions: txs,
 	}
 
-	v := bc.Validators[validator]
+	v := bc.validators[validator]
 	if v == nil || v.PrivKey == nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
-		return errors.New("missing validator signing key")
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
+		return domain.Block{}, errors.New("missing validator signing key")
 	}
 	if err := consensus.SignBlock(v.PrivKey, &block); err != nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
-		return err
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
+		return domain.Block{}, err
 	}
 
-	if err := bc.verifyBlockOnAccept(prev, block, bc.State); err != nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
-		return err
+	if err := bc.verifyBlockOnAccept(prev, block, bc.state); err != nil {
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
+		return domain.Block{}, err
 	}
 
 	if bc.blockStore != nil {
 		if err := bc.blockStore.SaveBlock(block); err != nil {
-			return err
+			return domain.Block{}, err
 		}
 	}
 
 	eqErr := bc.registerSlotProducer(block)
 	bc.insertBlock(block)
+	bc.publish(BlockAccepted{Block: block, ImportTime: time.Since(start)})
 	bc.updateCanonical(block.Hash)
-	consensus.RewardValidator(bc.Validators, validator)
+	consensus.RewardValidator(bc.validators, validator)
 	bc.processMissedSlots(bc.chainTipSlot())
-	return eqErr
+	bc.maybePrune()
+	return block, eqErr
 }
 
 func (bc *Blockchain) AddBlockExternal(prevHash string, txs []domain.Transaction) (string, error) {
-	if len(bc.Validators) == 0 {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	start := time.Now()
+	if len(bc.validators) == 0 {
 		return "", errors.New("no validators available")
 	}
 	if bc.poh == nil {
 		return "", errors.New("poh not initialized")
 	}
-	parent, ok := bc.Blocks[prevHash]
+	parent, ok := bc.blocks[prevHash]
 	if !ok {
 		return "", errors.New("unknown parent hash")
 	}
@@ -242,7 +304,7 @@ func (bc *Blockchain) AddBlockExternal(prevHash string, txs []domain.Transaction
 	producerAddr := bc.validatorRewardAddress(validator)
 
 	if err := consensus.VerifyTransactions(txs); err != nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
 		return "", err
 	}
 	parentState, err := bc.stateAtTip(prevHash)
@@ -251,7 +313,7 @@ func (bc *Blockchain) AddBlockExternal(prevHash string, txs []domain.Transaction
 	}
 	nextState, err := consensus.ApplyTransactions(parentState, txs, producerAddr)
 	if err != nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
 		return "", err
 	}
 
@@ -271,18 +333,18 @@ func (bc *Blockchain) AddBlockExternal(prevHash string, txs []domain.Transaction
 		Transactions: txs,
 	}
 
-	v := bc.Validators[validator]
+	v := bc.validators[validator]
 	if v == nil || v.PrivKey == nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
 		return "", errors.New("missing validator signing key")
 	}
 	if err := consensus.SignBlock(v.PrivKey, &block); err != nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
 		return "", err
 	}
 
 	if err := bc.verifyBlockOnAccept(parent, block, parentState); err != nil {
-		consensus.SlashValidator(bc.Validators, validator, consensus.SlashPenalty)
+		bc.slash(validator, slot, consensus.SlashPenalty, SlashInvalidBlock)
 		return "", err
 	}
 
@@ -294,48 +356,52 @@ func (bc *Blockchain) AddBlockExternal(prevHash string, txs []domain.Transaction
 
 	eqErr := bc.registerSlotProducer(block)
 	bc.insertBlock(block)
+	bc.publish(BlockAccepted{Block: block, ImportTime: time.Since(start)})
 	bc.updateCanonical(block.Hash)
-	consensus.RewardValidator(bc.Validators, validator)
+	consensus.RewardValidator(bc.validators, validator)
 	bc.processMissedSlots(bc.chainTipSlot())
+	bc.maybePrune()
 	return block.Hash, eqErr
 }
 
 func (bc *Blockchain) VerifyChain() error {
-	if len(bc.Chain) == 0 {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if len(bc.chain) == 0 {
 		return errors.New("empty chain")
 	}
-	genesis := bc.Chain[0]
+	genesis := bc.chain[0]
 	expectedGenesisHash := consensus.HashBlock(genesis.Index, genesis.PrevHash, genesis.Slot, genesis.Tick, genesis.Validator, genesis.TxRoot, genesis.StateRoot, genesis.PoHHash)
 	if genesis.Hash != expectedGenesisHash {
 		return errors.New("invalid genesis hash")
 	}
+	if genesis.PrevHash != "GENESIS" && !bc.isAnchor(genesis.Hash) {
+		return errors.New("chain does not start at genesis or prune anchor")
+	}
 	expectedHash, err := consensus.ParsePoHHashHex(genesis.PoHHash)
 	if err != nil {
 		return err
 	}
 	expectedTick := genesis.Tick
 	seenSlots := make(map[uint64]string)
-	state := make(map[string]domain.Account)
-	for k, v := range bc.Genesis {
-		state[k] = v
-	}
-	for i := 1; i < len(bc.Chain); i++ {
-		prev := bc.Chain[i-1]
-		cur := bc.Chain[i]
+	state := bc.baseState()
+	for i := 1; i < len(bc.chain); i++ {
+		prev := bc.chain[i-1]
+		cur := bc.chain[i]
 
 		if err := consensus.VerifyBlockLink(prev, cur); err != nil {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return err
 		}
 		nextHash, nextTick, err := consensus.VerifyPoH(expectedHash, expectedTick, cur)
 		if err != nil {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return err
 		}
 		expectedHash = nextHash
 		expectedTick = nextTick
 		if err := consensus.VerifyBlockHash(cur); err != nil {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return err
 		}
 		snap := bc.snapshotForSlot(cur.Slot)
@@ -343,38 +409,38 @@ func (bc *Blockchain) VerifyChain() error {
 			return errors.New("missing epoch snapshot for slot " + itoa(int(cur.Slot)))
 		}
 		if err := consensus.VerifyLeaderSnapshot(cur.Slot, cur.Validator, snap.Validators); err != nil {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return err
 		}
 		if prevValidator, ok := seenSlots[cur.Slot]; ok && prevValidator != "" {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return errors.New("double produce at slot " + itoa(int(cur.Slot)))
 		}
 		seenSlots[cur.Slot] = cur.Validator
-		v, err := consensus.VerifyValidator(cur.Validator, bc.Validators, i)
+		v, err := consensus.VerifyValidator(cur.Validator, bc.validators, i)
 		if err != nil {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return err
 		}
 		if err := consensus.VerifyBlockSig(cur, v); err != nil {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return err
 		}
 		if err := consensus.VerifyTransactions(cur.Transactions); err != nil {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return err
 		}
 		if consensus.TxRoot(cur.Transactions) != cur.TxRoot {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return errors.New("invalid tx root at index " + itoa(i))
 		}
 		nextState, err := consensus.ApplyTransactions(state, cur.Transactions, bc.validatorRewardAddress(cur.Validator))
 		if err != nil {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return err
 		}
 		if consensus.StateRoot(nextState) != cur.StateRoot {
-			consensus.SlashValidator(bc.Validators, cur.Validator, consensus.SlashPenalty)
+			bc.slash(cur.Validator, cur.Slot, consensus.SlashPenalty, SlashInvalidBlock)
 			return errors.New("invalid state root at index " + itoa(i))
 		}
 		state = nextState
@@ -406,7 +472,7 @@ func (bc *Blockchain) verifyBlockOnAccept(prev domain.Block, block domain.Block,
 	if consensus.StateRoot(nextState) != block.StateRoot {
 		return errors.New("invalid state root for block")
 	}
-	v := bc.Validators[block.Validator]
+	v := bc.validators[block.Validator]
 	if v == nil {
 		return errors.New("unknown validator for block")
 	}
@@ -434,58 +500,82 @@ func (bc *Blockchain) createGenesisBlock() domain.Block {
 }
 
 func (bc *Blockchain) insertBlock(block domain.Block) {
-	bc.Blocks[block.Hash] = block
-	bc.Parents[block.Hash] = block.PrevHash
+	bc.blocks[block.Hash] = block
+	bc.parents[block.Hash] = block.PrevHash
 }
 
 func (bc *Blockchain) updateCanonical(tipHash string) bool {
-	if bc.CanonicalTip == "" {
-		bc.CanonicalTip = tipHash
+	if bc.canonicalTip == "" {
+		bc.canonicalTip = tipHash
 		bc.rebuildCanonicalChain()
 		bc.updateFinality()
 		return true
 	}
-	currentScore := bc.scoreTip(bc.CanonicalTip)
+	currentScore := bc.scoreTip(bc.canonicalTip)
 	newScore := bc.scoreTip(tipHash)
 	if betterScore(newScore, currentScore) {
 		newChain, err := bc.chainFromTip(tipHash)
 		if err != nil {
 			return false
 		}
-		reorgDepth, divergeSlot := computeReorgDepthAndSlot(bc.Chain, newChain)
-		if bc.FinalizedSlot > 0 && divergeSlot <= bc.FinalizedSlot {
-			bc.ReorgStats.Critical++
-			bc.Logger.Criticalf("Reorg attempt touching finalized slot=%d", divergeSlot)
+		reorgDepth, divergeSlot := computeReorgDepthAndSlot(bc.chain, newChain)
+		rejected := ReorgRejected{Tip: bc.canonicalTip, Candidate: tipHash, Depth: reorgDepth, DivergeSlot: divergeSlot}
+		log := func() ports.Logger {
+			return bc.consensusLog().With(
+				"depth", reorgDepth,
+				"from_slot", divergeSlot,
+				"to_slot", newChain[len(newChain)-1].Slot,
+				"old_tip", bc.canonicalTip,
+				"new_tip", tipHash,
+			)
+		}
+		if bc.finalizedSlot > 0 && divergeSlot <= bc.finalizedSlot {
+			bc.reorgStats.Critical++
+			log().With("finalized_slot", bc.finalizedSlot).Criticalf("Reorg attempt touching finalized slot")
+			rejected.Reason = RejectFinalized
+			bc.publish(rejected)
 			return false
 		}
-		if reorgDepth > bc.Config.MaxReorgDepth {
-			bc.ReorgStats.Error++
-			bc.Logger.Errorf("Reorg rejected depth=%d exceeds max=%d (fromSlot=%d toSlot=%d)",
-				reorgDepth, bc.Config.MaxReorgDepth, divergeSlot, newChain[len(newChain)-1].Slot)
+		if reorgDepth > bc.config.MaxReorgDepth {
+			bc.reorgStats.Error++
+			log().With("max_depth", bc.config.MaxReorgDepth).Errorf("Reorg rejected: depth exceeds max")
+			rejected.Reason = RejectMaxDepth
+			bc.publish(rejected)
 			return false
 		}
 		if !bc.weightDeltaSatisfied(currentScore.CumulativeWeight, newScore.CumulativeWeight) {
 			required, actual := bc.weightDeltaRequired(currentScore.CumulativeWeight, newScore.CumulativeWeight)
-			bc.ReorgStats.Error++
-			bc.Logger.Errorf("Reorg rejected: insufficient weight delta required=%d actual=%d minDeltaPct=%d",
-				required, actual, bc.Config.MinReorgWeightDeltaP)
+			bc.reorgStats.Error++
+			log().With("required", required, "actual", actual, "min_delta_pct", bc.config.MinReorgWeightDeltaP).
+				Errorf("Reorg rejected: insufficient weight delta")
+			rejected.Reason = RejectWeightDelta
+			bc.publish(rejected)
 			return false
 		}
 		if reorgDepth > 0 {
 			if reorgDepth > 1 {
-				bc.ReorgStats.Warn++
-				bc.Logger.Warnf("Reorg detected depth=%d (fromSlot=%d toSlot=%d)",
-					reorgDepth, divergeSlot, newChain[len(newChain)-1].Slot)
+				bc.reorgStats.Warn++
+				log().Warnf("Reorg detected")
 			} else {
-				bc.ReorgStats.Info++
-				bc.Logger.Infof("Reorg detected depth=%d (fromSlot=%d toSlot=%d)",
-					reorgDepth, divergeSlot, newChain[len(newChain)-1].Slot)
+				bc.reorgStats.Info++
+				log().Infof("Reorg detected")
 			}
 		}
-		bc.CanonicalTip = tipHash
-		bc.Chain = newChain
+		diverge := len(bc.chain) - reorgDepth
+		change := CanonicalTipChanged{
+			OldTip:      bc.canonicalTip,
+			NewTip:      tipHash,
+			Head:        newChain[len(newChain)-1],
+			Depth:       reorgDepth,
+			DivergeSlot: divergeSlot,
+			Reverted:    append([]domain.Block(nil), bc.chain[diverge:]...),
+			Applied:     append([]domain.Block(nil), newChain[diverge:]...),
+		}
+		bc.canonicalTip = tipHash
+		bc.chain = newChain
 		bc.rebuildSlotMap()
 		bc.rebuildStateFromCanonical()
+		bc.publishTipChange(change)
 		bc.updateFinality()
 		return true
 	}
@@ -493,18 +583,22 @@ func (bc *Blockchain) updateCanonical(tipHash string) bool {
 }
 
 func (bc *Blockchain) scoreTip(tipHash string) ChainScore {
-	block, ok := bc.Blocks[tipHash]
+	block, ok := bc.blocks[tipHash]
 	if !ok {
 		return ChainScore{}
 	}
 	weight := uint64(0)
 	cur := block
 	for {
+		if bc.isAnchor(cur.Hash) {
+			weight += bc.anchor.Weight
+			break
+		}
 		weight += bc.snapshotStake(cur.Slot, cur.Validator)
 		if cur.PrevHash == "GENESIS" {
 			break
 		}
-		parent, ok := bc.Blocks[cur.PrevHash]
+		parent, ok := bc.blocks[cur.PrevHash]
 		if !ok {
 			break
 		}
@@ -514,7 +608,7 @@ func (bc *Blockchain) scoreTip(tipHash string) ChainScore {
 }
 
 func (bc *Blockchain) scoreTipCached(tipHash string, cache map[string]uint64) ChainScore {
-	block, ok := bc.Blocks[tipHash]
+	block, ok := bc.blocks[tipHash]
 	if !ok {
 		return ChainScore{}
 	}
@@ -526,10 +620,13 @@ func (bc *Blockchain) cumulativeWeightCached(hash string, cache map[string]uint6
 	if v, ok := cache[hash]; ok {
 		return v
 	}
-	block, ok := bc.Blocks[hash]
+	block, ok := bc.blocks[hash]
 	if !ok {
 		return 0
 	}
+	if bc.isAnchor(hash) {
+		return bc.anchor.Weight
+	}
 	weight := bc.snapshotStake(block.Slot, block.Validator)
 	if block.PrevHash != "GENESIS" {
 		weight += bc.cumulativeWeightCached(block.PrevHash, cache)
@@ -549,14 +646,14 @@ func betterScore(a ChainScore, b ChainScore) bool {
 }
 
 func (bc *Blockchain) weightDeltaSatisfied(oldWeight uint64, newWeight uint64) bool {
-	if bc.Config.MinReorgWeightDeltaP <= 0 {
+	if bc.config.MinReorgWeightDeltaP <= 0 {
 		return true
 	}
 	if newWeight <= oldWeight {
 		return false
 	}
 	active := bc.activeStake()
-	minDelta := (active * uint64(bc.Config.MinReorgWeightDeltaP)) / 100
+	minDelta := (active * uint64(bc.config.MinReorgWeightDeltaP)) / 100
 	if minDelta == 0 {
 		minDelta = 1
 	}
@@ -565,7 +662,7 @@ func (bc *Blockchain) weightDeltaSatisfied(oldWeight uint64, newWeight uint64) b
 
 func (bc *Blockchain) weightDeltaRequired(oldWeight uint64, newWeight uint64) (uint64, uint64) {
 	active := bc.activeStake()
-	minDelta := (active * uint64(bc.Config.MinReorgWeightDeltaP)) / 100
+	minDelta := (active * uint64(bc.config.MinReorgWeightDeltaP)) / 100
 	if minDelta == 0 {
 		minDelta = 1
 	}
@@ -577,6 +674,8 @@ func (bc *Blockchain) weightDeltaRequired(oldWeight uint64, newWeight uint64) (u
 }
 
 func (bc *Blockchain) WeightDeltaRequired(oldWeight uint64, newWeight uint64) (uint64, uint64) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
 	return bc.weightDeltaRequired(oldWeight, newWeight)
 }
 
@@ -589,19 +688,19 @@ func (bc *Blockchain) activeStake() uint64 {
 }
 
 func (bc *Blockchain) rebuildCanonicalChain() {
-	if bc.CanonicalTip == "" {
-		bc.Chain = nil
+	if bc.canonicalTip == "" {
+		bc.chain = nil
 		return
 	}
 	var chain []domain.Block
-	curHash := bc.CanonicalTip
+	curHash := bc.canonicalTip
 	for {
-		cur, ok := bc.Blocks[curHash]
+		cur, ok := bc.blocks[curHash]
 		if !ok {
 			break
 		}
 		chain = append(chain, cur)
-		if cur.PrevHash == "GENESIS" {
+		if cur.PrevHash == "GENESIS" || bc.isAnchor(curHash) {
 			break
 		}
 		curHash = cur.PrevHash
@@ -609,57 +708,57 @@ func (bc *Blockchain) rebuildCanonicalChain() {
 	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
 		chain[i], chain[j] = chain[j], chain[i]
 	}
-	bc.Chain = chain
+	bc.chain = chain
 	bc.rebuildSlotMap()
 	bc.rebuildStateFromCanonical()
 }
 
 func (bc *Blockchain) rebuildSlotMap() {
-	bc.SlotProduced = make(map[uint64]string)
-	for i := 1; i < len(bc.Chain); i++ {
-		b := bc.Chain[i]
-		bc.SlotProduced[b.Slot] = b.Validator
+	bc.slotProduced = make(map[uint64]string)
+	for i := 1; i < len(bc.chain); i++ {
+		b := bc.chain[i]
+		bc.slotProduced[b.Slot] = b.Validator
 	}
 }
 
 func (bc *Blockchain) rebuildStateFromCanonical() {
-	state := make(map[string]domain.Account)
-	for k, v := range bc.Genesis {
-		state[k] = v
-	}
-	for i := 1; i < len(bc.Chain); i++ {
-		next, err := consensus.ApplyTransactions(state, bc.Chain[i].Transactions, bc.validatorRewardAddress(bc.Chain[i].Validator))
+	state := bc.baseState()
+	for i := 1; i < len(bc.chain); i++ {
+		next, err := consensus.ApplyTransactions(state, bc.chain[i].Transactions, bc.validatorRewardAddress(bc.chain[i].Validator))
 		if err != nil {
 			return
 		}
 		state = next
 	}
-	bc.State = state
+	bc.state = state
 }
 
 func (bc *Blockchain) updateFinality() {
 	tipSlot := bc.chainTipSlot()
-	if tipSlot < bc.Config.FinalitySlots {
+	if tipSlot < bc.config.FinalitySlots {
 		return
 	}
-	finalized := tipSlot - bc.Config.FinalitySlots
-	if finalized > bc.FinalizedSlot {
-		bc.FinalizedSlot = finalized
+	finalized := tipSlot - bc.config.FinalitySlots
+	if finalized > bc.finalizedSlot {
+		bc.finalizedSlot = finalized
+		if block, ok := bc.finalizedBlock(finalized); ok {
+			bc.publish(SlotFinalized{Slot: finalized, Hash: block.Hash})
+		}
 	}
 }
 
 func (bc *Blockchain) chainTipSlot() uint64 {
-	if len(bc.Chain) == 0 {
+	if len(bc.chain) == 0 {
 		return 0
 	}
-	return bc.Chain[len(bc.Chain)-1].Slot
+	return bc.chain[len(bc.chain)-1].Slot
 }
 
 func (bc *Blockchain) epochForSlot(slot uint64) uint64 {
-	if bc.Config.EpochLength == 0 {
+	if bc.config.EpochLength == 0 {
 		return 0
 	}
-	return slot / bc.Config.EpochLength
+	return slot / bc.config.EpochLength
 }
 
 func (bc *Blockchain) ensureSnapshotForSlot(slot uint64) {
@@ -671,27 +770,34 @@ func (bc *Blockchain) ensureSnapshot(epoch uint64) {
 	if _, ok := bc.snapshots[epoch]; ok {
 		return
 	}
+	snap := bc.takeSnapshot(epoch)
+	bc.snapshots[epoch] = snap
+	bc.currentEpoch = epoch
+	if bc.snapshotStore != nil {
+		stateRoot := consensus.StateRoot(bc.state)
+		_ = bc.snapshotStore.SaveEpochSnapshot(epoch, stateRoot, snap.Validators)
+	}
+}
+
+// takeSnapshot computes epoch's stake snapshot from the current validators
+// without recording it.
+func (bc *Blockchain) takeSnapshot(epoch uint64) *EpochSnapshot {
 	snap := &EpochSnapshot{
 		Epoch:      epoch,
 		Validators: make(map[string]uint64),
 	}
-	epochSlot := epoch * bc.Config.EpochLength
-	for _, v := range bc.Validators {
+	epochSlot := epoch * bc.config.EpochLength
+	for _, v := range bc.validators {
 		if v.Stake < consensus.MinStake {
 			continue
 		}
-		if consensus.IsJailed(bc.Stats, v.Name, epochSlot) {
+		if consensus.IsJailed(bc.stats, v.Name, epochSlot) {
 			continue
 		}
 		snap.Validators[v.Name] = uint64(v.Stake)
 		snap.TotalStake += uint64(v.Stake)
 	}
-	bc.snapshots[epoch] = snap
-	bc.currentEpoch = epoch
-	if bc.snapshotStore != nil {
-		stateRoot := consensus.StateRoot(bc.State)
-		_ = bc.snapshotStore.SaveEpochSnapshot(epoch, stateRoot, snap.Validators)
-	}
+	return snap
 }
 
 func (bc *Blockchain) snapshotForSlot(slot uint64) *EpochSnapshot {
@@ -701,6 +807,8 @@ func (bc *Blockchain) snapshotForSlot(slot uint64) *EpochSnapshot {
 }
 
 func (bc *Blockchain) GetEpochSnapshot(slot uint64) EpochSnapshot {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
 	snap := bc.snapshotForSlot(slot)
 	if snap == nil {
 		return EpochSnapshot{}
@@ -716,7 +824,29 @@ func (bc *Blockchain) GetEpochSnapshot(slot uint64) EpochSnapshot {
 	return out
 }
 
+// PeekEpochSnapshot returns the snapshot covering slot only if it has
+// already been taken. Unlike GetEpochSnapshot it never creates one.
+func (bc *Blockchain) PeekEpochSnapshot(slot uint64) (EpochSnapshot, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	snap, ok := bc.snapshots[bc.epochForSlot(slot)]
+	if !ok || snap == nil {
+		return EpochSnapshot{}, false
+	}
+	out := EpochSnapshot{
+		Epoch:      snap.Epoch,
+		TotalStake: snap.TotalStake,
+		Validators: make(map[string]uint64, len(snap.Validators)),
+	}
+	for k, v := range snap.Validators {
+		out.Validators[k] = v
+	}
+	return out, true
+}
+
 func (bc *Blockchain) GetAllEpochSnapshots() []EpochSnapshot {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	if len(bc.snapshots) == 0 {
 		return nil
 	}
@@ -761,48 +891,49 @@ func (bc *Blockchain) leaderForSlot(slot uint64) string {
 }
 
 func (bc *Blockchain) processMissedSlots(targetSlot uint64) {
-	if targetSlot <= bc.LastProcessedSlot {
+	if targetSlot <= bc.lastProcessedSlot {
 		return
 	}
-	for slot := bc.LastProcessedSlot + 1; slot <= targetSlot; slot++ {
+	for slot := bc.lastProcessedSlot + 1; slot <= targetSlot; slot++ {
 		leader := bc.leaderForSlot(slot)
 		if leader == "genesis" {
 			continue
 		}
-		producedBy := bc.SlotProduced[slot]
+		producedBy := bc.slotProduced[slot]
 		if producedBy != leader {
 			stats := bc.ensureStats(leader)
 			stats.MissedSlots++
+			bc.publish(SlotMissed{Slot: slot, Leader: leader})
 			if stats.MissedSlots > consensus.MaxMissedSlots {
-				consensus.SlashValidatorPercent(bc.Validators, leader, consensus.SlashPercent)
+				bc.slashPercent(leader, slot, consensus.SlashPercent, SlashMissedSlots)
 				stats.MissedSlots = 0
-				stats.JailedUntilEpoch = (slot / consensus.SlotsPerEpoch) + consensus.JailEpochs
+				bc.jail(leader, slot, SlashMissedSlots)
 			}
 		}
 	}
-	bc.LastProcessedSlot = targetSlot
+	bc.lastProcessedSlot = targetSlot
 }
 
 func (bc *Blockchain) ensureStats(name string) *domain.ValidatorStats {
-	if bc.Stats == nil {
-		bc.Stats = make(map[string]*domain.ValidatorStats)
+	if bc.stats == nil {
+		bc.stats = make(map[string]*domain.ValidatorStats)
 	}
-	stats, ok := bc.Stats[name]
+	stats, ok := bc.stats[name]
 	if !ok {
 		stats = &domain.ValidatorStats{}
-		bc.Stats[name] = stats
+		bc.stats[name] = stats
 	}
 	return stats
 }
 
 func (bc *Blockchain) registerSlotProducer(block domain.Block) error {
-	if bc.SlotProducers == nil {
-		bc.SlotProducers = make(map[uint64]map[string]string)
+	if bc.slotProducers == nil {
+		bc.slotProducers = make(map[uint64]map[string]string)
 	}
-	slotMap := bc.SlotProducers[block.Slot]
+	slotMap := bc.slotProducers[block.Slot]
 	if slotMap == nil {
 		slotMap = make(map[string]string)
-		bc.SlotProducers[block.Slot] = slotMap
+		bc.slotProducers[block.Slot] = slotMap
 	}
 	if existing, ok := slotMap[block.Validator]; ok && existing != block.Hash {
 		bc.handleEquivocation(block.Validator, block.Slot, existing, block.Hash)
@@ -819,13 +950,19 @@ func (bc *Blockchain) handleEquivocation(validator string, slot uint64, h1 strin
 		BlockA:    h1,
 		BlockB:    h2,
 	}
-	bc.Equivocations = append(bc.Equivocations, proof)
+	bc.equivocations = append(bc.equivocations, proof)
+	bc.publish(EquivocationDetected{Proof: proof})
 	stats := bc.ensureStats(validator)
 	stats.Slashed = true
-	consensus.SlashValidatorPercent(bc.Validators, validator, consensus.SlashPercent)
-	stats.JailedUntilEpoch = (slot / consensus.SlotsPerEpoch) + consensus.JailEpochs
-	bc.Logger.Errorf("Equivocation detected validator=%s slot=%d block1=%s block2=%s jailedUntil=%d",
-		validator, slot, h1, h2, stats.JailedUntilEpoch)
+	bc.slashPercent(validator, slot, consensus.SlashPercent, SlashEquivocation)
+	bc.jail(validator, slot, SlashEquivocation)
+	bc.consensusLog().With(
+		"validator", validator,
+		"slot", slot,
+		"block_a", h1,
+		"block_b", h2,
+		"jailed_until_epoch", stats.JailedUntilEpoch,
+	).Errorf("Equivocation detected")
 }
 
 func (bc *Blockchain) stateAtTip(tipHash string) (map[string]domain.Account, error) {
@@ -833,10 +970,7 @@ func (bc *Blockchain) stateAtTip(tipHash string) (map[string]domain.Account, err
 	if err != nil {
 		return nil, err
 	}
-	state := make(map[string]domain.Account)
-	for k, v := range bc.Genesis {
-		state[k] = v
-	}
+	state := bc.baseState()
 	for i := 1; i < len(chain); i++ {
 		next, err := consensus.ApplyTransactions(state, chain[i].Transactions, bc.validatorRewardAddress(chain[i].Validator))
 		if err != nil {
@@ -854,12 +988,15 @@ func (bc *Blockchain) chainFromTip(tipHash string) ([]domain.Block, error) {
 	var chain []domain.Block
 	curHash := tipHash
 	for {
-		cur, ok := bc.Blocks[curHash]
+		cur, ok := bc.blocks[curHash]
 		if !ok {
+			if bc.anchor != nil && len(chain) > 0 && chain[len(chain)-1].Index <= bc.anchor.Index+1 {
+				return nil, ErrPruned
+			}
 			return nil, errors.New("missing block in chain")
 		}
 		chain = append(chain, cur)
-		if cur.PrevHash == "GENESIS" {
+		if cur.PrevHash == "GENESIS" || bc.isAnchor(curHash) {
 			break
 		}
 		curHash = cur.PrevHash
@@ -893,24 +1030,34 @@ func computeReorgDepthAndSlot(oldChain []domain.Block, newChain []domain.Block)
 }
 
 func (bc *Blockchain) CanonicalTipHash() string {
-	return bc.CanonicalTip
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.canonicalTip
 }
 
 func (bc *Blockchain) ScoreTip(tipHash string) ChainScore {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
 	return bc.scoreTip(tipHash)
 }
 
 func (bc *Blockchain) GetReorgStats() ReorgMetrics {
-	return bc.ReorgStats
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.reorgStats
 }
 
 func (bc *Blockchain) PrintReorgStats() {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	fmt.Printf("ReorgStats: INFO=%d WARN=%d ERROR=%d CRITICAL=%d\n",
-		bc.ReorgStats.Info, bc.ReorgStats.Warn, bc.ReorgStats.Error, bc.ReorgStats.Critical)
+		bc.reorgStats.Info, bc.reorgStats.Warn, bc.reorgStats.Error, bc.reorgStats.Critical)
 }
 
 func (bc *Blockchain) ResetReorgStats() {
-	bc.ReorgStats = ReorgMetrics{}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.reorgStats = ReorgMetrics{}
 }
 
 type ValidatorSummary struct {
@@ -930,20 +1077,22 @@ type ForkCandidate struct {
 }
 
 func (bc *Blockchain) GetValidatorSummaries() []ValidatorSummary {
-	names := make([]string, 0, len(bc.Validators))
-	for name := range bc.Validators {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	names := make([]string, 0, len(bc.validators))
+	for name := range bc.validators {
 		names = append(names, name)
 	}
 	sort.Strings(names)
 
 	produced := make(map[string]uint64, len(names))
-	for _, v := range bc.SlotProduced {
+	for _, v := range bc.slotProduced {
 		produced[v]++
 	}
 
 	out := make([]ValidatorSummary, 0, len(names))
 	for _, name := range names {
-		stats := bc.Stats[name]
+		stats := bc.stats[name]
 		var missed uint64
 		var slashed bool
 		var jailed uint64
@@ -971,18 +1120,20 @@ func (bc *Blockchain) GetValidatorSummaries() []ValidatorSummary {
 }
 
 func (bc *Blockchain) GetForkCandidates() []ForkCandidate {
-	if len(bc.Blocks) == 0 {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if len(bc.blocks) == 0 {
 		return nil
 	}
-	hasChild := make(map[string]bool, len(bc.Blocks))
-	for _, parent := range bc.Parents {
+	hasChild := make(map[string]bool, len(bc.blocks))
+	for _, parent := range bc.parents {
 		if parent != "" && parent != "GENESIS" {
 			hasChild[parent] = true
 		}
 	}
-	weightCache := make(map[string]uint64, len(bc.Blocks))
+	weightCache := make(map[string]uint64, len(bc.blocks))
 	candidates := make([]ForkCandidate, 0)
-	for hash, block := range bc.Blocks {
+	for hash, block := range bc.blocks {
 		if hash == "" {
 			continue
 		}
@@ -1009,6 +1160,12 @@ func (bc *Blockchain) GetForkCandidates() []ForkCandidate {
 	return candidates
 }
 
+// consensusLog is the logger for fork-choice, finality and slashing
+// decisions.
+func (bc *Blockchain) consensusLog() ports.Logger {
+	return ports.ForModule(bc.logger, ports.ModuleConsensus)
+}
+
 func ensureLogger(l ports.Logger) ports.Logger {
 	if l == nil {
 		return nopLogger{}
@@ -1017,7 +1174,7 @@ func ensureLogger(l ports.Logger) ports.Logger {
 }
 
 func (bc *Blockchain) validatorRewardAddress(name string) string {
-	v := bc.Validators[name]
+	v := bc.validators[name]
 	if v == nil || v.PubKey == "" {
 		return ""
 	}
@@ -1029,16 +1186,34 @@ func (bc *Blockchain) validatorRewardAddress(name string) string {
 }
 
 func (bc *Blockchain) SetStorage(blockStore ports.BlockStore, snapshotStore ports.SnapshotStore) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
 	bc.blockStore = blockStore
 	bc.snapshotStore = snapshotStore
 }
 
+// FlushStorage forces buffered block and snapshot writes to disk.
+func (bc *Blockchain) FlushStorage() error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	for _, store := range []any{bc.blockStore, bc.snapshotStore} {
+		if f, ok := store.(ports.Flusher); ok {
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 type nopLogger struct{}
 
-func (nopLogger) Infof(string, ...any)     {}
-func (nopLogger) Warnf(string, ...any)     {}
-func (nopLogger) Errorf(string, ...any)    {}
-func (nopLogger) Criticalf(string, ...any) {}
+func (nopLogger) Debugf(string, ...any)      {}
+func (nopLogger) Infof(string, ...any)       {}
+func (nopLogger) Warnf(string, ...any)       {}
+func (nopLogger) Errorf(string, ...any)      {}
+func (nopLogger) Criticalf(string, ...any)   {}
+func (l nopLogger) With(...any) ports.Logger { return l }
 
 func itoa(v int) string {
 	const digits = "0123456789"
@@ -24,14 +24,14 @@ func newTestChain(t *testing.T) *Blockchain {
 
 func buildBlock(t *testing.T, bc *Blockchain, validator string, signKey *ecdsa.PrivateKey, txs []domain.Transaction) (domain.Block, domain.Block) {
 	t.Helper()
-	prev := bc.Blocks[bc.CanonicalTip]
+	prev := bc.blocks[bc.canonicalTip]
 	_, _ = bc.poh.Tick(consensus.TicksPerSlot)
 	slot := bc.poh.Slot()
 	bc.ensureSnapshotForSlot(slot)
 	if validator == "" {
 		validator = bc.leaderForSlot(slot)
 	}
-	nextState, err := consensus.ApplyTransactions(bc.State, txs, bc.validatorRewardAddress(validator))
+	nextState, err := consensus.ApplyTransactions(bc.state, txs, bc.validatorRewardAddress(validator))
 	if err != nil {
 		t.Fatalf("apply txs: %v", err)
 	}
@@ -75,7 +75,7 @@ func TestVerifyBlockOnAcceptRejectsInvalidTx(t *testing.T) {
 	tx.Signature = "00" // corrupt signature
 
 	prev, block := buildBlock(t, bc, "", validator.PrivateKey, []domain.Transaction{tx})
-	if err := bc.verifyBlockOnAccept(prev, block, bc.State); err == nil {
+	if err := bc.verifyBlockOnAccept(prev, block, bc.state); err == nil {
 		t.Fatalf("expected invalid tx to be rejected")
 	}
 }
@@ -107,7 +107,7 @@ func TestVerifyBlockOnAcceptRejectsInvalidStateRoot(t *testing.T) {
 		t.Fatalf("resign block: %v", err)
 	}
 
-	err = bc.verifyBlockOnAccept(prev, block, bc.State)
+	err = bc.verifyBlockOnAccept(prev, block, bc.state)
 	if err == nil || !strings.Contains(err.Error(), "invalid state root") {
 		t.Fatalf("expected invalid state root error, got: %v", err)
 	}
@@ -131,7 +131,7 @@ func TestVerifyBlockOnAcceptRejectsWrongLeader(t *testing.T) {
 		t.Fatalf("add validator: %v", err)
 	}
 
-	prev := bc.Blocks[bc.CanonicalTip]
+	prev := bc.blocks[bc.canonicalTip]
 	_, _ = bc.poh.Tick(consensus.TicksPerSlot)
 	slot := bc.poh.Slot()
 	bc.ensureSnapshotForSlot(slot)
@@ -144,7 +144,7 @@ func TestVerifyBlockOnAcceptRejectsWrongLeader(t *testing.T) {
 		wrongKey = bob.PrivateKey
 	}
 
-	nextState, err := consensus.ApplyTransactions(bc.State, nil, "")
+	nextState, err := consensus.ApplyTransactions(bc.state, nil, "")
 	if err != nil {
 		t.Fatalf("apply txs: %v", err)
 	}
@@ -163,7 +163,7 @@ func TestVerifyBlockOnAcceptRejectsWrongLeader(t *testing.T) {
 		t.Fatalf("sign block: %v", err)
 	}
 
-	err = bc.verifyBlockOnAccept(prev, block, bc.State)
+	err = bc.verifyBlockOnAccept(prev, block, bc.state)
 	if err == nil || !strings.Contains(err.Error(), "wrong leader") {
 		t.Fatalf("expected wrong leader error, got: %v", err)
 	}
@@ -186,7 +186,7 @@ func TestVerifyBlockOnAcceptRejectsInvalidPrevHash(t *testing.T) {
 		t.Fatalf("resign block: %v", err)
 	}
 
-	err = bc.verifyBlockOnAccept(prev, block, bc.State)
+	err = bc.verifyBlockOnAccept(prev, block, bc.state)
 	if err == nil || !strings.Contains(err.Error(), "invalid prev hash") {
 		t.Fatalf("expected invalid prev hash error, got: %v", err)
 	}
@@ -212,7 +212,7 @@ func TestVerifyBlockOnAcceptRejectsInvalidBlockSignature(t *testing.T) {
 		t.Fatalf("resign block: %v", err)
 	}
 
-	err = bc.verifyBlockOnAccept(prev, block, bc.State)
+	err = bc.verifyBlockOnAccept(prev, block, bc.state)
 	if err == nil || !strings.Contains(err.Error(), "invalid block signature") {
 		t.Fatalf("expected invalid block signature error, got: %v", err)
 	}
@@ -236,7 +236,7 @@ func TestVerifyBlockOnAcceptRejectsWrongPubKeySignature(t *testing.T) {
 		t.Fatalf("add validator: %v", err)
 	}
 
-	prev := bc.Blocks[bc.CanonicalTip]
+	prev := bc.blocks[bc.canonicalTip]
 	_, _ = bc.poh.Tick(consensus.TicksPerSlot)
 	slot := bc.poh.Slot()
 	bc.ensureSnapshotForSlot(slot)
@@ -256,7 +256,7 @@ func TestVerifyBlockOnAcceptRejectsWrongPubKeySignature(t *testing.T) {
 		t.Fatalf("sign block: %v", err)
 	}
 
-	err = bc.verifyBlockOnAccept(prev, block, bc.State)
+	err = bc.verifyBlockOnAccept(prev, block, bc.state)
 	if err == nil || !strings.Contains(err.Error(), "invalid block signature") {
 		t.Fatalf("expected invalid block signature error, got: %v", err)
 	}
@@ -289,7 +289,7 @@ func TestVerifyBlockOnAcceptRejectsInvalidTxRoot(t *testing.T) {
 		t.Fatalf("resign block: %v", err)
 	}
 
-	err = bc.verifyBlockOnAccept(prev, block, bc.State)
+	err = bc.verifyBlockOnAccept(prev, block, bc.state)
 	if err == nil || !strings.Contains(err.Error(), "invalid tx root") {
 		t.Fatalf("expected invalid tx root error, got: %v", err)
 	}
@@ -338,12 +338,12 @@ func TestVerifyBlockOnAcceptRejectsExternalForkPrevMismatch(t *testing.T) {
 		t.Fatalf("add external block: %v", err)
 	}
 
-	prev := bc.Blocks[bc.CanonicalTip]
+	prev := bc.blocks[bc.canonicalTip]
 	_, _ = bc.poh.Tick(consensus.TicksPerSlot)
 	slot := bc.poh.Slot()
 	bc.ensureSnapshotForSlot(slot)
 	validator := bc.leaderForSlot(slot)
-	v := bc.Validators[validator]
+	v := bc.validators[validator]
 	if v == nil {
 		t.Fatalf("missing leader validator")
 	}
@@ -370,7 +370,7 @@ func TestVerifyBlockOnAcceptRejectsExternalForkPrevMismatch(t *testing.T) {
 		t.Fatalf("sign block: %v", err)
 	}
 
-	err = bc.verifyBlockOnAccept(prev, block, bc.State)
+	err = bc.verifyBlockOnAccept(prev, block, bc.state)
 	if err == nil || !strings.Contains(err.Error(), "invalid prev hash") {
 		t.Fatalf("expected invalid prev hash error, got: %v", err)
 	}
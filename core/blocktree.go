@@ -0,0 +1,186 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"xenium/domain"
+)
+
+// BlockTreeNode is one known block in a BlockTree. Finalized blocks are
+// canonical blocks at or below the finalized slot.
+type BlockTreeNode struct {
+	Hash             string `json:"hash"`
+	Parent           string `json:"parent"`
+	Height           uint64 `json:"height"`
+	Slot             uint64 `json:"slot"`
+	Validator        string `json:"validator"`
+	TxCount          int    `json:"tx_count"`
+	CumulativeWeight uint64 `json:"cumulative_weight"`
+	Canonical        bool   `json:"canonical"`
+	Finalized        bool   `json:"finalized"`
+	Tip              bool   `json:"tip"`
+}
+
+// BlockTree is every block the chain knows, forks included, ordered by
+// height and then hash. After pruning the root is the prune anchor and its
+// parent is not part of the tree.
+type BlockTree struct {
+	CanonicalTip  string          `json:"canonical_tip"`
+	FinalizedSlot uint64          `json:"finalized_slot"`
+	Nodes         []BlockTreeNode `json:"nodes"`
+}
+
+// ExportBlockTree only reads the chain. Epochs without a snapshot yet are
+// weighted from the stakes ensureSnapshot would record, without storing it.
+func (bc *Blockchain) ExportBlockTree() BlockTree {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	blocks := make([]domain.Block, 0, len(bc.blocks))
+	for _, b := range bc.blocks {
+		blocks = append(blocks, b)
+	}
+	taken := make(map[uint64]*EpochSnapshot)
+	stake := func(slot uint64, validator string) uint64 {
+		epoch := bc.epochForSlot(slot)
+		snap, ok := bc.snapshots[epoch]
+		if !ok {
+			if snap, ok = taken[epoch]; !ok {
+				snap = bc.takeSnapshot(epoch)
+				taken[epoch] = snap
+			}
+		}
+		if snap == nil {
+			return 0
+		}
+		return snap.Validators[validator]
+	}
+	return NewBlockTree(blocks, bc.canonicalTip, bc.finalizedSlot, bc.anchor, stake)
+}
+
+// NewBlockTree builds the tree of blocks with tip as the canonical tip. stake
+// returns the weight a validator's block adds in a slot; a non-nil anchor
+// roots the weights of a pruned chain.
+func NewBlockTree(blocks []domain.Block, tip string, finalizedSlot uint64, anchor *domain.PruneAnchor, stake func(slot uint64, validator string) uint64) BlockTree {
+	byHash := make(map[string]domain.Block, len(blocks))
+	hasChild := make(map[string]bool, len(blocks))
+	for _, b := range blocks {
+		byHash[b.Hash] = b
+		hasChild[b.PrevHash] = true
+	}
+	canonical := make(map[string]bool)
+	for cur, ok := byHash[tip]; ok && !canonical[cur.Hash]; cur, ok = byHash[cur.PrevHash] {
+		canonical[cur.Hash] = true
+	}
+	weights := make(map[string]uint64, len(blocks))
+	var weight func(hash string) uint64
+	weight = func(hash string) uint64 {
+		if v, ok := weights[hash]; ok {
+			return v
+		}
+		b, ok := byHash[hash]
+		if !ok {
+			return 0
+		}
+		if anchor != nil && anchor.Hash == hash {
+			return anchor.Weight
+		}
+		w := stake(b.Slot, b.Validator)
+		if b.PrevHash != "GENESIS" {
+			w += weight(b.PrevHash)
+		}
+		weights[hash] = w
+		return w
+	}
+	tree := BlockTree{CanonicalTip: tip, FinalizedSlot: finalizedSlot, Nodes: make([]BlockTreeNode, 0, len(byHash))}
+	for hash, b := range byHash {
+		if hash == "" {
+			continue
+		}
+		tree.Nodes = append(tree.Nodes, BlockTreeNode{
+			Hash:             hash,
+			Parent:           b.PrevHash,
+			Height:           b.Index,
+			Slot:             b.Slot,
+			Validator:        b.Validator,
+			TxCount:          len(b.Transactions),
+			CumulativeWeight: weight(hash),
+			Canonical:        canonical[hash],
+			Finalized:        canonical[hash] && finalizedSlot > 0 && b.Slot <= finalizedSlot,
+			Tip:              !hasChild[hash],
+		})
+	}
+	sort.Slice(tree.Nodes, func(i, j int) bool {
+		if tree.Nodes[i].Height != tree.Nodes[j].Height {
+			return tree.Nodes[i].Height < tree.Nodes[j].Height
+		}
+		return tree.Nodes[i].Hash < tree.Nodes[j].Hash
+	})
+	return tree
+}
+
+func (t BlockTree) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// WriteDOT renders the tree as a Graphviz digraph, parents to children.
+// Canonical blocks are filled, finalized ones darker, and the canonical
+// tip is drawn with a double border.
+func (t BlockTree) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	known := make(map[string]bool, len(t.Nodes))
+	for _, n := range t.Nodes {
+		known[n.Hash] = true
+	}
+	fmt.Fprintln(bw, "digraph blocktree {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, `  node [shape=box, style="rounded,filled", fillcolor=white, fontname="monospace", fontsize=10];`)
+	for _, n := range t.Nodes {
+		attrs := "label=" + dotQuote(fmt.Sprintf("#%d slot %d\n%s\n%s\nweight %d  txs %d", n.Height, n.Slot, shortHash(n.Hash), n.Validator, n.CumulativeWeight, n.TxCount))
+		switch {
+		case n.Finalized:
+			attrs += ", fillcolor=palegreen3"
+		case n.Canonical:
+			attrs += ", fillcolor=palegreen"
+		}
+		if n.Hash == t.CanonicalTip {
+			attrs += ", peripheries=2, penwidth=2"
+		} else if n.Tip {
+			attrs += ", color=firebrick"
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", dotQuote(n.Hash), attrs)
+	}
+	for _, n := range t.Nodes {
+		if !known[n.Parent] {
+			continue
+		}
+		style := ""
+		if n.Canonical {
+			style = " [penwidth=2]"
+		}
+		fmt.Fprintf(bw, "  %s -> %s%s;\n", dotQuote(n.Parent), dotQuote(n.Hash), style)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote quotes s as a DOT string, escaping only quotes and backslashes.
+// Newlines become the \n label line break.
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func shortHash(h string) string {
+	if len(h) <= 12 {
+		return h
+	}
+	return h[:12]
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"xenium/domain"
+)
+
+func TestExportBlockTree(t *testing.T) {
+	bc := newTestChain(t)
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	first, _ := bc.GetBlockByHeight(1)
+	fork, err := bc.AddBlockExternal(first.Hash, nil)
+	if err != nil {
+		t.Fatalf("fork block: %v", err)
+	}
+
+	snapshots := len(bc.GetAllEpochSnapshots())
+	tree := bc.ExportBlockTree()
+	if got := len(bc.GetAllEpochSnapshots()); got != snapshots {
+		t.Fatalf("export took %d epoch snapshots", got-snapshots)
+	}
+	if len(tree.Nodes) != 6 || tree.CanonicalTip != bc.CanonicalTipHash() || tree.FinalizedSlot != bc.FinalizedSlot() {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+	byHash := make(map[string]BlockTreeNode)
+	var tips, canonical int
+	for i, n := range tree.Nodes {
+		byHash[n.Hash] = n
+		if i > 0 && n.Height < tree.Nodes[i-1].Height {
+			t.Fatalf("nodes not ordered by height")
+		}
+		if n.Tip {
+			tips++
+		}
+		if n.Canonical {
+			canonical++
+		}
+		if n.Finalized && (!n.Canonical || n.Slot > tree.FinalizedSlot) {
+			t.Fatalf("block %s marked finalized", n.Hash)
+		}
+		if p, ok := byHash[n.Parent]; ok && n.CumulativeWeight <= p.CumulativeWeight {
+			t.Fatalf("weight of %s does not grow from its parent", n.Hash)
+		}
+	}
+	if tips != 2 || canonical != 5 {
+		t.Fatalf("expected 2 tips and 5 canonical blocks, got %d and %d", tips, canonical)
+	}
+	if n := byHash[fork]; n.Canonical || !n.Tip || n.Parent != first.Hash || n.Height != 2 {
+		t.Fatalf("unexpected fork node %+v", n)
+	}
+	if !byHash[first.Hash].Finalized {
+		t.Fatalf("expected block 1 to be finalized at slot %d", tree.FinalizedSlot)
+	}
+
+	var dot bytes.Buffer
+	if err := tree.WriteDOT(&dot); err != nil {
+		t.Fatalf("dot: %v", err)
+	}
+	out := dot.String()
+	for _, want := range []string{
+		"digraph blocktree {",
+		`"` + first.Hash + `" -> "` + fork + `";`,
+		`"` + tree.CanonicalTip + `" [label=`,
+		"peripheries=2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, " -> "); n != 5 {
+		t.Fatalf("expected 5 edges, got %d", n)
+	}
+
+	var js bytes.Buffer
+	if err := tree.WriteJSON(&js); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	var decoded BlockTree
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || len(decoded.Nodes) != 6 || decoded.Nodes[0] != tree.Nodes[0] {
+		t.Fatalf("json round trip: %v", err)
+	}
+}
+
+func TestWriteDOTQuoting(t *testing.T) {
+	tree := BlockTree{CanonicalTip: `a"b`, Nodes: []BlockTreeNode{
+		{Hash: `a\b`, Validator: `Al"ice`},
+		{Hash: `a"b`, Parent: `a\b`, Canonical: true},
+	}}
+	var dot bytes.Buffer
+	if err := tree.WriteDOT(&dot); err != nil {
+		t.Fatalf("dot: %v", err)
+	}
+	out := dot.String()
+	for _, want := range []string{
+		`"a\\b" -> "a\"b" [penwidth=2];`,
+		`label="#0 slot 0\na\\b\nAl\"ice\nweight 0  txs 0"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+}
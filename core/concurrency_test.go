@@ -0,0 +1,142 @@
+package core
+
+import (
+	"sync"
+	"testing"
+
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+// hammerReads queries the chain through every read API until stop closes.
+func hammerReads(bc *Blockchain, addr string, stop <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		tip := bc.Tip()
+		_ = bc.ScoreTip(tip.Hash)
+		_, _ = bc.GetBlockByHash(tip.PrevHash)
+		_, _ = bc.GetBlockByHeight(tip.Index / 2)
+		_, _ = bc.CanonicalRange(0, 8)
+		_, _ = bc.StateAt(tip.Hash)
+		_ = bc.Account(addr)
+		_ = bc.Accounts()
+		_ = bc.Validators()
+		_, _ = bc.ValidatorStats("Alice")
+		_ = bc.GetValidatorSummaries()
+		_ = bc.GetForkCandidates()
+		_ = bc.GetEpochSnapshot(tip.Slot)
+		_ = bc.GetAllEpochSnapshots()
+		_ = bc.CanonicalChain()
+		_ = bc.Blocks()
+		_ = bc.FinalizedSlot()
+		_ = bc.Equivocations()
+		_, _ = bc.GenesisDocument()
+	}
+}
+
+func TestConcurrentImportAndQueries(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	src := newPruneTestChain(t, PruneArchive, w)
+	nonces := uint64(0)
+	for i := 0; i < 40; i++ {
+		var txs []domain.Transaction
+		if i%3 == 0 {
+			nonces++
+			tx := domain.Transaction{To: "bob", Amount: 1, Fee: 1, Nonce: nonces}
+			if err := consensus.SignTransaction(w.PrivateKey, &tx); err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+			txs = append(txs, tx)
+		}
+		if err := src.AddBlock(txs); err != nil {
+			t.Fatalf("add block %d: %v", i, err)
+		}
+	}
+	genesis, err := src.GenesisDocument()
+	if err != nil {
+		t.Fatalf("genesis: %v", err)
+	}
+	dst := NewBlockchain(src.config, nil, nil)
+	if err := dst.ApplyGenesis(genesis); err != nil {
+		t.Fatalf("apply genesis: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go hammerReads(dst, w.Address, stop, &readers)
+	}
+	blocks := src.CanonicalChain()[1:]
+	var importers sync.WaitGroup
+	errs := make(chan error, 2*len(blocks))
+	for i := 0; i < 2; i++ {
+		importers.Add(1)
+		go func() {
+			defer importers.Done()
+			for _, b := range blocks {
+				if err := dst.ImportBlock(b); err != nil && err != ErrKnownBlock {
+					errs <- err
+				}
+			}
+		}()
+	}
+	importers.Wait()
+	close(stop)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("import: %v", err)
+	}
+	if dst.CanonicalTipHash() != src.CanonicalTipHash() {
+		t.Fatalf("tip mismatch after concurrent import")
+	}
+	if consensus.StateRoot(dst.Accounts()) != consensus.StateRoot(src.Accounts()) {
+		t.Fatalf("state mismatch after concurrent import")
+	}
+}
+
+func TestConcurrentProduceSubmitAndQueries(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	bc := newPruneTestChain(t, PruneFull, w)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go hammerReads(bc, w.Address, stop, &wg)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for nonce := uint64(1); nonce <= 30; nonce++ {
+			tx := domain.Transaction{To: "bob", Amount: 1, Fee: 1, Nonce: nonce}
+			if err := consensus.SignTransaction(w.PrivateKey, &tx); err != nil {
+				t.Errorf("sign: %v", err)
+				return
+			}
+			_ = bc.AddTx(tx)
+			_ = bc.PruneMempool()
+		}
+	}()
+	for i := 0; i < 40; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block %d: %v", i, err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if err := bc.VerifyChain(); err != nil {
+		t.Fatalf("verify chain: %v", err)
+	}
+}
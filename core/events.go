@@ -0,0 +1,296 @@
+package core
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+// Slash and jail reasons carried by ValidatorSlashed and ValidatorJailed.
+const (
+	SlashInvalidBlock = "invalid_block"
+	SlashMissedSlots  = "missed_slots"
+	SlashEquivocation = "equivocation"
+)
+
+// Reasons carried by ReorgRejected.
+const (
+	RejectFinalized   = "finalized"
+	RejectMaxDepth    = "max_depth"
+	RejectWeightDelta = "weight_delta"
+)
+
+// Reasons carried by TxDropped.
+const (
+	DropStaleNonce = "stale_nonce"
+	DropReorged    = "reorged_out"
+)
+
+var errStaleNonce = errors.New("nonce already used")
+
+// Event is one of the chain events below. Subscribers type-switch on it.
+type Event interface {
+	eventName() string
+}
+
+// BlockAccepted is published for every block that passes on-accept checks
+// and is stored, before fork-choice runs. ImportTime covers validation and
+// storage; PoHTime is the share spent verifying PoH, zero for blocks
+// produced locally.
+type BlockAccepted struct {
+	Block      domain.Block
+	ImportTime time.Duration
+	PoHTime    time.Duration
+}
+
+// CanonicalTipChanged is published when fork-choice moves the canonical
+// tip. Reverted holds the blocks that left the canonical chain (oldest
+// first) and Applied the ones that joined it; Depth is len(Reverted).
+type CanonicalTipChanged struct {
+	OldTip      string
+	NewTip      string
+	Head        domain.Block
+	Depth       int
+	DivergeSlot uint64
+	Reverted    []domain.Block
+	Applied     []domain.Block
+}
+
+// ReorgRejected is published when a heavier fork is refused.
+type ReorgRejected struct {
+	Tip         string
+	Candidate   string
+	Depth       int
+	DivergeSlot uint64
+	Reason      string
+}
+
+type SlotFinalized struct {
+	Slot uint64
+	Hash string
+}
+
+// SlotMissed is published when missed-slot accounting finds a slot whose
+// leader has no block on the canonical chain.
+type SlotMissed struct {
+	Slot   uint64
+	Leader string
+}
+
+type ValidatorSlashed struct {
+	Validator string
+	Slot      uint64
+	Reason    string
+	Amount    int
+	Stake     int
+}
+
+type ValidatorJailed struct {
+	Validator  string
+	Slot       uint64
+	Reason     string
+	UntilEpoch uint64
+}
+
+type EquivocationDetected struct {
+	Proof EquivocationProof
+}
+
+// TxPending is published when a transaction enters the mempool. Requeued
+// is set when a reorg returned it there from a block that left the
+// canonical chain.
+type TxPending struct {
+	Tx       domain.Transaction
+	Requeued bool
+}
+
+// TxIncluded is published for each transaction in a block that joins the
+// canonical chain.
+type TxIncluded struct {
+	Tx        domain.Transaction
+	BlockHash string
+	Height    uint64
+	Slot      uint64
+}
+
+// TxDropped is published when a transaction leaves the mempool without
+// being included, or falls out of the canonical chain in a reorg without
+// being included again or returning to the mempool.
+type TxDropped struct {
+	Tx     domain.Transaction
+	Reason string
+}
+
+func (BlockAccepted) eventName() string        { return "block_accepted" }
+func (CanonicalTipChanged) eventName() string  { return "canonical_tip_changed" }
+func (ReorgRejected) eventName() string        { return "reorg_rejected" }
+func (SlotFinalized) eventName() string        { return "slot_finalized" }
+func (SlotMissed) eventName() string           { return "slot_missed" }
+func (ValidatorSlashed) eventName() string     { return "validator_slashed" }
+func (ValidatorJailed) eventName() string      { return "validator_jailed" }
+func (EquivocationDetected) eventName() string { return "equivocation_detected" }
+func (TxPending) eventName() string            { return "tx_pending" }
+func (TxIncluded) eventName() string           { return "tx_included" }
+func (TxDropped) eventName() string            { return "tx_dropped" }
+
+// EventName returns a stable snake_case name for e, for logs and metrics.
+func EventName(e Event) string {
+	return e.eventName()
+}
+
+// EventHandler receives chain events. Handlers run synchronously with the
+// chain lock held, in the order the chain changed, so they must return
+// quickly and must not call back into the chain. Subscribe and Unsubscribe
+// are safe to call from a handler.
+type EventHandler func(Event)
+
+type eventBus struct {
+	mu       sync.Mutex
+	nextID   int
+	handlers map[int]EventHandler
+	order    []int
+}
+
+func (b *eventBus) subscribe(fn EventHandler) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.handlers == nil {
+		b.handlers = make(map[int]EventHandler)
+	}
+	b.nextID++
+	b.handlers[b.nextID] = fn
+	b.order = append(b.order, b.nextID)
+	return b.nextID
+}
+
+func (b *eventBus) unsubscribe(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.handlers[id]; !ok {
+		return
+	}
+	delete(b.handlers, id)
+	for i, v := range b.order {
+		if v == id {
+			b.order = append(b.order[:i:i], b.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (b *eventBus) publish(e Event) {
+	b.mu.Lock()
+	if len(b.order) == 0 {
+		b.mu.Unlock()
+		return
+	}
+	handlers := make([]EventHandler, 0, len(b.order))
+	for _, id := range b.order {
+		handlers = append(handlers, b.handlers[id])
+	}
+	b.mu.Unlock()
+	for _, fn := range handlers {
+		fn(e)
+	}
+}
+
+// Subscribe registers fn for all chain events and returns an id for
+// Unsubscribe. Handlers are called in subscription order.
+func (bc *Blockchain) Subscribe(fn EventHandler) int {
+	return bc.events.subscribe(fn)
+}
+
+func (bc *Blockchain) Unsubscribe(id int) {
+	bc.events.unsubscribe(id)
+}
+
+func (bc *Blockchain) publish(e Event) {
+	bc.events.publish(e)
+}
+
+func (bc *Blockchain) slash(name string, slot uint64, amount int, reason string) {
+	v, ok := bc.validators[name]
+	if !ok {
+		return
+	}
+	before := v.Stake
+	consensus.SlashValidator(bc.validators, name, amount)
+	after := 0
+	if v, ok := bc.validators[name]; ok {
+		after = v.Stake
+	}
+	if after == before {
+		return
+	}
+	bc.publish(ValidatorSlashed{Validator: name, Slot: slot, Reason: reason, Amount: before - after, Stake: after})
+}
+
+// slashPercent mirrors consensus.SlashValidatorPercent.
+func (bc *Blockchain) slashPercent(name string, slot uint64, percent int, reason string) {
+	v, ok := bc.validators[name]
+	if !ok || percent <= 0 {
+		return
+	}
+	amount := (v.Stake * percent) / 100
+	if amount == 0 {
+		amount = 1
+	}
+	bc.slash(name, slot, amount, reason)
+}
+
+func (bc *Blockchain) jail(name string, slot uint64, reason string) {
+	stats := bc.ensureStats(name)
+	stats.JailedUntilEpoch = (slot / consensus.SlotsPerEpoch) + consensus.JailEpochs
+	bc.publish(ValidatorJailed{Validator: name, Slot: slot, Reason: reason, UntilEpoch: stats.JailedUntilEpoch})
+}
+
+// publishTipChange reports a canonical tip switch and the transaction
+// inclusions and drops it implies. Reverted transactions the new canonical
+// state can still apply go back to the mempool.
+func (bc *Blockchain) publishTipChange(ev CanonicalTipChanged) {
+	bc.publish(ev)
+	included := make(map[string]bool)
+	for _, b := range ev.Applied {
+		for _, tx := range b.Transactions {
+			included[tx.Hash] = true
+			bc.publish(TxIncluded{Tx: tx, BlockHash: b.Hash, Height: b.Index, Slot: b.Slot})
+		}
+	}
+	for _, b := range ev.Reverted {
+		for _, tx := range b.Transactions {
+			if included[tx.Hash] {
+				continue
+			}
+			if err := bc.requeueTx(tx); err == nil {
+				bc.publish(TxPending{Tx: tx, Requeued: true})
+			} else if !errors.Is(err, ErrDuplicateTx) {
+				bc.publish(TxDropped{Tx: tx, Reason: DropReorged})
+			}
+		}
+	}
+}
+
+// requeueTx returns a reverted transaction to the mempool unless the
+// canonical state has already used its nonce.
+func (bc *Blockchain) requeueTx(tx domain.Transaction) error {
+	if tx.Nonce <= bc.state[tx.From].Nonce {
+		return errStaleNonce
+	}
+	if bc.mempool == nil {
+		bc.mempool = NewMempool()
+	}
+	return bc.mempool.Add(tx)
+}
+
+// finalizedBlock returns the highest canonical block at or below slot.
+func (bc *Blockchain) finalizedBlock(slot uint64) (domain.Block, bool) {
+	for i := len(bc.chain) - 1; i >= 0; i-- {
+		if bc.chain[i].Slot <= slot {
+			return bc.chain[i], true
+		}
+	}
+	return domain.Block{}, false
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"testing"
+
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+func TestEventsFollowForkChoice(t *testing.T) {
+	bc := newTestChain(t)
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	bc.SetBalance(w.Address, 100)
+	genesis := bc.CanonicalTipHash()
+
+	var events []Event
+	id := bc.Subscribe(func(e Event) { events = append(events, e) })
+
+	tx := domain.Transaction{To: "bob", Amount: 10, Fee: 1, Nonce: 1}
+	if err := consensus.SignTransaction(w.PrivateKey, &tx); err != nil {
+		t.Fatalf("sign tx: %v", err)
+	}
+	if err := bc.AddTx(tx); err != nil {
+		t.Fatalf("add tx: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	if len(events) == 0 {
+		t.Fatalf("no events published")
+	}
+	if ev, ok := events[0].(TxPending); !ok || ev.Tx.Hash != tx.Hash {
+		t.Fatalf("expected TxPending first, got %#v", events[0])
+	}
+	if ev := find[TxIncluded](events); ev == nil || ev.Tx.Hash != tx.Hash || ev.Height != 1 {
+		t.Fatalf("expected TxIncluded at height 1, got %+v", ev)
+	}
+	if n := count[BlockAccepted](events); n != 2 {
+		t.Fatalf("expected 2 BlockAccepted, got %d", n)
+	}
+	oldTip := bc.CanonicalTipHash()
+
+	// A three block fork from genesis outweighs the two block chain and
+	// reorgs it out, returning the transaction it carried to the mempool.
+	events = nil
+	parent := genesis
+	for i := 0; i < 3; i++ {
+		if parent, err = bc.AddBlockExternal(parent, nil); err != nil {
+			t.Fatalf("fork block %d: %v", i, err)
+		}
+	}
+	change := find[CanonicalTipChanged](events)
+	if change == nil || change.OldTip != oldTip || change.NewTip != parent || change.Depth != 2 ||
+		len(change.Reverted) != 2 || len(change.Applied) != 3 || change.DivergeSlot != 1 {
+		t.Fatalf("unexpected reorg event %+v", change)
+	}
+	if ev := find[TxPending](events); ev == nil || ev.Tx.Hash != tx.Hash || !ev.Requeued {
+		t.Fatalf("expected reorged tx to be requeued, got %+v", ev)
+	}
+	if ev := find[TxDropped](events); ev != nil {
+		t.Fatalf("requeued tx reported as dropped: %+v", ev)
+	}
+	if !bc.mempool.Has(tx.Hash) {
+		t.Fatalf("reorged tx not back in the mempool")
+	}
+	if ev := find[SlotFinalized](events); ev == nil || ev.Slot != bc.FinalizedSlot() {
+		t.Fatalf("expected SlotFinalized, got %+v", ev)
+	}
+
+	// Another genesis fork now crosses the finalized slot and is refused.
+	events = nil
+	parent = genesis
+	for i := 0; i < 4; i++ {
+		if parent, err = bc.AddBlockExternal(parent, nil); err != nil {
+			t.Fatalf("fork block %d: %v", i, err)
+		}
+	}
+	if ev := find[ReorgRejected](events); ev == nil || ev.Reason != RejectFinalized || ev.Candidate == "" {
+		t.Fatalf("expected finalized reorg rejection, got %+v", ev)
+	}
+	if find[CanonicalTipChanged](events) != nil {
+		t.Fatalf("rejected fork changed the tip")
+	}
+
+	bc.Unsubscribe(id)
+	events = nil
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unsubscribed handler got %d events", len(events))
+	}
+}
+
+func TestReorgDropsTxWhoseNonceWasUsed(t *testing.T) {
+	bc := newTestChain(t)
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	bc.SetBalance(w.Address, 100)
+	genesis := bc.CanonicalTipHash()
+	sign := func(amount int) domain.Transaction {
+		tx := domain.Transaction{To: "bob", Amount: amount, Fee: 1, Nonce: 1}
+		if err := consensus.SignTransaction(w.PrivateKey, &tx); err != nil {
+			t.Fatalf("sign tx: %v", err)
+		}
+		return tx
+	}
+	first, replaced := sign(10), sign(20)
+	if err := bc.AddBlock([]domain.Transaction{first}); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+
+	var events []Event
+	bc.Subscribe(func(e Event) { events = append(events, e) })
+	parent, err := bc.AddBlockExternal(genesis, []domain.Transaction{replaced})
+	if err != nil {
+		t.Fatalf("fork block: %v", err)
+	}
+	if _, err := bc.AddBlockExternal(parent, nil); err != nil {
+		t.Fatalf("fork block: %v", err)
+	}
+	if ev := find[TxDropped](events); ev == nil || ev.Tx.Hash != first.Hash || ev.Reason != DropReorged {
+		t.Fatalf("expected reorged tx to be dropped, got %+v", ev)
+	}
+	if bc.mempool.Has(first.Hash) {
+		t.Fatalf("tx with a used nonce returned to the mempool")
+	}
+}
+
+func TestEquivocationSlashesAndJails(t *testing.T) {
+	bc := newTestChain(t)
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	var events []Event
+	bc.Subscribe(func(e Event) { events = append(events, e) })
+
+	bc.mu.Lock()
+	bc.handleEquivocation("Alice", 5, "a", "b")
+	bc.mu.Unlock()
+
+	if ev := find[EquivocationDetected](events); ev == nil || ev.Proof.BlockB != "b" {
+		t.Fatalf("expected EquivocationDetected, got %+v", ev)
+	}
+	slashed := find[ValidatorSlashed](events)
+	if slashed == nil || slashed.Reason != SlashEquivocation || slashed.Amount == 0 || slashed.Stake != 100-slashed.Amount {
+		t.Fatalf("unexpected slash event %+v", slashed)
+	}
+	if ev := find[ValidatorJailed](events); ev == nil || ev.Validator != "Alice" || ev.UntilEpoch != consensus.JailEpochs {
+		t.Fatalf("unexpected jail event %+v", ev)
+	}
+}
+
+func find[T Event](events []Event) *T {
+	for _, e := range events {
+		if ev, ok := e.(T); ok {
+			return &ev
+		}
+	}
+	return nil
+}
+
+func count[T Event](events []Event) int {
+	n := 0
+	for _, e := range events {
+		if _, ok := e.(T); ok {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"xenium/domain"
+)
+
+// forkChoiceOrders is how many insertion orders each random tree is
+// replayed in.
+const forkChoiceOrders = 6
+
+// newRandomTree builds a chain whose blocks form a random tree: every block
+// extends a random earlier block, preferring recent ones. Reorg guards are
+// off so that fork-choice alone decides the tip, and the epoch is longer
+// than the tree so stake snapshots do not depend on import order.
+func newRandomTree(t *testing.T, rng *rand.Rand, size int) *Blockchain {
+	t.Helper()
+	cfg := ChainConfig{
+		MaxReorgDepth:    1 << 20,
+		FinalitySlots:    1 << 40,
+		EpochLength:      1 << 20,
+		DeterministicPoH: true,
+		PoHSeed:          rng.Int63(),
+	}
+	bc := NewBlockchain(cfg, nil, nil)
+	for _, v := range []struct {
+		name  string
+		stake int
+	}{{"Alice", 100}, {"Bob", 60}, {"Carol", 40}} {
+		w, err := domain.NewWallet()
+		if err != nil {
+			t.Fatalf("wallet: %v", err)
+		}
+		if err := bc.AddValidator(v.name, v.stake, w.PublicKey, w.PrivateKey); err != nil {
+			t.Fatalf("add validator: %v", err)
+		}
+	}
+	hashes := []string{bc.CanonicalTipHash()}
+	for i := 0; i < size; i++ {
+		lo := len(hashes) - 4
+		if lo < 0 {
+			lo = 0
+		}
+		parent := hashes[lo+rng.Intn(len(hashes)-lo)]
+		if rng.Intn(5) == 0 {
+			parent = hashes[rng.Intn(len(hashes))]
+		}
+		hash, err := bc.AddBlockExternal(parent, nil)
+		if err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return bc
+}
+
+// randomTopoOrder shuffles blocks so that every parent still comes before
+// its children.
+func randomTopoOrder(rng *rand.Rand, blocks []domain.Block, genesis string) []domain.Block {
+	known := map[string]bool{genesis: true}
+	pending := append([]domain.Block(nil), blocks...)
+	out := make([]domain.Block, 0, len(blocks))
+	for len(pending) > 0 {
+		var ready []int
+		for i, b := range pending {
+			if known[b.PrevHash] {
+				ready = append(ready, i)
+			}
+		}
+		i := ready[rng.Intn(len(ready))]
+		b := pending[i]
+		pending = append(pending[:i], pending[i+1:]...)
+		known[b.Hash] = true
+		out = append(out, b)
+	}
+	return out
+}
+
+// bestLeaf is the leaf fork-choice must settle on: highest weight, then
+// slot, then lowest hash.
+func bestLeaf(bc *Blockchain) string {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	hasChild := make(map[string]bool, len(bc.blocks))
+	for _, b := range bc.blocks {
+		hasChild[b.PrevHash] = true
+	}
+	var best ChainScore
+	found := false
+	for hash := range bc.blocks {
+		if hash == "" || hasChild[hash] {
+			continue
+		}
+		score := bc.scoreTip(hash)
+		if !found || betterScore(score, best) {
+			best, found = score, true
+		}
+	}
+	return best.Hash
+}
+
+func TestForkChoiceConvergesRegardlessOfOrder(t *testing.T) {
+	property := func(seed int64) bool {
+		rng := rand.New(rand.NewSource(seed))
+		src := newRandomTree(t, rng, 8+rng.Intn(25))
+		genesis, err := src.GenesisDocument()
+		if err != nil {
+			t.Fatalf("genesis: %v", err)
+		}
+		want := bestLeaf(src)
+		if src.CanonicalTipHash() != want {
+			t.Logf("seed %d: source tip %s, best leaf %s", seed, src.CanonicalTipHash(), want)
+			return false
+		}
+		var blocks []domain.Block
+		for _, b := range src.Blocks() {
+			if b.Index > 0 {
+				blocks = append(blocks, b)
+			}
+		}
+		for i := 0; i < forkChoiceOrders; i++ {
+			dst := NewBlockchain(src.config, nil, nil)
+			if err := dst.ApplyGenesis(genesis); err != nil {
+				t.Fatalf("apply genesis: %v", err)
+			}
+			for _, b := range randomTopoOrder(rng, blocks, dst.CanonicalTipHash()) {
+				if err := dst.ImportBlock(b); err != nil {
+					t.Logf("seed %d: import %s: %v", seed, b.Hash, err)
+					return false
+				}
+			}
+			if got := dst.CanonicalTipHash(); got != want {
+				t.Logf("seed %d order %d: tip %s, want %s", seed, i, got, want)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 25, Rand: rand.New(rand.NewSource(1))}); err != nil {
+		t.Fatal(err)
+	}
+}
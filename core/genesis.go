@@ -0,0 +1,124 @@
+package core
+
+import (
+	"errors"
+	"sort"
+
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+type GenesisValidator struct {
+	Name   string
+	Stake  int
+	PubKey string
+}
+
+type GenesisDoc struct {
+	Block      domain.Block
+	Validators []GenesisValidator
+	Balances   map[string]int
+	// Time is the wall-clock time of slot 0 in unix nanoseconds.
+	Time int64 `json:",omitempty"`
+}
+
+func (bc *Blockchain) GenesisDocument() (GenesisDoc, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	genesis, ok := bc.genesisBlock()
+	if !ok {
+		return GenesisDoc{}, ErrPruned
+	}
+	doc := GenesisDoc{
+		Block:      genesis,
+		Validators: make([]GenesisValidator, 0, len(bc.genesisValidators)),
+		Balances:   make(map[string]int, len(bc.genesis)),
+		Time:       bc.genesisTime,
+	}
+	for _, v := range bc.genesisValidators {
+		doc.Validators = append(doc.Validators, v)
+	}
+	sort.Slice(doc.Validators, func(i, j int) bool { return doc.Validators[i].Name < doc.Validators[j].Name })
+	for addr, acct := range bc.genesis {
+		doc.Balances[addr] = acct.Balance
+	}
+	return doc, nil
+}
+
+// ApplyGenesis installs a genesis document. On a fresh chain the genesis block
+// is replaced by the document's; on a restored chain the stored genesis must match.
+func (bc *Blockchain) ApplyGenesis(doc GenesisDoc) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if err := consensus.VerifyBlockHash(doc.Block); err != nil {
+		return err
+	}
+	if doc.Block.PrevHash != "GENESIS" || doc.Block.Index != 0 {
+		return errors.New("genesis document block is not a genesis block")
+	}
+	current, ok := bc.genesisBlock()
+	if !ok {
+		return ErrPruned
+	}
+	if current.Hash != doc.Block.Hash {
+		if len(bc.blocks) > 1 {
+			return errors.New("genesis mismatch: chain already has blocks")
+		}
+		pohSeed, err := consensus.ParsePoHHashHex(doc.Block.PoHHash)
+		if err != nil {
+			return err
+		}
+		delete(bc.blocks, current.Hash)
+		delete(bc.parents, current.Hash)
+		bc.insertBlock(doc.Block)
+		bc.canonicalTip = doc.Block.Hash
+		bc.poh = consensus.NewPoH(pohSeed)
+		if bc.blockStore != nil {
+			if err := bc.blockStore.SaveBlock(doc.Block); err != nil {
+				return err
+			}
+		}
+	}
+	for _, v := range doc.Validators {
+		if existing, ok := bc.validators[v.Name]; ok {
+			if existing.PubKey != v.PubKey {
+				return errors.New("genesis validator pubkey mismatch for " + v.Name)
+			}
+			continue
+		}
+		if err := consensus.AddValidator(bc.validators, bc.stats, v.Name, v.Stake, v.PubKey, nil); err != nil {
+			return err
+		}
+		bc.recordGenesisValidator(v.Name, v.Stake, v.PubKey)
+	}
+	for addr, balance := range doc.Balances {
+		acct := bc.genesis[addr]
+		acct.Balance = balance
+		bc.genesis[addr] = acct
+	}
+	if doc.Time != 0 {
+		bc.genesisTime = doc.Time
+	}
+	bc.rebuildCanonicalChain()
+	bc.updateFinality()
+	return nil
+}
+
+func (bc *Blockchain) genesisBlock() (domain.Block, bool) {
+	if len(bc.chain) == 0 || bc.chain[0].PrevHash != "GENESIS" {
+		return domain.Block{}, false
+	}
+	return bc.chain[0], true
+}
+
+func (bc *Blockchain) recordGenesisValidator(name string, stake int, pubKey string) {
+	if bc.genesisValidators == nil {
+		bc.genesisValidators = make(map[string]GenesisValidator)
+	}
+	gv, ok := bc.genesisValidators[name]
+	if !ok {
+		gv = GenesisValidator{Name: name, PubKey: pubKey}
+	}
+	gv.Stake += stake
+	bc.genesisValidators[name] = gv
+}
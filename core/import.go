@@ -0,0 +1,112 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+var ErrKnownBlock = errors.New("block already known")
+var ErrUnknownParent = errors.New("unknown parent hash")
+var ErrSnapshotMismatch = errors.New("epoch snapshot mismatch")
+
+// ImportBlock accepts a block produced elsewhere. It runs the same on-accept
+// checks as locally produced blocks plus hash, link and PoH verification
+// against the parent, then goes through fork-choice.
+func (bc *Blockchain) ImportBlock(block domain.Block) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	start := time.Now()
+	if _, ok := bc.blocks[block.Hash]; ok {
+		return ErrKnownBlock
+	}
+	parent, ok := bc.blocks[block.PrevHash]
+	if !ok {
+		// A missing parent at or below the anchor height was pruned, or
+		// sits on a fork that pruning dropped.
+		if bc.anchor != nil && block.Index <= bc.anchor.Index+1 {
+			return ErrPruned
+		}
+		return ErrUnknownParent
+	}
+	if block.Index != parent.Index+1 {
+		return errors.New("invalid index for block")
+	}
+	if err := consensus.VerifyBlockLink(parent, block); err != nil {
+		return err
+	}
+	if err := consensus.VerifyBlockHash(block); err != nil {
+		return err
+	}
+	parentPoH, err := consensus.ParsePoHHashHex(parent.PoHHash)
+	if err != nil {
+		return err
+	}
+	pohStart := time.Now()
+	pohHash, _, err := consensus.VerifyPoH(parentPoH, parent.Tick, block)
+	if err != nil {
+		return err
+	}
+	pohTime := time.Since(pohStart)
+	parentState := bc.state
+	if parent.Hash != bc.canonicalTip {
+		parentState, err = bc.stateAtTip(parent.Hash)
+		if err != nil {
+			return err
+		}
+	}
+	if err := bc.verifyBlockOnAccept(parent, block, parentState); err != nil {
+		return err
+	}
+
+	if bc.blockStore != nil {
+		if err := bc.blockStore.SaveBlock(block); err != nil {
+			return err
+		}
+	}
+
+	eqErr := bc.registerSlotProducer(block)
+	bc.insertBlock(block)
+	bc.publish(BlockAccepted{Block: block, ImportTime: time.Since(start), PoHTime: pohTime})
+	bc.updateCanonical(block.Hash)
+	consensus.RewardValidator(bc.validators, block.Validator)
+	if block.Tick > bc.poh.CurrentTick {
+		bc.poh.CurrentTick = block.Tick
+		bc.poh.Hash = pohHash
+	}
+	bc.processMissedSlots(bc.chainTipSlot())
+	bc.maybePrune()
+	return eqErr
+}
+
+// CheckEpochSnapshot compares a snapshot taken elsewhere with the one this
+// chain took for the same epoch. Foreign snapshots are never installed, since
+// they decide the leader schedule; an epoch the chain has not reached yet is
+// not an error.
+func (bc *Blockchain) CheckEpochSnapshot(snap EpochSnapshot) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	own, ok := bc.snapshots[snap.Epoch]
+	if !ok {
+		return nil
+	}
+	if !sameSnapshot(*own, snap) {
+		return fmt.Errorf("%w for epoch %d", ErrSnapshotMismatch, snap.Epoch)
+	}
+	return nil
+}
+
+func sameSnapshot(a EpochSnapshot, b EpochSnapshot) bool {
+	if a.Epoch != b.Epoch || len(a.Validators) != len(b.Validators) {
+		return false
+	}
+	for name, stake := range a.Validators {
+		if other, ok := b.Validators[name]; !ok || other != stake {
+			return false
+		}
+	}
+	return true
+}
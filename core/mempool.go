@@ -9,6 +9,8 @@ import (
 	"xenium/domain"
 )
 
+var ErrDuplicateTx = errors.New("duplicate tx")
+
 type Mempool struct {
 	mu     sync.Mutex
 	byHash map[string]domain.Transaction
@@ -31,7 +33,7 @@ func (m *Mempool) Add(tx domain.Transaction) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if _, ok := m.byHash[tx.Hash]; ok {
-		return errors.New("duplicate tx")
+		return ErrDuplicateTx
 	}
 	m.byHash[tx.Hash] = tx
 	m.list = append(m.list, tx)
@@ -47,6 +49,19 @@ func (m *Mempool) Add(tx domain.Transaction) error {
 	return nil
 }
 
+func (m *Mempool) Has(hash string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.byHash[hash]
+	return ok
+}
+
+func (m *Mempool) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.list)
+}
+
 func (m *Mempool) PopForBlock(state map[string]domain.Account, max int, producer string) []domain.Transaction {
 	if max <= 0 {
 		return nil
@@ -81,6 +96,29 @@ func (m *Mempool) PopForBlock(state map[string]domain.Account, max int, producer
 	return out
 }
 
+// Prune drops transactions whose nonce has already been used in state and
+// returns how many were removed.
+func (m *Mempool) Prune(state map[string]domain.Account) int {
+	return len(m.prune(state))
+}
+
+func (m *Mempool) prune(state map[string]domain.Account) []domain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.list[:0]
+	var removed []domain.Transaction
+	for _, tx := range m.list {
+		if tx.Nonce <= state[tx.From].Nonce {
+			delete(m.byHash, tx.Hash)
+			removed = append(removed, tx)
+			continue
+		}
+		kept = append(kept, tx)
+	}
+	m.list = kept
+	return removed
+}
+
 func copyState(state map[string]domain.Account) map[string]domain.Account {
 	next := make(map[string]domain.Account, len(state))
 	for k, v := range state {
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"errors"
+
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+var ErrNotLeader = errors.New("slot leader key not held locally")
+var ErrSlotTaken = errors.New("canonical tip already covers slot")
+
+// GenesisTime is the wall-clock time of slot 0 in unix nanoseconds, or zero
+// when the chain has not been bound to wall time.
+func (bc *Blockchain) GenesisTime() int64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.genesisTime
+}
+
+func (bc *Blockchain) SetGenesisTime(t int64) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.genesisTime = t
+}
+
+// LocalLeader returns the leader for slot and whether this node holds its
+// signing key.
+func (bc *Blockchain) LocalLeader(slot uint64) (string, bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.localLeader(slot)
+}
+
+func (bc *Blockchain) localLeader(slot uint64) (string, bool) {
+	leader := bc.leaderForSlot(slot)
+	v := bc.validators[leader]
+	return leader, v != nil && v.PrivKey != nil
+}
+
+// ProduceBlockAt builds a block on the canonical tip at the given PoH tick.
+// Unlike AddBlock, which advances PoH by one slot per call, the tick comes
+// from the caller, so slots without a local leader are left empty and get
+// counted as missed once a later block arrives.
+func (bc *Blockchain) ProduceBlockAt(tick uint64, txs []domain.Transaction) (domain.Block, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.poh == nil {
+		return domain.Block{}, errors.New("poh not initialized")
+	}
+	prev := bc.blocks[bc.canonicalTip]
+	slot := tick / consensus.TicksPerSlot
+	if slot <= prev.Slot {
+		return domain.Block{}, ErrSlotTaken
+	}
+	leader, local := bc.localLeader(slot)
+	if !local {
+		return domain.Block{}, ErrNotLeader
+	}
+	hash, err := consensus.ParsePoHHashHex(prev.PoHHash)
+	if err != nil {
+		return domain.Block{}, err
+	}
+	for t := prev.Tick + 1; t <= tick; t++ {
+		hash = consensus.HashPoH(hash, t)
+	}
+	bc.poh.CurrentTick = tick
+	bc.poh.Hash = hash
+	return bc.produceOnTip(prev, slot, leader, txs)
+}
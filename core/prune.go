@@ -0,0 +1,238 @@
+package core
+
+import (
+	"errors"
+
+	"xenium/consensus"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+type PruneMode string
+
+const (
+	PruneArchive PruneMode = "archive"
+	PruneFull    PruneMode = "full"
+	PruneLight   PruneMode = "light"
+)
+
+var ErrPruned = errors.New("data pruned")
+var ErrBlockNotFound = errors.New("block not found")
+
+func (bc *Blockchain) GetBlockByHash(hash string) (domain.Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	b, ok := bc.blocks[hash]
+	if !ok {
+		return domain.Block{}, bc.missingBlock(hash)
+	}
+	return b, nil
+}
+
+func (bc *Blockchain) GetBlockByHeight(height uint64) (domain.Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if bc.anchor != nil && height < bc.anchor.Index {
+		return domain.Block{}, ErrPruned
+	}
+	if len(bc.chain) == 0 {
+		return domain.Block{}, ErrBlockNotFound
+	}
+	base := bc.chain[0].Index
+	if height < base || height-base >= uint64(len(bc.chain)) {
+		return domain.Block{}, ErrBlockNotFound
+	}
+	return bc.chain[height-base], nil
+}
+
+func (bc *Blockchain) StateAt(hash string) (map[string]domain.Account, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if _, ok := bc.blocks[hash]; !ok {
+		return nil, bc.missingBlock(hash)
+	}
+	return bc.stateAtTip(hash)
+}
+
+// missingBlock tells a hash dropped by pruning apart from an unknown one.
+// Only the last maxPrunedHashes blocks pruned since the chain was loaded
+// are remembered.
+func (bc *Blockchain) missingBlock(hash string) error {
+	if bc.pruned.has(hash) {
+		return ErrPruned
+	}
+	return ErrBlockNotFound
+}
+
+const maxPrunedHashes = 4096
+
+// prunedHashes remembers pruned block hashes in insertion order and forgets
+// the oldest once full, so memory stays bounded as the chain grows.
+type prunedHashes struct {
+	set   map[string]struct{}
+	order []string
+	next  int
+}
+
+func (p *prunedHashes) has(hash string) bool {
+	_, ok := p.set[hash]
+	return ok
+}
+
+func (p *prunedHashes) add(hash string) {
+	if p.set == nil {
+		p.set = make(map[string]struct{}, maxPrunedHashes)
+		p.order = make([]string, maxPrunedHashes)
+	}
+	if p.has(hash) {
+		return
+	}
+	if old := p.order[p.next]; old != "" {
+		delete(p.set, old)
+	}
+	p.order[p.next] = hash
+	p.next = (p.next + 1) % len(p.order)
+	p.set[hash] = struct{}{}
+}
+
+// PrunedHeight returns the lowest block height still held by the chain.
+func (bc *Blockchain) PrunedHeight() uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if bc.anchor == nil {
+		return 0
+	}
+	return bc.anchor.Index
+}
+
+func (bc *Blockchain) isAnchor(hash string) bool {
+	return bc.anchor != nil && bc.anchor.Hash == hash
+}
+
+// baseState returns a copy of the state the canonical chain is replayed from:
+// the genesis balances, or the anchor state once the chain has been pruned.
+func (bc *Blockchain) baseState() map[string]domain.Account {
+	if bc.anchor != nil {
+		return copyState(bc.anchor.State)
+	}
+	return copyState(bc.genesis)
+}
+
+func (bc *Blockchain) maybePrune() {
+	if bc.config.PruneMode == "" || bc.config.PruneMode == PruneArchive {
+		return
+	}
+	cutoff := bc.finalizedSlot
+	if bc.config.PruneMode == PruneFull {
+		keep := bc.config.PruneKeepEpochs * bc.config.EpochLength
+		if cutoff <= keep {
+			return
+		}
+		cutoff -= keep
+	}
+	// Pruning rewrites storage, so it runs at most once per epoch of progress.
+	epoch := bc.epochForSlot(cutoff)
+	if epoch <= bc.prunedEpoch {
+		return
+	}
+	if err := bc.pruneTo(cutoff); err != nil {
+		bc.logger.With("cutoff_slot", cutoff).Errorf("Prune failed: %v", err)
+		return
+	}
+	bc.prunedEpoch = epoch
+}
+
+func (bc *Blockchain) pruneTo(cutoff uint64) error {
+	idx := 0
+	for i := 1; i < len(bc.chain); i++ {
+		if bc.chain[i].Slot > cutoff {
+			break
+		}
+		idx = i
+	}
+	if idx == 0 {
+		return nil
+	}
+	base := bc.chain[idx]
+
+	state := bc.baseState()
+	for i := 1; i <= idx; i++ {
+		next, err := consensus.ApplyTransactions(state, bc.chain[i].Transactions, bc.validatorRewardAddress(bc.chain[i].Validator))
+		if err != nil {
+			return err
+		}
+		state = next
+	}
+	anchor := domain.PruneAnchor{
+		Hash:   base.Hash,
+		Index:  base.Index,
+		Slot:   base.Slot,
+		Weight: bc.scoreTip(base.Hash).CumulativeWeight,
+		State:  state,
+	}
+
+	keep := bc.descendantsOf(base.Hash)
+	removed := 0
+	for hash := range bc.blocks {
+		if keep[hash] {
+			continue
+		}
+		bc.pruned.add(hash)
+		delete(bc.blocks, hash)
+		delete(bc.parents, hash)
+		removed++
+	}
+	for slot := range bc.slotProducers {
+		if slot < base.Slot {
+			delete(bc.slotProducers, slot)
+		}
+	}
+
+	bc.anchor = &anchor
+	bc.chain = append([]domain.Block(nil), bc.chain[idx:]...)
+	bc.rebuildSlotMap()
+
+	if store, ok := bc.blockStore.(ports.PrunableBlockStore); ok {
+		if err := store.Compact(anchor); err != nil {
+			return err
+		}
+	}
+	bc.logger.With("removed", removed, "height", base.Index, "slot", base.Slot, "mode", bc.config.PruneMode).Infof("Pruned blocks")
+	return nil
+}
+
+// descendantsOf returns the set of known blocks whose ancestry includes root,
+// root itself included.
+func (bc *Blockchain) descendantsOf(root string) map[string]bool {
+	memo := map[string]bool{root: true}
+	for hash := range bc.blocks {
+		var path []string
+		cur := hash
+		result := false
+		for {
+			if v, ok := memo[cur]; ok {
+				result = v
+				break
+			}
+			b, ok := bc.blocks[cur]
+			if !ok || b.PrevHash == "GENESIS" {
+				break
+			}
+			path = append(path, cur)
+			cur = b.PrevHash
+		}
+		for _, h := range path {
+			memo[h] = result
+		}
+		if _, ok := memo[hash]; !ok {
+			memo[hash] = result
+		}
+	}
+	out := make(map[string]bool, len(memo))
+	for hash, ok := range memo {
+		if ok {
+			out[hash] = true
+		}
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"xenium/domain"
+)
+
+func newPruneTestChain(t *testing.T, mode PruneMode, w *domain.Wallet) *Blockchain {
+	t.Helper()
+	cfg := ChainConfig{
+		MaxReorgDepth:        2,
+		FinalitySlots:        2,
+		MinReorgWeightDeltaP: 10,
+		EpochLength:          5,
+		DeterministicPoH:     true,
+		PoHSeed:              1,
+		PruneMode:            mode,
+		PruneKeepEpochs:      1,
+	}
+	bc := NewBlockchain(cfg, nil, nil)
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	bc.SetBalance(w.Address, 1000)
+	return bc
+}
+
+func TestPruneLightDropsFinalizedBlocks(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	bc := newPruneTestChain(t, PruneLight, w)
+	genesis := bc.CanonicalTipHash()
+	for i := 0; i < 30; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block %d: %v", i, err)
+		}
+	}
+	if bc.PrunedHeight() == 0 {
+		t.Fatalf("expected chain to be pruned")
+	}
+	if len(bc.blocks) >= 31 {
+		t.Fatalf("expected pruned block map, got %d blocks", len(bc.blocks))
+	}
+	if _, err := bc.GetBlockByHeight(0); !errors.Is(err, ErrPruned) {
+		t.Fatalf("expected ErrPruned for genesis height, got %v", err)
+	}
+	if _, err := bc.GetBlockByHash(genesis); !errors.Is(err, ErrPruned) {
+		t.Fatalf("expected ErrPruned for genesis hash, got %v", err)
+	}
+	if _, err := bc.StateAt(genesis); !errors.Is(err, ErrPruned) {
+		t.Fatalf("expected ErrPruned for state at genesis, got %v", err)
+	}
+	if _, err := bc.GetBlockByHash("unknown"); !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("expected ErrBlockNotFound for unknown hash, got %v", err)
+	}
+	if _, err := bc.GetBlockByHeight(bc.PrunedHeight()); err != nil {
+		t.Fatalf("expected anchor height to be served: %v", err)
+	}
+	if err := bc.VerifyChain(); err != nil {
+		t.Fatalf("verify pruned chain: %v", err)
+	}
+}
+
+func TestPruneFullKeepsRecentEpochs(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	light := newPruneTestChain(t, PruneLight, w)
+	full := newPruneTestChain(t, PruneFull, w)
+	archive := newPruneTestChain(t, PruneArchive, w)
+	for i := 0; i < 30; i++ {
+		for _, bc := range []*Blockchain{light, full, archive} {
+			if err := bc.AddBlock(nil); err != nil {
+				t.Fatalf("add block %d: %v", i, err)
+			}
+		}
+	}
+	if archive.PrunedHeight() != 0 {
+		t.Fatalf("archive mode must not prune")
+	}
+	if full.PrunedHeight() == 0 || full.PrunedHeight() >= light.PrunedHeight() {
+		t.Fatalf("expected full mode to retain more than light: full=%d light=%d", full.PrunedHeight(), light.PrunedHeight())
+	}
+	if full.CanonicalTipHash() != archive.CanonicalTipHash() || light.CanonicalTipHash() != archive.CanonicalTipHash() {
+		t.Fatalf("pruning changed the canonical tip")
+	}
+	if full.ScoreTip(full.CanonicalTipHash()) != archive.ScoreTip(archive.CanonicalTipHash()) {
+		t.Fatalf("pruning changed the tip score")
+	}
+}
+
+func TestPrunedHashesStayBounded(t *testing.T) {
+	var p prunedHashes
+	for i := 0; i < maxPrunedHashes+10; i++ {
+		p.add(fmt.Sprintf("h%d", i))
+	}
+	if len(p.set) != maxPrunedHashes {
+		t.Fatalf("remembered %d hashes, want %d", len(p.set), maxPrunedHashes)
+	}
+	if p.has("h0") || !p.has(fmt.Sprintf("h%d", maxPrunedHashes+9)) {
+		t.Fatalf("expected the oldest hashes to be forgotten first")
+	}
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"sort"
+
+	"xenium/consensus"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+// Read accessors. Accepted blocks are never modified, so returned blocks
+// share their transaction slices with the chain and must be treated as
+// read-only; maps, slices of blocks and validator records are copied.
+
+func (bc *Blockchain) Tip() domain.Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.blocks[bc.canonicalTip]
+}
+
+func (bc *Blockchain) Height() uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.blocks[bc.canonicalTip].Index
+}
+
+func (bc *Blockchain) HasBlock(hash string) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	_, ok := bc.blocks[hash]
+	return ok
+}
+
+func (bc *Blockchain) BlockCount() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return len(bc.blocks)
+}
+
+// Blocks returns every known block, forks included, ordered by height and
+// then hash.
+func (bc *Blockchain) Blocks() []domain.Block {
+	bc.mu.RLock()
+	out := make([]domain.Block, 0, len(bc.blocks))
+	for _, b := range bc.blocks {
+		out = append(out, b)
+	}
+	bc.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Index != out[j].Index {
+			return out[i].Index < out[j].Index
+		}
+		return out[i].Hash < out[j].Hash
+	})
+	return out
+}
+
+// CanonicalChain returns the canonical chain from genesis, or from the prune
+// anchor, to the tip.
+func (bc *Blockchain) CanonicalChain() []domain.Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return append([]domain.Block(nil), bc.chain...)
+}
+
+// CanonicalRange returns up to count canonical blocks starting at height,
+// read from a single consistent view of the chain.
+func (bc *Blockchain) CanonicalRange(height uint64, count int) ([]domain.Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if bc.anchor != nil && height < bc.anchor.Index {
+		return nil, ErrPruned
+	}
+	if len(bc.chain) == 0 || count <= 0 {
+		return nil, nil
+	}
+	base := bc.chain[0].Index
+	if height < base || height-base >= uint64(len(bc.chain)) {
+		return nil, nil
+	}
+	start := int(height - base)
+	end := start + count
+	if end > len(bc.chain) {
+		end = len(bc.chain)
+	}
+	return append([]domain.Block(nil), bc.chain[start:end]...), nil
+}
+
+func (bc *Blockchain) Account(address string) domain.Account {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.state[address]
+}
+
+// Accounts returns a copy of the state at the canonical tip.
+func (bc *Blockchain) Accounts() map[string]domain.Account {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return copyState(bc.state)
+}
+
+func (bc *Blockchain) Validator(name string) (domain.Validator, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	v, ok := bc.validators[name]
+	if !ok || v == nil {
+		return domain.Validator{}, false
+	}
+	return *v, true
+}
+
+func (bc *Blockchain) Validators() map[string]domain.Validator {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	out := make(map[string]domain.Validator, len(bc.validators))
+	for name, v := range bc.validators {
+		out[name] = *v
+	}
+	return out
+}
+
+func (bc *Blockchain) ValidatorStats(name string) (domain.ValidatorStats, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	s, ok := bc.stats[name]
+	if !ok || s == nil {
+		return domain.ValidatorStats{}, false
+	}
+	return *s, true
+}
+
+func (bc *Blockchain) FinalizedSlot() uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.finalizedSlot
+}
+
+func (bc *Blockchain) LastProcessedSlot() uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.lastProcessedSlot
+}
+
+// JailedValidators lists, sorted, the validators jailed at the tip slot.
+func (bc *Blockchain) JailedValidators() []string {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	var out []string
+	for name := range bc.validators {
+		if consensus.IsJailed(bc.stats, name, bc.chainTipSlot()) {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (bc *Blockchain) Equivocations() []EquivocationProof {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return append([]EquivocationProof(nil), bc.equivocations...)
+}
+
+// Config, Clock and Logger are fixed when the chain is built.
+
+func (bc *Blockchain) Config() ChainConfig {
+	return bc.config
+}
+
+func (bc *Blockchain) Clock() ports.Clock {
+	return bc.clock
+}
+
+func (bc *Blockchain) Logger() ports.Logger {
+	return bc.logger
+}
+
+// MempoolLen returns the number of pending transactions.
+func (bc *Blockchain) MempoolLen() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if bc.mempool == nil {
+		return 0
+	}
+	return bc.mempool.Len()
+}
+
+func (bc *Blockchain) HasPendingTx(hash string) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.mempool != nil && bc.mempool.Has(hash)
+}
@@ -8,14 +8,16 @@ import (
 )
 
 func (bc *Blockchain) RestoreFromStorage(blockStore ports.BlockStore, snapshotStore ports.SnapshotStore) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
 	if blockStore == nil {
 		return errors.New("block store required")
 	}
 	tip, ok := blockStore.GetTip()
 	if !ok {
 		// Fresh store, persist genesis so the node can restart safely.
-		if len(bc.Chain) > 0 {
-			_ = blockStore.SaveBlock(bc.Chain[0])
+		if len(bc.chain) > 0 {
+			_ = blockStore.SaveBlock(bc.chain[0])
 		}
 		return nil
 	}
@@ -37,17 +39,29 @@ func (bc *Blockchain) RestoreFromStorage(blockStore ports.BlockStore, snapshotSt
 			_ = stateRoot // reserved for Phase 3 (state replay from snapshot)
 		}
 	}
-	blocks, err := blockStore.GetRange(0, tip.Index)
+	start := uint64(0)
+	if prunable, ok := blockStore.(ports.PrunableBlockStore); ok {
+		anchor, ok, err := prunable.LoadPruneAnchor()
+		if err != nil {
+			return err
+		}
+		if ok {
+			bc.anchor = &anchor
+			bc.prunedEpoch = bc.epochForSlot(anchor.Slot)
+			start = anchor.Index
+		}
+	}
+	blocks, err := blockStore.GetRange(start, tip.Index)
 	if err != nil {
 		return err
 	}
 
-	bc.Blocks = make(map[string]domain.Block)
-	bc.Parents = make(map[string]string)
+	bc.blocks = make(map[string]domain.Block)
+	bc.parents = make(map[string]string)
 	for _, b := range blocks {
 		bc.insertBlock(b)
 	}
-	bc.CanonicalTip = tip.Hash
+	bc.canonicalTip = tip.Hash
 	bc.rebuildCanonicalChain()
 	bc.updateFinality()
 	return nil
@@ -0,0 +1,45 @@
+package domain
+
+type BlockHeader struct {
+	Index     uint64
+	PrevHash  string
+	Slot      uint64
+	Tick      uint64
+	Validator string
+	TxRoot    string
+	StateRoot string
+	PoHHash   string
+	Signature []byte
+	Hash      string
+}
+
+func (b Block) Header() BlockHeader {
+	return BlockHeader{
+		Index:     b.Index,
+		PrevHash:  b.PrevHash,
+		Slot:      b.Slot,
+		Tick:      b.Tick,
+		Validator: b.Validator,
+		TxRoot:    b.TxRoot,
+		StateRoot: b.StateRoot,
+		PoHHash:   b.PoHHash,
+		Signature: b.Signature,
+		Hash:      b.Hash,
+	}
+}
+
+func (h BlockHeader) WithBody(txs []Transaction) Block {
+	return Block{
+		Index:        h.Index,
+		PrevHash:     h.PrevHash,
+		Slot:         h.Slot,
+		Tick:         h.Tick,
+		Validator:    h.Validator,
+		TxRoot:       h.TxRoot,
+		StateRoot:    h.StateRoot,
+		PoHHash:      h.PoHHash,
+		Signature:    h.Signature,
+		Hash:         h.Hash,
+		Transactions: txs,
+	}
+}
@@ -0,0 +1,9 @@
+package domain
+
+type PruneAnchor struct {
+	Hash   string
+	Index  uint64
+	Slot   uint64
+	Weight uint64
+	State  map[string]Account
+}
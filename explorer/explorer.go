@@ -0,0 +1,284 @@
+// Package explorer serves a read-only, server-rendered block explorer for a
+// chain. Templates and styles are embedded in the binary.
+package explorer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"xenium/core"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+const defaultLatest = 20
+
+// TxRecord is one transaction in an account's history.
+type TxRecord struct {
+	Tx        domain.Transaction
+	BlockHash string
+	Height    uint64
+	Slot      uint64
+}
+
+type Config struct {
+	Chain  *core.Blockchain
+	Logger ports.Logger
+	// History returns up to limit transactions touching address, newest
+	// first. By default the canonical chain is scanned.
+	History func(address string, limit int) []TxRecord
+	Latest  int
+}
+
+// Server implements http.Handler.
+type Server struct {
+	cfg   Config
+	mux   *http.ServeMux
+	pages map[string]*template.Template
+}
+
+func New(cfg Config) (*Server, error) {
+	if cfg.Logger == nil {
+		cfg.Logger = cfg.Chain.Logger()
+	}
+	if cfg.Latest <= 0 {
+		cfg.Latest = defaultLatest
+	}
+	s := &Server{cfg: cfg, mux: http.NewServeMux(), pages: make(map[string]*template.Template)}
+	if cfg.History == nil {
+		s.cfg.History = s.scanHistory
+	}
+	for _, page := range []string{"index", "block", "account", "validators", "epochs", "forks", "error"} {
+		t, err := template.New("layout.html").Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+page+".html")
+		if err != nil {
+			return nil, err
+		}
+		s.pages[page] = t
+	}
+	s.mux.HandleFunc("GET /{$}", s.index)
+	s.mux.HandleFunc("GET /block/{id}", s.block)
+	s.mux.HandleFunc("GET /account/{address}", s.account)
+	s.mux.HandleFunc("GET /validators", s.validators)
+	s.mux.HandleFunc("GET /epochs", s.epochs)
+	s.mux.HandleFunc("GET /forks", s.forks)
+	s.mux.HandleFunc("GET /search", s.search)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+var funcs = template.FuncMap{
+	"short": func(s string) string {
+		if len(s) <= 16 {
+			return s
+		}
+		return s[:8] + "…" + s[len(s)-6:]
+	},
+	"percent": func(f float64) string {
+		return strconv.FormatFloat(f*100, 'f', 1, 64) + "%"
+	},
+}
+
+type page struct {
+	Title string
+	Tip   domain.Block
+	Final uint64
+	Data  any
+}
+
+func (s *Server) render(w http.ResponseWriter, status int, name, title string, data any) {
+	var buf bytes.Buffer
+	p := page{Title: title, Tip: s.cfg.Chain.Tip(), Final: s.cfg.Chain.FinalizedSlot(), Data: data}
+	if err := s.pages[name].Execute(&buf, p); err != nil {
+		s.cfg.Logger.Errorf("Explorer: render %s: %v", name, err)
+		http.Error(w, "template error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
+
+func (s *Server) notFound(w http.ResponseWriter, msg string) {
+	s.render(w, http.StatusNotFound, "error", "Not found", msg)
+}
+
+type blockRow struct {
+	domain.Block
+	TxCount int
+}
+
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	tip := s.cfg.Chain.Tip()
+	start := uint64(0)
+	if tip.Index+1 > uint64(s.cfg.Latest) {
+		start = tip.Index + 1 - uint64(s.cfg.Latest)
+	}
+	if pruned := s.cfg.Chain.PrunedHeight(); start < pruned {
+		start = pruned
+	}
+	blocks, err := s.cfg.Chain.CanonicalRange(start, int(tip.Index-start+1))
+	if err != nil {
+		s.cfg.Logger.Warnf("Explorer: latest blocks: %v", err)
+	}
+	rows := make([]blockRow, 0, len(blocks))
+	for i := len(blocks) - 1; i >= 0; i-- {
+		rows = append(rows, blockRow{Block: blocks[i], TxCount: len(blocks[i].Transactions)})
+	}
+	s.render(w, http.StatusOK, "index", "Latest blocks", rows)
+}
+
+type blockView struct {
+	Block     domain.Block
+	Canonical bool
+}
+
+// block accepts a height or a hash.
+func (s *Server) block(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var b domain.Block
+	var err error
+	if height, perr := strconv.ParseUint(id, 10, 64); perr == nil {
+		b, err = s.cfg.Chain.GetBlockByHeight(height)
+	} else {
+		b, err = s.cfg.Chain.GetBlockByHash(id)
+	}
+	if err != nil {
+		if errors.Is(err, core.ErrPruned) {
+			s.notFound(w, "Block "+id+" has been pruned.")
+			return
+		}
+		s.notFound(w, "No block "+id+".")
+		return
+	}
+	canonical := false
+	if c, err := s.cfg.Chain.GetBlockByHeight(b.Index); err == nil {
+		canonical = c.Hash == b.Hash
+	}
+	s.render(w, http.StatusOK, "block", "Block "+strconv.FormatUint(b.Index, 10), blockView{Block: b, Canonical: canonical})
+}
+
+type accountView struct {
+	Address string
+	Account domain.Account
+	History []TxRecord
+}
+
+func (s *Server) account(w http.ResponseWriter, r *http.Request) {
+	addr := r.PathValue("address")
+	view := accountView{
+		Address: addr,
+		Account: s.cfg.Chain.Account(addr),
+		History: s.cfg.History(addr, 100),
+	}
+	s.render(w, http.StatusOK, "account", "Account", view)
+}
+
+// scanHistory walks the canonical chain from the tip down.
+func (s *Server) scanHistory(address string, limit int) []TxRecord {
+	chain := s.cfg.Chain.CanonicalChain()
+	var out []TxRecord
+	for i := len(chain) - 1; i >= 0 && len(out) < limit; i-- {
+		b := chain[i]
+		for j := len(b.Transactions) - 1; j >= 0 && len(out) < limit; j-- {
+			tx := b.Transactions[j]
+			if tx.From == address || tx.To == address {
+				out = append(out, TxRecord{Tx: tx, BlockHash: b.Hash, Height: b.Index, Slot: b.Slot})
+			}
+		}
+	}
+	return out
+}
+
+type validatorRow struct {
+	core.ValidatorSummary
+	Stake  int
+	Jailed bool
+}
+
+func (s *Server) validators(w http.ResponseWriter, r *http.Request) {
+	validators := s.cfg.Chain.Validators()
+	jailed := make(map[string]bool)
+	for _, name := range s.cfg.Chain.JailedValidators() {
+		jailed[name] = true
+	}
+	var rows []validatorRow
+	for _, sum := range s.cfg.Chain.GetValidatorSummaries() {
+		rows = append(rows, validatorRow{ValidatorSummary: sum, Stake: validators[sum.Name].Stake, Jailed: jailed[sum.Name]})
+	}
+	s.render(w, http.StatusOK, "validators", "Validators", rows)
+}
+
+func (s *Server) epochs(w http.ResponseWriter, r *http.Request) {
+	snaps := s.cfg.Chain.GetAllEpochSnapshots()
+	for i, j := 0, len(snaps)-1; i < j; i, j = i+1, j-1 {
+		snaps[i], snaps[j] = snaps[j], snaps[i]
+	}
+	s.render(w, http.StatusOK, "epochs", "Epoch snapshots", snaps)
+}
+
+type forkRow struct {
+	core.ForkCandidate
+	Canonical  bool
+	ForkHeight uint64
+	ForkHash   string
+	Length     int
+}
+
+// forks shows every chain tip with the point where its branch leaves the
+// canonical chain.
+func (s *Server) forks(w http.ResponseWriter, r *http.Request) {
+	canonical := make(map[string]bool)
+	for _, b := range s.cfg.Chain.CanonicalChain() {
+		canonical[b.Hash] = true
+	}
+	tip := s.cfg.Chain.CanonicalTipHash()
+	var rows []forkRow
+	for _, c := range s.cfg.Chain.GetForkCandidates() {
+		row := forkRow{ForkCandidate: c, Canonical: c.Hash == tip}
+		hash := c.Hash
+		for !canonical[hash] {
+			b, err := s.cfg.Chain.GetBlockByHash(hash)
+			if err != nil {
+				break
+			}
+			row.Length++
+			hash = b.PrevHash
+		}
+		if b, err := s.cfg.Chain.GetBlockByHash(hash); err == nil {
+			row.ForkHeight, row.ForkHash = b.Index, b.Hash
+		}
+		rows = append(rows, row)
+	}
+	s.render(w, http.StatusOK, "forks", "Fork tree", rows)
+}
+
+// search sends heights and known block hashes to the block page and
+// anything else to the account page.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	switch {
+	case q == "":
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	case isHeight(q) || s.cfg.Chain.HasBlock(q):
+		http.Redirect(w, r, "/block/"+url.PathEscape(q), http.StatusSeeOther)
+	default:
+		http.Redirect(w, r, "/account/"+url.PathEscape(q), http.StatusSeeOther)
+	}
+}
+
+func isHeight(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
@@ -0,0 +1,105 @@
+package explorer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+)
+
+func TestExplorerPages(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	bc := core.NewBlockchain(core.ChainConfig{EpochLength: 10, DeterministicPoH: true, PoHSeed: 1}, nil, nil)
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("validator: %v", err)
+	}
+	bc.SetBalance(w.Address, 100)
+	genesis := bc.CanonicalTipHash()
+
+	tx := domain.Transaction{To: "bob", Amount: 10, Fee: 1, Nonce: 1}
+	if err := consensus.SignTransaction(w.PrivateKey, &tx); err != nil {
+		t.Fatalf("sign tx: %v", err)
+	}
+	if err := bc.AddTx(tx); err != nil {
+		t.Fatalf("add tx: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	fork, err := bc.AddBlockExternal(genesis, nil)
+	if err != nil {
+		t.Fatalf("fork block: %v", err)
+	}
+	first, err := bc.GetBlockByHeight(1)
+	if err != nil {
+		t.Fatalf("block 1: %v", err)
+	}
+
+	s, err := New(Config{Chain: bc})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	get := func(path string) (int, string, *http.Response) {
+		t.Helper()
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), resp
+	}
+
+	for _, tc := range []struct {
+		path string
+		want []string
+	}{
+		{"/", []string{"Latest blocks", `href="/block/3"`, `href="/block/` + first.Hash + `"`}},
+		{"/block/1", []string{first.Hash, "canonical", `href="/account/bob"`}},
+		{"/block/" + fork, []string{fork, "fork"}},
+		{"/account/bob", []string{"<dd>10</dd>", "in", first.Hash}},
+		{"/account/" + w.Address, []string{"<dd>1</dd>", "out"}},
+		{"/validators", []string{"Alice", "100", "active"}},
+		{"/epochs", []string{"Epoch 0", "total stake 100"}},
+		{"/forks", []string{fork, bc.CanonicalTipHash(), "canonical", "#0"}},
+	} {
+		status, body, _ := get(tc.path)
+		if status != http.StatusOK {
+			t.Fatalf("%s: status %d\n%s", tc.path, status, body)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(body, want) {
+				t.Fatalf("%s: missing %q in:\n%s", tc.path, want, body)
+			}
+		}
+	}
+
+	if status, body, _ := get("/block/99"); status != http.StatusNotFound || !strings.Contains(body, "No block 99") {
+		t.Fatalf("unknown block: status %d\n%s", status, body)
+	}
+	for q, want := range map[string]string{
+		"2":        "/block/2",
+		first.Hash: "/block/" + first.Hash,
+		"bob":      "/account/bob",
+		"":         "/",
+	} {
+		status, _, resp := get("/search?q=" + q)
+		if status != http.StatusSeeOther || resp.Header.Get("Location") != want {
+			t.Fatalf("search %q: status %d location %q, want %q", q, status, resp.Header.Get("Location"), want)
+		}
+	}
+}
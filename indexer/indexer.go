@@ -0,0 +1,619 @@
+// Package indexer keeps an optional transaction index over the canonical
+// chain: transaction hash to location, and address to transactions. It
+// follows fork-choice through the chain event bus, reverting blocks that
+// are reorged out, and appends its changes to a log in the data dir
+// whenever a slot is finalized. Only locations of transactions are stored;
+// bodies are read from the chain.
+package indexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"xenium/core"
+	"xenium/domain"
+	"xenium/ports"
+)
+
+const (
+	indexFileName   = "txindex.jsonl"
+	indexVersion    = 3
+	defaultPageSize = 50
+	// compactSlack is how many log records beyond twice the live ones are
+	// tolerated before the log is rewritten.
+	compactSlack = 1024
+)
+
+// legacyIndexFileName is where version 2 kept the whole index as one
+// document; it is removed on open.
+const legacyIndexFileName = "txindex.json"
+
+var ErrTxNotFound = errors.New("transaction not found")
+
+type Config struct {
+	Chain *core.Blockchain
+	// Dir is the data dir the index is saved in; empty keeps it in memory.
+	Dir      string
+	Logger   ports.Logger
+	PageSize int
+}
+
+// TxResult is a transaction with its place on the canonical chain. When
+// the block has been pruned only the hash and addresses of Tx are known.
+type TxResult struct {
+	Tx        domain.Transaction `json:"tx"`
+	BlockHash string             `json:"block_hash"`
+	Height    uint64             `json:"height"`
+	Slot      uint64             `json:"slot"`
+	Index     int                `json:"index"`
+}
+
+// HistoryPage is one page of an account's transactions, newest first.
+// Pages are numbered from zero.
+type HistoryPage struct {
+	Address  string     `json:"address"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"page_size"`
+	Total    int        `json:"total"`
+	Txs      []TxResult `json:"txs"`
+}
+
+type indexedBlock struct {
+	Hash   string      `json:"hash"`
+	Height uint64      `json:"height"`
+	Slot   uint64      `json:"slot"`
+	Txs    []indexedTx `json:"txs,omitempty"`
+}
+
+// indexedTx is what the index keeps of a transaction: enough to find it in
+// its block and to list it under its addresses.
+type indexedTx struct {
+	Hash string `json:"hash"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// indexTip is the last canonical block the index has seen. It need not
+// hold transactions, so it is kept apart from the indexed blocks.
+type indexTip struct {
+	Hash   string `json:"hash"`
+	Height uint64 `json:"height"`
+}
+
+// logRecord is one line of the index file. The first line carries only the
+// version; each later one adds a block, removes the top block, or moves
+// the tip.
+type logRecord struct {
+	Version int           `json:"version,omitempty"`
+	Block   *indexedBlock `json:"block,omitempty"`
+	Revert  string        `json:"revert,omitempty"`
+	Tip     *indexTip     `json:"tip,omitempty"`
+}
+
+// txRef points at blocks[block].Txs[tx].
+type txRef struct {
+	block int
+	tx    int
+}
+
+// Indexer is safe for concurrent use. Chain events are applied in place;
+// when one does not line up with the index (it was behind, or a handler
+// was skipped) the index is marked stale and the next query, or Sync,
+// rebuilds the difference from the canonical chain.
+type Indexer struct {
+	chain    *core.Blockchain
+	path     string
+	logger   ports.Logger
+	pageSize int
+	sub      int
+	flushReq chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+	flushMu  sync.Mutex
+
+	mu sync.Mutex
+	// blocks holds only blocks with transactions.
+	blocks []indexedBlock
+	tip    indexTip
+	txs    map[string]txRef
+	byAddr map[string][]txRef
+	gen    uint64
+	stale  bool
+	// pending are the records not yet appended to the file, which holds
+	// records lines and was last given savedTip. rewrite asks the next
+	// Flush to write the file afresh.
+	pending  []logRecord
+	records  int
+	savedTip indexTip
+	rewrite  bool
+}
+
+// Open loads the saved index, if any, subscribes to the chain and catches
+// up with the canonical chain. History below a pruned anchor that was
+// never indexed is not recoverable and is skipped.
+func Open(cfg Config) (*Indexer, error) {
+	if cfg.Logger == nil {
+		cfg.Logger = cfg.Chain.Logger()
+	}
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = defaultPageSize
+	}
+	ix := &Indexer{
+		chain:    cfg.Chain,
+		logger:   cfg.Logger,
+		pageSize: cfg.PageSize,
+		txs:      make(map[string]txRef),
+		byAddr:   make(map[string][]txRef),
+	}
+	if cfg.Dir != "" {
+		ix.path = filepath.Join(cfg.Dir, indexFileName)
+		os.Remove(filepath.Join(cfg.Dir, legacyIndexFileName))
+		if err := ix.load(); err != nil {
+			return nil, err
+		}
+	}
+	ix.flushReq = make(chan struct{}, 1)
+	ix.done = make(chan struct{})
+	if ix.path != "" {
+		ix.wg.Add(1)
+		go ix.flushLoop()
+	}
+	ix.sub = cfg.Chain.Subscribe(ix.onEvent)
+	ix.Sync()
+	return ix, nil
+}
+
+// Close stops following the chain and saves the index.
+func (ix *Indexer) Close() error {
+	ix.chain.Unsubscribe(ix.sub)
+	close(ix.done)
+	ix.wg.Wait()
+	return ix.Flush()
+}
+
+// flushLoop saves the index when a slot is finalized. Chain events are
+// delivered under the chain lock, so the handler only signals this loop.
+func (ix *Indexer) flushLoop() {
+	defer ix.wg.Done()
+	for {
+		select {
+		case <-ix.flushReq:
+			if err := ix.Flush(); err != nil {
+				ix.logger.Errorf("Saving %s: %v", indexFileName, err)
+			}
+		case <-ix.done:
+			return
+		}
+	}
+}
+
+// Flush appends the changes since the last save to the index file. When
+// the file has grown to more than twice the live records it is rewritten
+// instead.
+func (ix *Indexer) Flush() error {
+	ix.flushMu.Lock()
+	defer ix.flushMu.Unlock()
+	ix.mu.Lock()
+	if ix.path == "" {
+		ix.mu.Unlock()
+		return nil
+	}
+	recs := ix.pending
+	if ix.tip != ix.savedTip {
+		tip := ix.tip
+		recs = append(recs, logRecord{Tip: &tip})
+	}
+	if len(recs) == 0 && !ix.rewrite {
+		ix.mu.Unlock()
+		return nil
+	}
+	compact := ix.rewrite || ix.records+len(recs) > 2*(len(ix.blocks)+1)+compactSlack
+	if compact {
+		recs = ix.snapshot()
+	}
+	data, err := encodeRecords(recs)
+	tip := ix.tip
+	ix.pending = nil
+	ix.mu.Unlock()
+	if err == nil {
+		if compact {
+			err = writeFile(ix.path, data)
+		} else {
+			err = appendFile(ix.path, data)
+		}
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err != nil {
+		// The file may now end in a partial record.
+		ix.rewrite = true
+		return err
+	}
+	if compact {
+		ix.records = 0
+		ix.rewrite = false
+	}
+	ix.records += len(recs)
+	ix.savedTip = tip
+	return nil
+}
+
+// snapshot returns the records that rebuild the index as it is now.
+func (ix *Indexer) snapshot() []logRecord {
+	recs := make([]logRecord, 0, len(ix.blocks)+2)
+	recs = append(recs, logRecord{Version: indexVersion})
+	for i := range ix.blocks {
+		recs = append(recs, logRecord{Block: &ix.blocks[i]})
+	}
+	tip := ix.tip
+	return append(recs, logRecord{Tip: &tip})
+}
+
+func encodeRecords(recs []logRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range recs {
+		if err := enc.Encode(r); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func appendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// load replays the index file. A file that cannot be replayed, such as one
+// cut short by a crash, is dropped and the index is rebuilt from the chain.
+func (ix *Indexer) load() error {
+	ix.rewrite = true
+	f, err := os.Open(ix.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	var head logRecord
+	if err := dec.Decode(&head); err != nil {
+		ix.logger.Warnf("Ignoring unreadable %s, rebuilding: %v", indexFileName, err)
+		return nil
+	}
+	if head.Version != indexVersion {
+		ix.logger.Warnf("Ignoring %s with version %d, rebuilding", indexFileName, head.Version)
+		return nil
+	}
+	n := 1
+	for {
+		var r logRecord
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			break
+		}
+		if err == nil && !ix.replay(r) {
+			err = errors.New("record does not follow the index")
+		}
+		if err != nil {
+			ix.logger.Warnf("Ignoring %s after %d records, rebuilding: %v", indexFileName, n, err)
+			ix.blocks, ix.tip = nil, indexTip{}
+			ix.txs = make(map[string]txRef)
+			ix.byAddr = make(map[string][]txRef)
+			ix.pending = nil
+			return nil
+		}
+		n++
+	}
+	ix.pending = nil
+	ix.records = n
+	ix.savedTip = ix.tip
+	ix.rewrite = false
+	return nil
+}
+
+func (ix *Indexer) replay(r logRecord) bool {
+	switch {
+	case r.Block != nil:
+		ix.push(*r.Block)
+	case r.Revert != "":
+		if n := len(ix.blocks); n == 0 || ix.blocks[n-1].Hash != r.Revert {
+			return false
+		}
+		ix.revertTop()
+	case r.Tip != nil:
+		ix.tip = *r.Tip
+	default:
+		return false
+	}
+	return true
+}
+
+// Height returns the height of the last block the index has seen.
+func (ix *Indexer) Height() uint64 {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.tip.Height
+}
+
+func (ix *Indexer) GetTransaction(hash string) (TxResult, error) {
+	ix.syncIfStale()
+	ix.mu.Lock()
+	ref, ok := ix.txs[hash]
+	var res TxResult
+	if ok {
+		res = ix.result(ref)
+	}
+	ix.mu.Unlock()
+	if !ok {
+		return TxResult{}, ErrTxNotFound
+	}
+	out := []TxResult{res}
+	ix.loadBodies(out)
+	return out[0], nil
+}
+
+// GetAccountHistory returns page number page of the transactions sent
+// from or to addr.
+func (ix *Indexer) GetAccountHistory(addr string, page int) HistoryPage {
+	if page < 0 {
+		page = 0
+	}
+	txs, total := ix.history(addr, page*ix.pageSize, ix.pageSize)
+	return HistoryPage{Address: addr, Page: page, PageSize: ix.pageSize, Total: total, Txs: txs}
+}
+
+// History returns up to limit of addr's transactions, newest first,
+// skipping the first offset.
+func (ix *Indexer) History(addr string, offset, limit int) []TxResult {
+	txs, _ := ix.history(addr, offset, limit)
+	return txs
+}
+
+func (ix *Indexer) history(addr string, offset, limit int) ([]TxResult, int) {
+	ix.syncIfStale()
+	ix.mu.Lock()
+	refs := ix.byAddr[addr]
+	out := []TxResult{}
+	for i := len(refs) - 1 - offset; i >= 0 && len(out) < limit; i-- {
+		out = append(out, ix.result(refs[i]))
+	}
+	ix.mu.Unlock()
+	ix.loadBodies(out)
+	return out, len(refs)
+}
+
+func (ix *Indexer) result(ref txRef) TxResult {
+	b := ix.blocks[ref.block]
+	tx := b.Txs[ref.tx]
+	return TxResult{Tx: domain.Transaction{Hash: tx.Hash, From: tx.From, To: tx.To}, BlockHash: b.Hash, Height: b.Height, Slot: b.Slot, Index: ref.tx}
+}
+
+// loadBodies fills in transaction bodies from the chain. It takes the chain
+// lock, so it must run without ix.mu held.
+func (ix *Indexer) loadBodies(results []TxResult) {
+	for i := range results {
+		r := &results[i]
+		block, err := ix.chain.GetBlockByHash(r.BlockHash)
+		if err != nil || r.Index >= len(block.Transactions) || block.Transactions[r.Index].Hash != r.Tx.Hash {
+			continue
+		}
+		r.Tx = block.Transactions[r.Index]
+	}
+}
+
+func (ix *Indexer) onEvent(e core.Event) {
+	if _, ok := e.(core.SlotFinalized); ok {
+		select {
+		case ix.flushReq <- struct{}{}:
+		default:
+		}
+		return
+	}
+	change, ok := e.(core.CanonicalTipChanged)
+	if !ok {
+		return
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.gen++
+	if ix.stale {
+		return
+	}
+	for i := len(change.Reverted) - 1; i >= 0; i-- {
+		if !ix.revert(change.Reverted[i]) {
+			ix.stale = true
+			return
+		}
+	}
+	for _, b := range change.Applied {
+		if !ix.apply(b) {
+			ix.stale = true
+			return
+		}
+	}
+}
+
+func (ix *Indexer) syncIfStale() {
+	ix.mu.Lock()
+	stale := ix.stale
+	ix.mu.Unlock()
+	if stale {
+		ix.Sync()
+	}
+}
+
+// Sync reconciles the index with the canonical chain. It must not be
+// called from a chain event handler.
+func (ix *Indexer) Sync() {
+	for {
+		ix.mu.Lock()
+		gen := ix.gen
+		ix.mu.Unlock()
+		chain := ix.chain.CanonicalChain()
+		ix.mu.Lock()
+		// A tip change since the snapshot was taken may already be in the
+		// index; take a fresh one.
+		if ix.gen != gen {
+			ix.mu.Unlock()
+			continue
+		}
+		ix.reconcile(chain)
+		ix.stale = false
+		ix.mu.Unlock()
+		return
+	}
+}
+
+func (ix *Indexer) reconcile(chain []domain.Block) {
+	if len(chain) == 0 {
+		return
+	}
+	base := chain[0].Index
+	// Blocks below the pruned anchor are finalized.
+	kept := func(hash string, height uint64) bool {
+		if height < base {
+			return true
+		}
+		i := height - base
+		return i < uint64(len(chain)) && chain[i].Hash == hash
+	}
+	for len(ix.blocks) > 0 {
+		top := ix.blocks[len(ix.blocks)-1]
+		if kept(top.Hash, top.Height) {
+			break
+		}
+		ix.revertTop()
+	}
+	if ix.tip.Hash != "" && !kept(ix.tip.Hash, ix.tip.Height) {
+		// Everything above the top indexed block was empty, so indexing
+		// can resume right after it.
+		ix.tip = indexTip{}
+		if n := len(ix.blocks); n > 0 {
+			ix.tip = indexTip{Hash: ix.blocks[n-1].Hash, Height: ix.blocks[n-1].Height}
+		}
+	}
+	next := base
+	if ix.tip.Hash != "" {
+		next = ix.tip.Height + 1
+	}
+	if next < base {
+		ix.logger.With("from", next, "to", base-1).Warnf("Blocks pruned before they were indexed")
+		next = base
+	}
+	for _, b := range chain[next-base:] {
+		ix.advance(b)
+	}
+}
+
+func indexBlock(b domain.Block) indexedBlock {
+	ib := indexedBlock{Hash: b.Hash, Height: b.Index, Slot: b.Slot}
+	for _, tx := range b.Transactions {
+		ib.Txs = append(ib.Txs, indexedTx{Hash: tx.Hash, From: tx.From, To: tx.To})
+	}
+	return ib
+}
+
+// advance moves the tip to b, indexing it if it holds transactions.
+func (ix *Indexer) advance(b domain.Block) {
+	if len(b.Transactions) > 0 {
+		ix.push(indexBlock(b))
+	}
+	ix.tip = indexTip{Hash: b.Hash, Height: b.Index}
+}
+
+// apply adds b if it extends the indexed tip.
+func (ix *Indexer) apply(b domain.Block) bool {
+	if ix.tip.Hash == "" || b.PrevHash != ix.tip.Hash || b.Index != ix.tip.Height+1 {
+		return false
+	}
+	ix.advance(b)
+	return true
+}
+
+// revert moves the tip back past b if b is the tip.
+func (ix *Indexer) revert(b domain.Block) bool {
+	if ix.tip.Hash != b.Hash {
+		return false
+	}
+	if n := len(ix.blocks); n > 0 && ix.blocks[n-1].Hash == b.Hash {
+		ix.revertTop()
+	}
+	ix.tip = indexTip{Hash: b.PrevHash, Height: b.Index - 1}
+	return true
+}
+
+func (ix *Indexer) push(b indexedBlock) {
+	n := len(ix.blocks)
+	ix.blocks = append(ix.blocks, b)
+	for i, tx := range b.Txs {
+		ref := txRef{block: n, tx: i}
+		ix.txs[tx.Hash] = ref
+		for _, addr := range addresses(tx) {
+			ix.byAddr[addr] = append(ix.byAddr[addr], ref)
+		}
+	}
+	if ix.path != "" {
+		ix.pending = append(ix.pending, logRecord{Block: &b})
+	}
+}
+
+func (ix *Indexer) revertTop() {
+	n := len(ix.blocks) - 1
+	b := ix.blocks[n]
+	for i := len(b.Txs) - 1; i >= 0; i-- {
+		tx := b.Txs[i]
+		if ref, ok := ix.txs[tx.Hash]; ok && ref.block == n {
+			delete(ix.txs, tx.Hash)
+		}
+		for _, addr := range addresses(tx) {
+			refs := ix.byAddr[addr]
+			if len(refs) > 0 && refs[len(refs)-1].block == n {
+				refs = refs[:len(refs)-1]
+			}
+			if len(refs) == 0 {
+				delete(ix.byAddr, addr)
+			} else {
+				ix.byAddr[addr] = refs
+			}
+		}
+	}
+	ix.blocks = ix.blocks[:n]
+	if ix.path != "" {
+		ix.pending = append(ix.pending, logRecord{Revert: b.Hash})
+	}
+}
+
+func addresses(tx indexedTx) []string {
+	switch {
+	case tx.From == "" && tx.To == "":
+		return nil
+	case tx.From == "" || tx.From == tx.To:
+		return []string{tx.To}
+	case tx.To == "":
+		return []string{tx.From}
+	}
+	return []string{tx.From, tx.To}
+}
@@ -0,0 +1,278 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+)
+
+func newChain(t *testing.T) (*core.Blockchain, *domain.Wallet) {
+	t.Helper()
+	bc := core.NewBlockchain(core.ChainConfig{
+		MaxReorgDepth:        2,
+		FinalitySlots:        2,
+		MinReorgWeightDeltaP: 10,
+		EpochLength:          consensus.SlotsPerEpoch,
+		DeterministicPoH:     true,
+		PoHSeed:              1,
+	}, nil, nil)
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	bc.SetBalance(w.Address, 100)
+	return bc, w
+}
+
+func sendTx(t *testing.T, bc *core.Blockchain, w *domain.Wallet, to string, nonce uint64) domain.Transaction {
+	t.Helper()
+	tx := domain.Transaction{To: to, Amount: 10, Fee: 1, Nonce: nonce}
+	if err := consensus.SignTransaction(w.PrivateKey, &tx); err != nil {
+		t.Fatalf("sign tx: %v", err)
+	}
+	if err := bc.AddTx(tx); err != nil {
+		t.Fatalf("add tx: %v", err)
+	}
+	return tx
+}
+
+func TestIndexFollowsReorgs(t *testing.T) {
+	bc, w := newChain(t)
+	genesis := bc.CanonicalTipHash()
+	ix, err := Open(Config{Chain: bc, PageSize: 1})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer ix.Close()
+
+	first := sendTx(t, bc, w, "bob", 1)
+	second := sendTx(t, bc, w, "carol", 2)
+	for i := 0; i < 2; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	got, err := ix.GetTransaction(first.Hash)
+	if err != nil || got.Height != 1 || got.Index != 0 || got.Tx.To != "bob" {
+		t.Fatalf("unexpected lookup %+v, %v", got, err)
+	}
+	page := ix.GetAccountHistory(w.Address, 0)
+	if page.Total != 2 || len(page.Txs) != 1 || page.Txs[0].Tx.Hash != second.Hash {
+		t.Fatalf("expected newest tx on page 0, got %+v", page)
+	}
+	if page := ix.GetAccountHistory(w.Address, 1); len(page.Txs) != 1 || page.Txs[0].Tx.Hash != first.Hash {
+		t.Fatalf("expected oldest tx on page 1, got %+v", page)
+	}
+	if page := ix.GetAccountHistory(w.Address, 2); len(page.Txs) != 0 || page.Total != 2 {
+		t.Fatalf("expected empty page 2, got %+v", page)
+	}
+
+	// A heavier empty fork from genesis reorgs both transactions out.
+	parent := genesis
+	for i := 0; i < 3; i++ {
+		if parent, err = bc.AddBlockExternal(parent, nil); err != nil {
+			t.Fatalf("fork block %d: %v", i, err)
+		}
+	}
+	if bc.CanonicalTipHash() != parent {
+		t.Fatalf("fork did not become canonical")
+	}
+	if ix.stale {
+		t.Fatalf("expected the reorg to be applied from chain events")
+	}
+	if _, err := ix.GetTransaction(first.Hash); !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("expected reorged tx to be gone, got %v", err)
+	}
+	if page := ix.GetAccountHistory("bob", 0); page.Total != 0 {
+		t.Fatalf("expected no history for bob, got %+v", page)
+	}
+	if ix.Height() != 3 {
+		t.Fatalf("expected index at height 3, got %d", ix.Height())
+	}
+}
+
+func TestIndexPersistsAndCatchesUp(t *testing.T) {
+	dir := t.TempDir()
+	bc, w := newChain(t)
+	ix, err := Open(Config{Chain: bc, Dir: dir})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	first := sendTx(t, bc, w, "bob", 1)
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	if err := ix.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	saved := &Indexer{path: filepath.Join(dir, indexFileName), logger: bc.Logger(), txs: make(map[string]txRef), byAddr: make(map[string][]txRef)}
+	if err := saved.load(); err != nil || len(saved.blocks) != 1 || saved.txs[first.Hash].block != 0 || saved.tip.Height != 1 {
+		t.Fatalf("expected the one block with a tx saved at tip 1, got %d blocks, tip %+v (%v)", len(saved.blocks), saved.tip, err)
+	}
+
+	// Blocks added while the index is closed are picked up on open.
+	second := sendTx(t, bc, w, "bob", 2)
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	ix, err = Open(Config{Chain: bc, Dir: dir})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer ix.Close()
+	for _, tx := range []domain.Transaction{first, second} {
+		if _, err := ix.GetTransaction(tx.Hash); err != nil {
+			t.Fatalf("lookup %s: %v", tx.Hash, err)
+		}
+	}
+	if page := ix.GetAccountHistory("bob", 0); page.Total != 2 || page.Txs[0].Height != 2 {
+		t.Fatalf("unexpected history %+v", page)
+	}
+
+	// A saved index from another chain is reconciled against this one.
+	other, _ := newChain(t)
+	ix2, err := Open(Config{Chain: other, Dir: dir})
+	if err != nil {
+		t.Fatalf("open on other chain: %v", err)
+	}
+	defer ix2.Close()
+	if _, err := ix2.GetTransaction(first.Hash); !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("expected foreign tx to be reverted, got %v", err)
+	}
+}
+
+func TestIndexSavedWhenSlotsFinalize(t *testing.T) {
+	dir := t.TempDir()
+	bc, w := newChain(t)
+	ix, err := Open(Config{Chain: bc, Dir: dir})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer ix.Close()
+	tx := sendTx(t, bc, w, "bob", 1)
+	for i := 0; i < 4; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	if bc.FinalizedSlot() == 0 {
+		t.Fatalf("expected a finalized slot")
+	}
+
+	// No Close: a crash now must not lose the finalized history.
+	path := filepath.Join(dir, indexFileName)
+	var saved *indexedBlock
+	deadline := time.Now().Add(5 * time.Second)
+	for saved == nil {
+		recs, err := readRecords(path)
+		for _, r := range recs {
+			if r.Block != nil {
+				saved = r.Block
+			}
+		}
+		if saved == nil && time.Now().After(deadline) {
+			t.Fatalf("index not saved after finality: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := saved.Txs; saved.Height != 1 || len(got) != 1 || got[0] != (indexedTx{Hash: tx.Hash, From: tx.From, To: tx.To}) {
+		t.Fatalf("expected a tx reference in the saved block, got %+v", got)
+	}
+	res, err := ix.GetTransaction(tx.Hash)
+	if err != nil || res.Tx.Amount != tx.Amount || res.Tx.Signature != tx.Signature {
+		t.Fatalf("expected the body to be read from the chain, got %+v (%v)", res, err)
+	}
+}
+
+func readRecords(path string) ([]logRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var recs []logRecord
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var r logRecord
+		if err := dec.Decode(&r); err != nil {
+			return recs, err
+		}
+		recs = append(recs, r)
+	}
+	return recs, nil
+}
+
+func TestIndexLogAppendsAndCompacts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, indexFileName)
+	bc, w := newChain(t)
+	ix, err := Open(Config{Chain: bc, Dir: dir})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer ix.Close()
+	tx := sendTx(t, bc, w, "bob", 1)
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	if err := ix.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	before, _ := os.ReadFile(path)
+
+	// An empty block only moves the tip, and the file is appended to.
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	if err := ix.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	after, _ := os.ReadFile(path)
+	if !bytes.HasPrefix(after, before) {
+		t.Fatalf("index file rewritten instead of appended to")
+	}
+	recs, err := readRecords(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if last := recs[len(recs)-1]; last.Tip == nil || last.Tip.Height != 2 {
+		t.Fatalf("expected a tip record at height 2, got %+v", last)
+	}
+	for _, r := range recs {
+		if r.Block != nil && len(r.Block.Txs) == 0 {
+			t.Fatalf("empty block %d saved", r.Block.Height)
+		}
+	}
+
+	// A log much longer than the index is rewritten on the next save.
+	ix.mu.Lock()
+	ix.records += compactSlack
+	ix.mu.Unlock()
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	if err := ix.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if recs, err = readRecords(path); err != nil || len(recs) != 3 || recs[0].Version != indexVersion || recs[2].Tip.Height != 3 {
+		t.Fatalf("expected a compacted log of 3 records, got %+v (%v)", recs, err)
+	}
+
+	saved := &Indexer{path: path, logger: bc.Logger(), txs: make(map[string]txRef), byAddr: make(map[string][]txRef)}
+	if err := saved.load(); err != nil || saved.tip.Height != 3 || saved.records != 3 {
+		t.Fatalf("expected the compacted log to load at tip 3, got %+v (%v)", saved.tip, err)
+	}
+	if _, ok := saved.txs[tx.Hash]; !ok {
+		t.Fatalf("tx lost in compaction")
+	}
+}
@@ -0,0 +1,300 @@
+// Package invariant checks the chain invariants listed in the README while
+// a Blockchain is being driven by tests or simulations:
+//
+//   - epoch snapshots never change once taken
+//   - the canonical chain never drops a finalized block, and the finalized
+//     slot never moves back
+//   - the account state equals a replay of the canonical chain
+//   - total supply (balances plus stake) only changes through block
+//     rewards, slashing and fees burned for blocks without a reward address
+//
+// Finality is checked as events arrive; everything else when Check is
+// called, which must not race with chain mutations.
+package invariant
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+)
+
+// Invariants reported in a Violation.
+const (
+	Snapshot = "snapshot"
+	Finality = "finality"
+	Replay   = "replay"
+	Supply   = "supply"
+)
+
+// maxDiffs bounds how many differing accounts a replay violation lists.
+const maxDiffs = 10
+
+// Violation describes a broken invariant.
+type Violation struct {
+	Invariant string
+	Detail    string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("invariant %s violated: %s", v.Invariant, v.Detail)
+}
+
+// Checker watches one chain. Attach it after genesis validators and
+// balances are set: supply is measured from that point.
+type Checker struct {
+	chain *core.Blockchain
+	sub   int
+
+	mu            sync.Mutex
+	snapshots     map[uint64]core.EpochSnapshot
+	finalized     map[string]uint64 // hash -> slot
+	finalizedSlot uint64
+	stakeBase     int
+	rewards       int
+	slashed       int
+	removed       map[string]bool
+	failed        *Violation // first violation found by the event handler
+}
+
+func Attach(bc *core.Blockchain) *Checker {
+	c := &Checker{
+		chain:     bc,
+		snapshots: make(map[uint64]core.EpochSnapshot),
+		finalized: make(map[string]uint64),
+		removed:   make(map[string]bool),
+	}
+	for _, v := range bc.Validators() {
+		c.stakeBase += v.Stake
+	}
+	c.finalizedSlot = bc.FinalizedSlot()
+	c.sub = bc.Subscribe(c.onEvent)
+	return c
+}
+
+func (c *Checker) Detach() {
+	c.chain.Unsubscribe(c.sub)
+}
+
+// onEvent runs under the chain lock and must not call back into the chain.
+func (c *Checker) onEvent(e core.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch ev := e.(type) {
+	case core.SlotFinalized:
+		if ev.Slot < c.finalizedSlot {
+			c.fail(Finality, "finalized slot moved back from %d to %d", c.finalizedSlot, ev.Slot)
+		}
+		c.finalizedSlot = ev.Slot
+		c.finalized[ev.Hash] = ev.Slot
+	case core.CanonicalTipChanged:
+		for _, b := range ev.Reverted {
+			if slot, ok := c.finalized[b.Hash]; ok {
+				c.fail(Finality, "reorg from %s to %s reverted block %s at height %d, finalized at slot %d",
+					short(ev.OldTip), short(ev.NewTip), short(b.Hash), b.Index, slot)
+			}
+		}
+	case core.BlockAccepted:
+		if !c.removed[ev.Block.Validator] {
+			c.rewards += consensus.BlockReward
+		}
+	case core.ValidatorSlashed:
+		c.slashed += ev.Amount
+		if ev.Stake == 0 {
+			c.removed[ev.Validator] = true
+		}
+	}
+}
+
+func (c *Checker) fail(invariant, format string, args ...any) {
+	if c.failed == nil {
+		c.failed = &Violation{Invariant: invariant, Detail: fmt.Sprintf(format, args...)}
+	}
+}
+
+// Check verifies every invariant against the chain as it is now and
+// returns the first violation, including any seen earlier by the event
+// handler.
+func (c *Checker) Check() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failed != nil {
+		return c.failed
+	}
+	for _, check := range []func() *Violation{c.checkSnapshots, c.checkFinalized, c.checkReplay} {
+		if v := check(); v != nil {
+			c.failed = v
+			return v
+		}
+	}
+	return nil
+}
+
+func (c *Checker) checkSnapshots() *Violation {
+	for _, snap := range c.chain.GetAllEpochSnapshots() {
+		prev, ok := c.snapshots[snap.Epoch]
+		if !ok {
+			c.snapshots[snap.Epoch] = snap
+			continue
+		}
+		if diff := snapshotDiff(prev, snap); diff != "" {
+			return &Violation{Invariant: Snapshot, Detail: fmt.Sprintf("epoch %d snapshot changed: %s", snap.Epoch, diff)}
+		}
+	}
+	return nil
+}
+
+func snapshotDiff(a, b core.EpochSnapshot) string {
+	var diffs []string
+	if a.TotalStake != b.TotalStake {
+		diffs = append(diffs, fmt.Sprintf("total stake %d -> %d", a.TotalStake, b.TotalStake))
+	}
+	names := make(map[string]bool)
+	for name := range a.Validators {
+		names[name] = true
+	}
+	for name := range b.Validators {
+		names[name] = true
+	}
+	for _, name := range sortedKeys(names) {
+		before, hadBefore := a.Validators[name]
+		after, hasAfter := b.Validators[name]
+		if before != after || hadBefore != hasAfter {
+			diffs = append(diffs, fmt.Sprintf("%s %d -> %d", name, before, after))
+		}
+	}
+	return strings.Join(diffs, ", ")
+}
+
+// checkFinalized makes sure every finalized block still held by the chain
+// is canonical; blocks pruned below the anchor are skipped.
+func (c *Checker) checkFinalized() *Violation {
+	chain := c.chain.CanonicalChain()
+	if len(chain) == 0 {
+		return nil
+	}
+	canonical := make(map[string]bool, len(chain))
+	for _, b := range chain {
+		canonical[b.Hash] = true
+	}
+	for hash, slot := range c.finalized {
+		if slot >= chain[0].Slot && !canonical[hash] {
+			return &Violation{Invariant: Finality, Detail: fmt.Sprintf("finalized block %s at slot %d is not canonical (tip %s)", short(hash), slot, short(chain[len(chain)-1].Hash))}
+		}
+	}
+	if got := c.chain.FinalizedSlot(); got < c.finalizedSlot {
+		return &Violation{Invariant: Finality, Detail: fmt.Sprintf("finalized slot moved back from %d to %d", c.finalizedSlot, got)}
+	}
+	return nil
+}
+
+// checkReplay replays the canonical chain from its base state, compares
+// the result with the chain's state and then checks supply.
+func (c *Checker) checkReplay() *Violation {
+	chain := c.chain.CanonicalChain()
+	if len(chain) == 0 {
+		return nil
+	}
+	base, err := c.chain.StateAt(chain[0].Hash)
+	if err != nil {
+		return &Violation{Invariant: Replay, Detail: fmt.Sprintf("base state at %s: %v", short(chain[0].Hash), err)}
+	}
+	validators := c.chain.Validators()
+	state, burned := base, 0
+	for _, b := range chain[1:] {
+		producer := rewardAddress(validators, b.Validator)
+		next, err := consensus.ApplyTransactions(state, b.Transactions, producer)
+		if err != nil {
+			return &Violation{Invariant: Replay, Detail: fmt.Sprintf("block %s at height %d does not replay: %v", short(b.Hash), b.Index, err)}
+		}
+		if producer == "" {
+			for _, tx := range b.Transactions {
+				burned += tx.Fee
+			}
+		}
+		state = next
+	}
+	accounts := c.chain.Accounts()
+	if diffs := stateDiff(accounts, state); len(diffs) > 0 {
+		tip := chain[len(chain)-1]
+		return &Violation{Invariant: Replay, Detail: fmt.Sprintf("state differs from replay of %d blocks to %s at height %d: %s",
+			len(chain)-1, short(tip.Hash), tip.Index, strings.Join(diffs, "; "))}
+	}
+
+	balances, baseBalances := totalBalance(accounts), totalBalance(base)
+	if want := baseBalances - burned; balances != want {
+		return &Violation{Invariant: Supply, Detail: fmt.Sprintf("balances total %d, want %d (base %d, burned fees %d)", balances, want, baseBalances, burned)}
+	}
+	stake := 0
+	for _, v := range validators {
+		stake += v.Stake
+	}
+	if want := c.stakeBase + c.rewards - c.slashed; stake != want {
+		return &Violation{Invariant: Supply, Detail: fmt.Sprintf("stake total %d, want %d (base %d, rewards %d, slashed %d)", stake, want, c.stakeBase, c.rewards, c.slashed)}
+	}
+	return nil
+}
+
+func rewardAddress(validators map[string]domain.Validator, name string) string {
+	v, ok := validators[name]
+	if !ok || v.PubKey == "" {
+		return ""
+	}
+	addr, err := domain.AddressFromPubKey(v.PubKey)
+	if err != nil {
+		return ""
+	}
+	return addr
+}
+
+// stateDiff lists accounts that differ; a missing account equals a zero one.
+func stateDiff(got, want map[string]domain.Account) []string {
+	addrs := make(map[string]bool, len(got))
+	for a := range got {
+		addrs[a] = true
+	}
+	for a := range want {
+		addrs[a] = true
+	}
+	var diffs []string
+	for _, a := range sortedKeys(addrs) {
+		if got[a] == want[a] {
+			continue
+		}
+		if len(diffs) == maxDiffs {
+			diffs = append(diffs, "...")
+			break
+		}
+		diffs = append(diffs, fmt.Sprintf("%s balance %d nonce %d, replay balance %d nonce %d",
+			short(a), got[a].Balance, got[a].Nonce, want[a].Balance, want[a].Nonce))
+	}
+	return diffs
+}
+
+func totalBalance(state map[string]domain.Account) int {
+	total := 0
+	for _, a := range state {
+		total += a.Balance
+	}
+	return total
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func short(hash string) string {
+	if len(hash) <= 12 {
+		return hash
+	}
+	return hash[:12]
+}
@@ -0,0 +1,122 @@
+package invariant
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+)
+
+func newChain(t *testing.T) (*core.Blockchain, *domain.Wallet) {
+	t.Helper()
+	bc := core.NewBlockchain(core.ChainConfig{
+		MaxReorgDepth:        2,
+		FinalitySlots:        2,
+		MinReorgWeightDeltaP: 10,
+		DeterministicPoH:     true,
+		PoHSeed:              1,
+	}, nil, nil)
+	v, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	if err := bc.AddValidator("Alice", 100, v.PublicKey, v.PrivateKey); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	user, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	bc.SetBalance(user.Address, 1000)
+	return bc, user
+}
+
+func expectViolation(t *testing.T, err error, invariant, detail string) {
+	t.Helper()
+	var v *Violation
+	if !errors.As(err, &v) || v.Invariant != invariant || !strings.Contains(v.Detail, detail) {
+		t.Fatalf("expected %s violation mentioning %q, got %v", invariant, detail, err)
+	}
+}
+
+func TestCheckerHoldsOnHonestChain(t *testing.T) {
+	bc, user := newChain(t)
+	c := Attach(bc)
+	defer c.Detach()
+	for i := 1; i <= 6; i++ {
+		tx := domain.Transaction{To: "sink", Amount: 10, Fee: 2, Nonce: uint64(i)}
+		if err := consensus.SignTransaction(user.PrivateKey, &tx); err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		if err := bc.AddTx(tx); err != nil {
+			t.Fatalf("add tx: %v", err)
+		}
+		if err := bc.AddBlock(bc.SelectTxsForBlock(10, "")); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+		if err := c.Check(); err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+	}
+	tip := bc.Tip()
+	parent, _ := bc.GetBlockByHeight(tip.Index - 1)
+	if _, err := bc.AddBlockExternal(parent.Hash, nil); err != nil {
+		t.Fatalf("fork block: %v", err)
+	}
+	if err := c.Check(); err != nil {
+		t.Fatalf("after fork: %v", err)
+	}
+	if len(c.finalized) == 0 {
+		t.Fatalf("no finalized blocks recorded")
+	}
+}
+
+func TestCheckerReportsViolations(t *testing.T) {
+	bc, user := newChain(t)
+	c := Attach(bc)
+	defer c.Detach()
+	for i := 0; i < 3; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	if err := c.Check(); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	// Past genesis SetBalance only touches state, which replay must catch.
+	bc.SetBalance(user.Address, 5000)
+	expectViolation(t, c.Check(), Replay, "replay balance 1000")
+	if err := c.Check(); err == nil {
+		t.Fatalf("violation not kept")
+	}
+
+	bc, _ = newChain(t)
+	c = Attach(bc)
+	defer c.Detach()
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	if err := c.Check(); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	for epoch, snap := range c.snapshots {
+		snap.Validators = map[string]uint64{"Alice": 1}
+		c.snapshots[epoch] = snap
+	}
+	expectViolation(t, c.Check(), Snapshot, "Alice 1 -> 100")
+
+	c = Attach(bc)
+	defer c.Detach()
+	c.onEvent(core.SlotFinalized{Slot: 9, Hash: "finalized-block"})
+	c.onEvent(core.CanonicalTipChanged{OldTip: "a", NewTip: "b", Depth: 1, Reverted: []domain.Block{{Hash: "finalized-block", Index: 4}}})
+	expectViolation(t, c.Check(), Finality, "finalized at slot 9")
+
+	c = Attach(bc)
+	defer c.Detach()
+	c.onEvent(core.ValidatorSlashed{Validator: "Alice", Amount: 5, Stake: 95})
+	expectViolation(t, c.Check(), Supply, "slashed 5")
+}
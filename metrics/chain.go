@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"strconv"
+
+	"xenium/core"
+)
+
+// Reorg severities, matching the counters in core.ReorgMetrics.
+const (
+	SeverityInfo     = "info"
+	SeverityWarn     = "warn"
+	SeverityError    = "error"
+	SeverityCritical = "critical"
+)
+
+// ChainMetrics exports a Blockchain. Event-driven series (reorgs, import
+// latency, missed slots) are updated from the chain's event bus; state
+// gauges are read from the chain on every scrape.
+type ChainMetrics struct {
+	chain *core.Blockchain
+	sub   int
+
+	height        *Gauge
+	finalizedSlot *Gauge
+	tipWeight     *Gauge
+	mempoolSize   *Gauge
+	jailed        *Gauge
+	activeStake   *Gauge
+	epochStake    *Gauge
+
+	blocksAccepted *Counter
+	reorgs         *Counter
+	reorgDepth     *Histogram
+	importSeconds  *Histogram
+	pohSeconds     *Histogram
+	missedSlots    *Counter
+	slashes        *Counter
+	txDropped      *Counter
+}
+
+func NewChainMetrics(reg *Registry, chain *core.Blockchain) *ChainMetrics {
+	m := &ChainMetrics{
+		chain:          chain,
+		height:         reg.NewGauge("xenium_chain_height", "Height of the canonical tip."),
+		finalizedSlot:  reg.NewGauge("xenium_chain_finalized_slot", "Highest finalized slot."),
+		tipWeight:      reg.NewGauge("xenium_chain_tip_weight", "Cumulative weight of the canonical tip."),
+		mempoolSize:    reg.NewGauge("xenium_mempool_size", "Transactions waiting in the mempool."),
+		jailed:         reg.NewGauge("xenium_validators_jailed", "Validators jailed at the tip slot."),
+		activeStake:    reg.NewGauge("xenium_active_stake", "Total stake in the epoch snapshot of the tip slot."),
+		epochStake:     reg.NewGauge("xenium_epoch_total_stake", "Total stake per epoch snapshot.", "epoch"),
+		blocksAccepted: reg.NewCounter("xenium_blocks_accepted_total", "Blocks that passed on-accept checks."),
+		reorgs:         reg.NewCounter("xenium_reorgs_total", "Reorgs by severity; error and critical ones were rejected.", "severity"),
+		reorgDepth:     reg.NewHistogram("xenium_reorg_depth", "Depth of applied reorgs in blocks.", []float64{1, 2, 3, 4, 6, 8, 16}),
+		importSeconds:  reg.NewHistogram("xenium_block_import_seconds", "Time to validate and store a block.", DefBuckets),
+		pohSeconds:     reg.NewHistogram("xenium_poh_verify_seconds", "Time to verify PoH of an imported block.", DefBuckets),
+		missedSlots:    reg.NewCounter("xenium_validator_missed_slots_total", "Slots a validator led without a canonical block.", "validator"),
+		slashes:        reg.NewCounter("xenium_validator_slashes_total", "Slashing events by reason.", "validator", "reason"),
+		txDropped:      reg.NewCounter("xenium_tx_dropped_total", "Transactions dropped without inclusion, by reason.", "reason"),
+	}
+	for _, sev := range []string{SeverityInfo, SeverityWarn, SeverityError, SeverityCritical} {
+		m.reorgs.Add(0, sev)
+	}
+	m.sub = chain.Subscribe(m.onEvent)
+	reg.OnScrape(m.refresh)
+	return m
+}
+
+// Close stops following chain events.
+func (m *ChainMetrics) Close() {
+	m.chain.Unsubscribe(m.sub)
+}
+
+func (m *ChainMetrics) onEvent(e core.Event) {
+	switch ev := e.(type) {
+	case core.BlockAccepted:
+		m.blocksAccepted.Inc()
+		m.importSeconds.Observe(ev.ImportTime.Seconds())
+		if ev.PoHTime > 0 {
+			m.pohSeconds.Observe(ev.PoHTime.Seconds())
+		}
+	case core.CanonicalTipChanged:
+		switch {
+		case ev.Depth > 1:
+			m.reorgs.Inc(SeverityWarn)
+		case ev.Depth == 1:
+			m.reorgs.Inc(SeverityInfo)
+		}
+		if ev.Depth > 0 {
+			m.reorgDepth.Observe(float64(ev.Depth))
+		}
+	case core.ReorgRejected:
+		if ev.Reason == core.RejectFinalized {
+			m.reorgs.Inc(SeverityCritical)
+		} else {
+			m.reorgs.Inc(SeverityError)
+		}
+	case core.SlotMissed:
+		m.missedSlots.Inc(ev.Leader)
+	case core.ValidatorSlashed:
+		m.slashes.Inc(ev.Validator, ev.Reason)
+	case core.TxDropped:
+		m.txDropped.Inc(ev.Reason)
+	}
+}
+
+func (m *ChainMetrics) refresh() {
+	tip := m.chain.Tip()
+	m.height.Set(float64(tip.Index))
+	m.finalizedSlot.Set(float64(m.chain.FinalizedSlot()))
+	m.tipWeight.Set(float64(m.chain.ScoreTip(tip.Hash).CumulativeWeight))
+	m.mempoolSize.Set(float64(m.chain.MempoolLen()))
+	m.jailed.Set(float64(len(m.chain.JailedValidators())))
+	var active uint64
+	if snap, ok := m.chain.PeekEpochSnapshot(tip.Slot); ok {
+		active = snap.TotalStake
+	}
+	m.activeStake.Set(float64(active))
+	m.epochStake.Reset()
+	for _, snap := range m.chain.GetAllEpochSnapshots() {
+		m.epochStake.Set(float64(snap.TotalStake), strconv.FormatUint(snap.Epoch, 10))
+	}
+}
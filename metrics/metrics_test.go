@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"xenium/core"
+	"xenium/domain"
+)
+
+func TestTextExposition(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounter("test_events_total", "Events seen.", "kind")
+	g := reg.NewGauge("test_temperature", "Line one\nline two.")
+	h := reg.NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1})
+
+	c.Inc("a")
+	c.Add(2, `quote"d`)
+	g.Set(-1.5)
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	var b strings.Builder
+	if err := reg.WriteText(&b); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	want := `# HELP test_events_total Events seen.
+# TYPE test_events_total counter
+test_events_total{kind="a"} 1
+test_events_total{kind="quote\"d"} 2
+# HELP test_temperature Line one\nline two.
+# TYPE test_temperature gauge
+test_temperature -1.5
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.1"} 1
+test_latency_seconds_bucket{le="1"} 2
+test_latency_seconds_bucket{le="+Inf"} 3
+test_latency_seconds_sum 3.55
+test_latency_seconds_count 3
+`
+	if b.String() != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestChainMetricsEndpoint(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	bc := core.NewBlockchain(core.ChainConfig{EpochLength: 10, DeterministicPoH: true, PoHSeed: 1}, nil, nil)
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("validator: %v", err)
+	}
+	reg := NewRegistry()
+	m := NewChainMetrics(reg, bc)
+	defer m.Close()
+
+	genesis := bc.CanonicalTipHash()
+	for i := 0; i < 3; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	// A fork from genesis crosses the finalized slot and is rejected.
+	parent := genesis
+	for i := 0; i < 4; i++ {
+		if parent, err = bc.AddBlockExternal(parent, nil); err != nil {
+			t.Fatalf("fork block: %v", err)
+		}
+	}
+
+	srv := httptest.NewServer(reg)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	text := string(body)
+	for _, line := range []string{
+		"xenium_chain_height 3",
+		"xenium_chain_finalized_slot 1",
+		"xenium_blocks_accepted_total 7",
+		`xenium_reorgs_total{severity="info"} 0`,
+		"xenium_block_import_seconds_count 7",
+		"xenium_active_stake 100",
+		`xenium_epoch_total_stake{epoch="0"} 100`,
+		"xenium_validators_jailed 0",
+		"xenium_mempool_size 0",
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, text)
+		}
+	}
+	stats := bc.GetReorgStats()
+	if v, _ := reg.Value("xenium_reorgs_total", SeverityCritical); stats.Critical == 0 || v != float64(stats.Critical) {
+		t.Fatalf("critical reorgs %v, chain counted %d", v, stats.Critical)
+	}
+	if v, ok := reg.Value("xenium_chain_tip_weight"); !ok || v == 0 {
+		t.Fatalf("tip weight not exported: %v %v", v, ok)
+	}
+}
@@ -1,8 +1,40 @@
 package ports
 
+// Logger is the logging port. The f-methods take a printf-style message;
+// With returns a logger that attaches key-value fields to every entry, e.g.
+// logger.With("depth", 2, "slot", 40).Warnf("Reorg detected").
 type Logger interface {
+	Debugf(format string, args ...any)
 	Infof(format string, args ...any)
 	Warnf(format string, args ...any)
 	Errorf(format string, args ...any)
 	Criticalf(format string, args ...any)
+	With(kv ...any) Logger
+}
+
+// ModuleLogger is implemented by loggers that tag entries with a module,
+// replacing the module of the logger it was derived from, and may filter
+// levels per module.
+type ModuleLogger interface {
+	Module(name string) Logger
+}
+
+// Log modules used across the node.
+const (
+	ModuleCore      = "core"
+	ModuleConsensus = "consensus"
+	ModuleStorage   = "storage"
+	ModuleNetwork   = "network"
+	ModuleSync      = "sync"
+	ModuleRPC       = "rpc"
+)
+
+// ForModule returns the logger for a module. Loggers that do not implement
+// ModuleLogger get a module field appended with With, so they should not be
+// nested.
+func ForModule(l Logger, name string) Logger {
+	if m, ok := l.(ModuleLogger); ok {
+		return m.Module(name)
+	}
+	return l.With("module", name)
 }
@@ -1,7 +1,35 @@
 package ports
 
-import "xenium/domain"
+import (
+	"errors"
 
+	"xenium/domain"
+)
+
+// Handlers wrap rejections in these errors so the transport can score the
+// sending peer. Other errors, such as a block with an unknown parent, are
+// not held against the peer. ErrKnown marks gossip the node already holds,
+// which the transport may then stop asking about.
+var (
+	ErrInvalidBlock = errors.New("invalid block")
+	ErrInvalidTx    = errors.New("invalid transaction")
+	ErrBadSignature = errors.New("bad signature")
+	ErrKnown        = errors.New("already known")
+)
+
+// Network gossips blocks and transactions. Transactions travel by hash:
+// BroadcastTx announces a tx accepted locally and peers fetch bodies they
+// have not seen; AnnounceTxs announces txs a reorg returned to the mempool
+// to peers not yet known to have them.
 type Network interface {
 	BroadcastBlock(block domain.Block) error
+	BroadcastTx(tx domain.Transaction) error
+	AnnounceTxs(txs []domain.Transaction) error
+}
+
+// NetworkHandler receives gossip delivered by a Network. Returning an error
+// marks the message invalid and stops it from being relayed further.
+type NetworkHandler interface {
+	HandleBlock(peer string, block domain.Block) error
+	HandleTx(peer string, tx domain.Transaction) error
 }
@@ -10,8 +10,20 @@ type BlockStore interface {
 	GetRange(startHeight uint64, endHeight uint64) ([]domain.Block, error)
 }
 
+type PrunableBlockStore interface {
+	BlockStore
+	Compact(anchor domain.PruneAnchor) error
+	LoadPruneAnchor() (domain.PruneAnchor, bool, error)
+}
+
 type SnapshotStore interface {
 	SaveEpochSnapshot(epoch uint64, stateRoot string, validatorSet map[string]uint64) error
 	LoadLatestSnapshot() (epoch uint64, stateRoot string, validatorSet map[string]uint64, ok bool, err error)
 	LoadSnapshotByEpoch(epoch uint64) (stateRoot string, validatorSet map[string]uint64, ok bool, err error)
 }
+
+// Flusher is implemented by stores that buffer or cache writes and can
+// force them to durable storage.
+type Flusher interface {
+	Flush() error
+}
@@ -0,0 +1,31 @@
+package ports
+
+import (
+	"context"
+
+	"xenium/domain"
+)
+
+type ChainStatus struct {
+	GenesisHash string
+	TipHash     string
+	TipHeight   uint64
+	TipSlot     uint64
+	Weight      uint64
+}
+
+// SyncProvider serves chain data to syncing peers.
+type SyncProvider interface {
+	ChainStatus() ChainStatus
+	HeadersFrom(height uint64, count int) ([]domain.BlockHeader, error)
+	Bodies(hashes []string) ([][]domain.Transaction, error)
+}
+
+// SyncNetwork is the request/response side of a Network used by chain sync.
+type SyncNetwork interface {
+	SyncPeers() []string
+	RequestStatus(ctx context.Context, peer string) (ChainStatus, error)
+	RequestHeaders(ctx context.Context, peer string, from uint64, count int) ([]domain.BlockHeader, error)
+	RequestBodies(ctx context.Context, peer string, hashes []string) ([][]domain.Transaction, error)
+	DisconnectPeer(peer string, reason string)
+}
@@ -0,0 +1,232 @@
+package rpc
+
+import (
+	"encoding/json"
+
+	"xenium/consensus"
+	"xenium/domain"
+)
+
+type TipResult struct {
+	Hash             string `json:"hash"`
+	Height           uint64 `json:"height"`
+	Slot             uint64 `json:"slot"`
+	Tick             uint64 `json:"tick"`
+	CumulativeWeight uint64 `json:"cumulative_weight"`
+	FinalizedSlot    uint64 `json:"finalized_slot"`
+}
+
+type AccountResult struct {
+	Address string `json:"address"`
+	Balance int    `json:"balance"`
+	Nonce   uint64 `json:"nonce"`
+}
+
+type SendTxResult struct {
+	Hash string `json:"hash"`
+}
+
+type LeaderSlot struct {
+	Slot   uint64 `json:"slot"`
+	Leader string `json:"leader"`
+}
+
+type LeaderSchedule struct {
+	Epoch uint64       `json:"epoch"`
+	Slots []LeaderSlot `json:"slots"`
+}
+
+func (s *Server) chainMethods() map[string]method {
+	methods := map[string]method{
+		"getBlockByHash":        s.getBlockByHash,
+		"getBlockByHeight":      s.getBlockByHeight,
+		"getTip":                s.getTip,
+		"getAccount":            s.getAccount,
+		"sendTransaction":       s.sendTransaction,
+		"getEpochSnapshot":      s.getEpochSnapshot,
+		"getForkCandidates":     s.getForkCandidates,
+		"getValidatorSummaries": s.getValidatorSummaries,
+		"getReorgStats":         s.getReorgStats,
+		"getLeaderSchedule":     s.getLeaderSchedule,
+		"getBlockTree":          s.getBlockTree,
+	}
+	if s.cfg.Index != nil {
+		methods["getTransaction"] = s.getTransaction
+		methods["getAccountHistory"] = s.getAccountHistory
+	}
+	return methods
+}
+
+func (s *Server) getBlockByHash(params json.RawMessage) (any, error) {
+	var p struct {
+		Hash string `json:"hash"`
+	}
+	if err := decodeParams(params, &p, "hash"); err != nil {
+		return nil, err
+	}
+	if p.Hash == "" {
+		return nil, newError(CodeInvalidParams, "hash is required")
+	}
+	return s.cfg.Chain.GetBlockByHash(p.Hash)
+}
+
+func (s *Server) getBlockByHeight(params json.RawMessage) (any, error) {
+	var p struct {
+		Height *uint64 `json:"height"`
+	}
+	if err := decodeParams(params, &p, "height"); err != nil {
+		return nil, err
+	}
+	if p.Height == nil {
+		return nil, newError(CodeInvalidParams, "height is required")
+	}
+	return s.cfg.Chain.GetBlockByHeight(*p.Height)
+}
+
+func (s *Server) getTip(params json.RawMessage) (any, error) {
+	chain := s.cfg.Chain
+	tip := chain.Tip()
+	return TipResult{
+		Hash:             tip.Hash,
+		Height:           tip.Index,
+		Slot:             tip.Slot,
+		Tick:             tip.Tick,
+		CumulativeWeight: chain.ScoreTip(tip.Hash).CumulativeWeight,
+		FinalizedSlot:    chain.FinalizedSlot(),
+	}, nil
+}
+
+func (s *Server) getAccount(params json.RawMessage) (any, error) {
+	var p struct {
+		Address string `json:"address"`
+	}
+	if err := decodeParams(params, &p, "address"); err != nil {
+		return nil, err
+	}
+	if p.Address == "" {
+		return nil, newError(CodeInvalidParams, "address is required")
+	}
+	acct := s.cfg.Chain.Account(p.Address)
+	return AccountResult{Address: p.Address, Balance: acct.Balance, Nonce: acct.Nonce}, nil
+}
+
+func (s *Server) sendTransaction(params json.RawMessage) (any, error) {
+	var p struct {
+		Tx *domain.Transaction `json:"tx"`
+	}
+	if err := decodeParams(params, &p, "tx"); err != nil {
+		return nil, err
+	}
+	if p.Tx == nil {
+		return nil, newError(CodeInvalidParams, "tx is required")
+	}
+	if err := s.cfg.SubmitTx(*p.Tx); err != nil {
+		return nil, newError(CodeTxRejected, "%v", err)
+	}
+	return SendTxResult{Hash: p.Tx.Hash}, nil
+}
+
+// getEpochSnapshot returns the snapshot covering slot, the tip slot by
+// default. Snapshots that have not been taken yet are not created here,
+// since taking one early would freeze the stake set too soon.
+func (s *Server) getEpochSnapshot(params json.RawMessage) (any, error) {
+	var p struct {
+		Slot *uint64 `json:"slot"`
+	}
+	if err := decodeParams(params, &p, "slot"); err != nil {
+		return nil, err
+	}
+	slot := s.cfg.Chain.Tip().Slot
+	if p.Slot != nil {
+		slot = *p.Slot
+	}
+	snap, ok := s.cfg.Chain.PeekEpochSnapshot(slot)
+	if !ok {
+		return nil, newError(CodeSnapshotUnavailable, "no epoch snapshot for slot %d yet", slot)
+	}
+	return snap, nil
+}
+
+func (s *Server) getForkCandidates(params json.RawMessage) (any, error) {
+	return nonNil(s.cfg.Chain.GetForkCandidates()), nil
+}
+
+func (s *Server) getValidatorSummaries(params json.RawMessage) (any, error) {
+	return nonNil(s.cfg.Chain.GetValidatorSummaries()), nil
+}
+
+func (s *Server) getReorgStats(params json.RawMessage) (any, error) {
+	return s.cfg.Chain.GetReorgStats(), nil
+}
+
+func (s *Server) getBlockTree(params json.RawMessage) (any, error) {
+	return s.cfg.Chain.ExportBlockTree(), nil
+}
+
+// getLeaderSchedule lists the leader of every slot in an epoch, the tip's
+// epoch by default.
+func (s *Server) getLeaderSchedule(params json.RawMessage) (any, error) {
+	var p struct {
+		Epoch *uint64 `json:"epoch"`
+	}
+	if err := decodeParams(params, &p, "epoch"); err != nil {
+		return nil, err
+	}
+	length := s.cfg.Chain.Config().EpochLength
+	if length == 0 {
+		length = consensus.SlotsPerEpoch
+	}
+	epoch := s.cfg.Chain.Tip().Slot / length
+	if p.Epoch != nil {
+		epoch = *p.Epoch
+	}
+	first := epoch * length
+	snap, ok := s.cfg.Chain.PeekEpochSnapshot(first)
+	if !ok {
+		return nil, newError(CodeSnapshotUnavailable, "no epoch snapshot for epoch %d yet", epoch)
+	}
+	out := LeaderSchedule{Epoch: epoch, Slots: make([]LeaderSlot, 0, length)}
+	for slot := first; slot < first+length; slot++ {
+		out.Slots = append(out.Slots, LeaderSlot{Slot: slot, Leader: consensus.LeaderFromSnapshot(slot, snap.Validators)})
+	}
+	return out, nil
+}
+
+func (s *Server) getTransaction(params json.RawMessage) (any, error) {
+	var p struct {
+		Hash string `json:"hash"`
+	}
+	if err := decodeParams(params, &p, "hash"); err != nil {
+		return nil, err
+	}
+	if p.Hash == "" {
+		return nil, newError(CodeInvalidParams, "hash is required")
+	}
+	return s.cfg.Index.GetTransaction(p.Hash)
+}
+
+// getAccountHistory returns a page of an address's transactions, newest
+// first. Pages are numbered from zero.
+func (s *Server) getAccountHistory(params json.RawMessage) (any, error) {
+	var p struct {
+		Address string `json:"address"`
+		Page    int    `json:"page"`
+	}
+	if err := decodeParams(params, &p, "address", "page"); err != nil {
+		return nil, err
+	}
+	if p.Address == "" {
+		return nil, newError(CodeInvalidParams, "address is required")
+	}
+	if p.Page < 0 {
+		return nil, newError(CodeInvalidParams, "page must not be negative")
+	}
+	return s.cfg.Index.GetAccountHistory(p.Address, p.Page), nil
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,261 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"xenium/core"
+	"xenium/domain"
+	"xenium/indexer"
+	"xenium/ports"
+)
+
+const defaultMaxBodyBytes = 1 << 20
+
+type Config struct {
+	Chain *core.Blockchain
+	// SubmitTx accepts a transaction from sendTransaction. Nodes pass one
+	// that also gossips it; by default it only goes into the mempool.
+	SubmitTx func(tx domain.Transaction) error
+	// Index enables getTransaction and getAccountHistory.
+	Index        *indexer.Indexer
+	Logger       ports.Logger
+	MaxBodyBytes int64
+	// SendQueue bounds the messages queued per WebSocket client before it is
+	// disconnected as a slow consumer.
+	SendQueue int
+}
+
+// Server is a JSON-RPC 2.0 server over HTTP POST, with chain event
+// subscriptions over WebSocket on the same address. It implements
+// http.Handler, so it can be mounted in any mux or served with Start.
+type Server struct {
+	cfg     Config
+	methods map[string]method
+	hub     *hub
+	sub     int
+
+	mu       sync.Mutex
+	http     *http.Server
+	listener net.Listener
+	done     chan struct{}
+}
+
+type method func(params json.RawMessage) (any, error)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func New(cfg Config) *Server {
+	if cfg.SubmitTx == nil {
+		cfg.SubmitTx = cfg.Chain.AddTx
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = cfg.Chain.Logger()
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	s := &Server{cfg: cfg, hub: newHub(cfg.Logger, cfg.SendQueue)}
+	s.methods = s.chainMethods()
+	s.sub = cfg.Chain.Subscribe(s.hub.onEvent)
+	return s
+}
+
+// Start listens on addr and serves until Close.
+func (s *Server) Start(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	s.mu.Lock()
+	s.http = srv
+	s.listener = ln
+	s.done = make(chan struct{})
+	s.mu.Unlock()
+	go func() {
+		defer close(s.done)
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.cfg.Logger.Errorf("RPC server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Close disconnects WebSocket subscribers, stops accepting requests and
+// waits for in-flight ones until ctx is done.
+func (s *Server) Close(ctx context.Context) error {
+	s.cfg.Chain.Unsubscribe(s.sub)
+	s.hub.closeAll()
+	s.mu.Lock()
+	srv, done := s.http, s.done
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	err := srv.Shutdown(ctx)
+	<-done
+	return err
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isWebSocketUpgrade(r) {
+		s.serveWebSocket(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "JSON-RPC requests must use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+	if err != nil {
+		writeJSON(w, response{JSONRPC: "2.0", Error: newError(CodeInvalidRequest, "request body: %v", err), ID: json.RawMessage("null")})
+		return
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			writeJSON(w, response{JSONRPC: "2.0", Error: newError(CodeParseError, "%v", err), ID: json.RawMessage("null")})
+			return
+		}
+		if len(batch) == 0 {
+			writeJSON(w, response{JSONRPC: "2.0", Error: newError(CodeInvalidRequest, "empty batch"), ID: json.RawMessage("null")})
+			return
+		}
+		out := make([]response, 0, len(batch))
+		for _, raw := range batch {
+			if resp, ok := s.handle(raw); ok {
+				out = append(out, resp)
+			}
+		}
+		if len(out) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, out)
+		return
+	}
+	resp, ok := s.handle(body)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// handle runs one request. It reports false for notifications, which get
+// no response.
+func (s *Server) handle(raw json.RawMessage) (response, bool) {
+	resp := response{JSONRPC: "2.0", ID: json.RawMessage("null")}
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			resp.Error = newError(CodeParseError, "%v", err)
+		} else {
+			resp.Error = newError(CodeInvalidRequest, "%v", err)
+		}
+		return resp, true
+	}
+	if req.ID != nil {
+		resp.ID = req.ID
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = newError(CodeInvalidRequest, "expected jsonrpc 2.0 request with a method")
+		return resp, true
+	}
+	fn, ok := s.methods[req.Method]
+	if !ok {
+		resp.Error = newError(CodeMethodNotFound, "method %q not found", req.Method)
+		return resp, req.ID != nil
+	}
+	result, err := fn(req.Params)
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		resp.Error = toError(err)
+		s.cfg.Logger.Warnf("RPC %s failed: %v", req.Method, err)
+	}
+	return resp, req.ID != nil
+}
+
+func toError(err error) *Error {
+	var rpcErr *Error
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case errors.Is(err, core.ErrBlockNotFound), errors.Is(err, indexer.ErrTxNotFound):
+		return newError(CodeNotFound, "%v", err)
+	case errors.Is(err, core.ErrPruned):
+		return newError(CodePruned, "%v", err)
+	}
+	return newError(CodeInternalError, "%v", err)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// decodeParams fills dst from named (object) or positional (array) params.
+// Positional params are matched to names in order.
+func decodeParams(raw json.RawMessage, dst any, names ...string) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	if raw[0] == '[' {
+		var positional []json.RawMessage
+		if err := json.Unmarshal(raw, &positional); err != nil {
+			return newError(CodeInvalidParams, "%v", err)
+		}
+		if len(positional) > len(names) {
+			return newError(CodeInvalidParams, "expected at most %d params, got %d", len(names), len(positional))
+		}
+		named := make(map[string]json.RawMessage, len(positional))
+		for i, p := range positional {
+			named[names[i]] = p
+		}
+		var err error
+		if raw, err = json.Marshal(named); err != nil {
+			return newError(CodeInvalidParams, "%v", err)
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return newError(CodeInvalidParams, "%v", err)
+	}
+	return nil
+}
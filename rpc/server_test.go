@@ -0,0 +1,276 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+	"xenium/indexer"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *core.Blockchain, *domain.Wallet) {
+	t.Helper()
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	bc := core.NewBlockchain(core.ChainConfig{EpochLength: 10, DeterministicPoH: true, PoHSeed: 1}, nil, nil)
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("validator: %v", err)
+	}
+	bc.SetBalance(w.Address, 1000)
+	for i := 0; i < 3; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	srv := httptest.NewServer(New(Config{Chain: bc}))
+	t.Cleanup(srv.Close)
+	return srv, bc, w
+}
+
+type testResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func post(t *testing.T, srv *httptest.Server, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func call(t *testing.T, srv *httptest.Server, method string, params any, result any) *Error {
+	t.Helper()
+	req := map[string]any{"jsonrpc": "2.0", "method": method, "id": 1}
+	if params != nil {
+		req["params"] = params
+	}
+	body, _ := json.Marshal(req)
+	var resp testResponse
+	if err := json.NewDecoder(post(t, srv, string(body)).Body).Decode(&resp); err != nil {
+		t.Fatalf("%s: decode response: %v", method, err)
+	}
+	if resp.JSONRPC != "2.0" || string(resp.ID) != "1" {
+		t.Fatalf("%s: bad envelope %+v", method, resp)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			t.Fatalf("%s: decode result: %v", method, err)
+		}
+	}
+	return nil
+}
+
+func TestChainQueries(t *testing.T) {
+	srv, bc, w := newTestServer(t)
+
+	var tip TipResult
+	if err := call(t, srv, "getTip", nil, &tip); err != nil {
+		t.Fatalf("getTip: %v", err)
+	}
+	if tip.Hash != bc.CanonicalTipHash() || tip.Height != 3 || tip.CumulativeWeight == 0 {
+		t.Fatalf("unexpected tip %+v", tip)
+	}
+
+	var byHeight, byHash domain.Block
+	if err := call(t, srv, "getBlockByHeight", []any{2}, &byHeight); err != nil {
+		t.Fatalf("getBlockByHeight: %v", err)
+	}
+	if err := call(t, srv, "getBlockByHash", map[string]any{"hash": byHeight.Hash}, &byHash); err != nil {
+		t.Fatalf("getBlockByHash: %v", err)
+	}
+	if byHeight.Index != 2 || byHash.Hash != byHeight.Hash {
+		t.Fatalf("block lookups disagree: %d %s %s", byHeight.Index, byHeight.Hash, byHash.Hash)
+	}
+
+	var acct AccountResult
+	if err := call(t, srv, "getAccount", []any{w.Address}, &acct); err != nil {
+		t.Fatalf("getAccount: %v", err)
+	}
+	if acct.Balance != bc.Account(w.Address).Balance {
+		t.Fatalf("unexpected account %+v", acct)
+	}
+
+	var snap core.EpochSnapshot
+	if err := call(t, srv, "getEpochSnapshot", nil, &snap); err != nil {
+		t.Fatalf("getEpochSnapshot: %v", err)
+	}
+	if snap.Validators["Alice"] != 100 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+	var schedule LeaderSchedule
+	if err := call(t, srv, "getLeaderSchedule", map[string]any{"epoch": 0}, &schedule); err != nil {
+		t.Fatalf("getLeaderSchedule: %v", err)
+	}
+	if len(schedule.Slots) != 10 || schedule.Slots[3].Leader != "Alice" {
+		t.Fatalf("unexpected schedule %+v", schedule)
+	}
+	if err := call(t, srv, "getLeaderSchedule", []any{50}, nil); err == nil || err.Code != CodeSnapshotUnavailable {
+		t.Fatalf("expected snapshot unavailable, got %v", err)
+	}
+
+	var forks []core.ForkCandidate
+	var summaries []core.ValidatorSummary
+	var reorgs core.ReorgMetrics
+	if err := call(t, srv, "getForkCandidates", nil, &forks); err != nil || len(forks) != 1 {
+		t.Fatalf("getForkCandidates: %v %+v", err, forks)
+	}
+	if err := call(t, srv, "getValidatorSummaries", nil, &summaries); err != nil || len(summaries) != 1 || summaries[0].Produced != 3 {
+		t.Fatalf("getValidatorSummaries: %v %+v", err, summaries)
+	}
+	if err := call(t, srv, "getReorgStats", nil, &reorgs); err != nil {
+		t.Fatalf("getReorgStats: %v", err)
+	}
+	var tree core.BlockTree
+	if err := call(t, srv, "getBlockTree", nil, &tree); err != nil || len(tree.Nodes) != 4 || tree.CanonicalTip != tip.Hash {
+		t.Fatalf("getBlockTree: %v %+v", err, tree)
+	}
+}
+
+func TestSendTransaction(t *testing.T) {
+	srv, bc, w := newTestServer(t)
+	tx := domain.Transaction{To: "bob", Amount: 5, Fee: 1, Nonce: 1}
+	if err := consensus.SignTransaction(w.PrivateKey, &tx); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	var sent SendTxResult
+	if err := call(t, srv, "sendTransaction", []any{tx}, &sent); err != nil {
+		t.Fatalf("sendTransaction: %v", err)
+	}
+	if sent.Hash != tx.Hash || !bc.HasPendingTx(tx.Hash) {
+		t.Fatalf("transaction not in mempool")
+	}
+	if err := call(t, srv, "sendTransaction", []any{tx}, nil); err == nil || err.Code != CodeTxRejected {
+		t.Fatalf("expected duplicate to be rejected, got %v", err)
+	}
+	tx.Amount = 6
+	if err := call(t, srv, "sendTransaction", map[string]any{"tx": tx}, nil); err == nil || err.Code != CodeTxRejected {
+		t.Fatalf("expected tampered tx to be rejected, got %v", err)
+	}
+}
+
+func TestTransactionIndexMethods(t *testing.T) {
+	srv, bc, w := newTestServer(t)
+	if err := call(t, srv, "getTransaction", []any{"0x00"}, nil); err == nil || err.Code != CodeMethodNotFound {
+		t.Fatalf("expected getTransaction to need an index, got %v", err)
+	}
+	ix, err := indexer.Open(indexer.Config{Chain: bc})
+	if err != nil {
+		t.Fatalf("open index: %v", err)
+	}
+	defer ix.Close()
+	indexed := httptest.NewServer(New(Config{Chain: bc, Index: ix}))
+	defer indexed.Close()
+
+	tx := domain.Transaction{To: "bob", Amount: 5, Fee: 1, Nonce: 1}
+	if err := consensus.SignTransaction(w.PrivateKey, &tx); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := bc.AddTx(tx); err != nil {
+		t.Fatalf("add tx: %v", err)
+	}
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	var got indexer.TxResult
+	if err := call(t, indexed, "getTransaction", []any{tx.Hash}, &got); err != nil {
+		t.Fatalf("getTransaction: %v", err)
+	}
+	if got.Tx.Hash != tx.Hash || got.Height != 4 {
+		t.Fatalf("unexpected transaction %+v", got)
+	}
+	if err := call(t, indexed, "getTransaction", map[string]any{"hash": "missing"}, nil); err == nil || err.Code != CodeNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	var page indexer.HistoryPage
+	if err := call(t, indexed, "getAccountHistory", []any{"bob", 0}, &page); err != nil {
+		t.Fatalf("getAccountHistory: %v", err)
+	}
+	if page.Total != 1 || len(page.Txs) != 1 || page.Txs[0].BlockHash != bc.CanonicalTipHash() {
+		t.Fatalf("unexpected history %+v", page)
+	}
+	if err := call(t, indexed, "getAccountHistory", map[string]any{"address": "bob", "page": -1}, nil); err == nil || err.Code != CodeInvalidParams {
+		t.Fatalf("expected negative page to be rejected, got %v", err)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	cases := []struct {
+		method string
+		params any
+		code   int
+	}{
+		{"noSuchMethod", nil, CodeMethodNotFound},
+		{"getBlockByHash", []any{"missing"}, CodeNotFound},
+		{"getBlockByHash", nil, CodeInvalidParams},
+		{"getBlockByHeight", []any{"two"}, CodeInvalidParams},
+		{"getBlockByHeight", map[string]any{"heigth": 1}, CodeInvalidParams},
+		{"getAccount", []any{"a", "b"}, CodeInvalidParams},
+		{"getBlockByHeight", []any{99}, CodeNotFound},
+	}
+	for _, c := range cases {
+		err := call(t, srv, c.method, c.params, nil)
+		if err == nil || err.Code != c.code {
+			t.Fatalf("%s %v: expected code %d, got %v", c.method, c.params, c.code, err)
+		}
+	}
+
+	var resp testResponse
+	_ = json.NewDecoder(post(t, srv, `{"jsonrpc":"2.0","method":`).Body).Decode(&resp)
+	if resp.Error == nil || resp.Error.Code != CodeParseError || string(resp.ID) != "null" {
+		t.Fatalf("expected parse error, got %+v", resp)
+	}
+	resp = testResponse{}
+	_ = json.NewDecoder(post(t, srv, `{"jsonrpc":"1.0","method":"getTip","id":7}`).Body).Decode(&resp)
+	if resp.Error == nil || resp.Error.Code != CodeInvalidRequest || string(resp.ID) != "7" {
+		t.Fatalf("expected invalid request, got %+v", resp)
+	}
+	get, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	get.Body.Close()
+	if get.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for GET, got %d", get.StatusCode)
+	}
+}
+
+func TestBatchAndNotifications(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	body := `[
+		{"jsonrpc":"2.0","method":"getTip","id":"a"},
+		{"jsonrpc":"2.0","method":"getTip"},
+		{"jsonrpc":"2.0","method":"nope","id":"b"}
+	]`
+	var batch []testResponse
+	if err := json.NewDecoder(post(t, srv, body).Body).Decode(&batch); err != nil {
+		t.Fatalf("decode batch: %v", err)
+	}
+	if len(batch) != 2 || string(batch[0].ID) != `"a"` || batch[0].Error != nil || batch[1].Error.Code != CodeMethodNotFound {
+		t.Fatalf("unexpected batch response %+v", batch)
+	}
+
+	resp := post(t, srv, `{"jsonrpc":"2.0","method":"getTip"}`)
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(resp.Body)
+	if resp.StatusCode != http.StatusNoContent || buf.Len() != 0 {
+		t.Fatalf("notification should get no response, got %d %q", resp.StatusCode, buf.String())
+	}
+}
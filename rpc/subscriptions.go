@@ -0,0 +1,309 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"xenium/core"
+	"xenium/ports"
+)
+
+// Subscription topics accepted by subscribe.
+const (
+	TopicNewHeads            = "newHeads"
+	TopicReorg               = "reorg"
+	TopicFinalized           = "finalized"
+	TopicPendingTransactions = "pendingTransactions"
+	TopicEquivocation        = "equivocation"
+	TopicSlashing            = "slashing"
+)
+
+var topics = map[string]bool{
+	TopicNewHeads:            true,
+	TopicReorg:               true,
+	TopicFinalized:           true,
+	TopicPendingTransactions: true,
+	TopicEquivocation:        true,
+	TopicSlashing:            true,
+}
+
+// Each client gets a bounded queue. Publishing never blocks the chain: a
+// client whose queue is full is disconnected as a slow consumer.
+const defaultSendQueue = 256
+
+type ReorgEvent struct {
+	OldTip      string `json:"old_tip"`
+	NewTip      string `json:"new_tip"`
+	Depth       int    `json:"depth"`
+	DivergeSlot uint64 `json:"diverge_slot"`
+}
+
+type FinalizedEvent struct {
+	Slot uint64 `json:"slot"`
+	Hash string `json:"hash"`
+}
+
+type SlashEvent struct {
+	Validator string `json:"validator"`
+	Slot      uint64 `json:"slot"`
+	Reason    string `json:"reason"`
+	Amount    int    `json:"amount"`
+	Stake     int    `json:"stake"`
+}
+
+type subscriptionParams struct {
+	Subscription string `json:"subscription"`
+	Result       any    `json:"result"`
+}
+
+type notification struct {
+	JSONRPC string             `json:"jsonrpc"`
+	Method  string             `json:"method"`
+	Params  subscriptionParams `json:"params"`
+}
+
+type wsClient struct {
+	conn *wsConn
+	send chan []byte
+	done chan struct{}
+	subs map[string]string // subscription id -> topic
+	once sync.Once
+}
+
+// hub fans chain events out to WebSocket subscribers.
+type hub struct {
+	logger    ports.Logger
+	queueSize int
+
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+	nextID  uint64
+}
+
+func newHub(logger ports.Logger, queueSize int) *hub {
+	if queueSize <= 0 {
+		queueSize = defaultSendQueue
+	}
+	return &hub{logger: logger, queueSize: queueSize, clients: make(map[*wsClient]struct{})}
+}
+
+// onEvent maps chain events onto subscription topics.
+func (h *hub) onEvent(e core.Event) {
+	switch ev := e.(type) {
+	case core.CanonicalTipChanged:
+		if ev.Depth > 0 {
+			h.publish(TopicReorg, ReorgEvent{OldTip: ev.OldTip, NewTip: ev.NewTip, Depth: ev.Depth, DivergeSlot: ev.DivergeSlot})
+		}
+		h.publish(TopicNewHeads, ev.Head.Header())
+	case core.SlotFinalized:
+		h.publish(TopicFinalized, FinalizedEvent{Slot: ev.Slot, Hash: ev.Hash})
+	case core.TxPending:
+		h.publish(TopicPendingTransactions, ev.Tx)
+	case core.EquivocationDetected:
+		h.publish(TopicEquivocation, ev.Proof)
+	case core.ValidatorSlashed:
+		h.publish(TopicSlashing, SlashEvent{Validator: ev.Validator, Slot: ev.Slot, Reason: ev.Reason, Amount: ev.Amount, Stake: ev.Stake})
+	}
+}
+
+func (h *hub) publish(topic string, result any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		for id, t := range c.subs {
+			if t != topic {
+				continue
+			}
+			msg, err := json.Marshal(notification{
+				JSONRPC: "2.0",
+				Method:  "subscription",
+				Params:  subscriptionParams{Subscription: id, Result: result},
+			})
+			if err != nil {
+				h.logger.Errorf("RPC: encode %s notification: %v", topic, err)
+				return
+			}
+			if !h.enqueueLocked(c, msg) {
+				break
+			}
+		}
+	}
+}
+
+// enqueueLocked queues msg for c, dropping the client if its queue is full.
+func (h *hub) enqueueLocked(c *wsClient, msg []byte) bool {
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		h.logger.Warnf("RPC: dropping slow WebSocket subscriber (%d queued)", len(c.send))
+		h.removeLocked(c, closePolicy, "slow consumer")
+		return false
+	}
+}
+
+func (h *hub) enqueue(c *wsClient, msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; ok {
+		h.enqueueLocked(c, msg)
+	}
+}
+
+func (h *hub) add(conn *wsConn) *wsClient {
+	c := &wsClient{
+		conn: conn,
+		send: make(chan []byte, h.queueSize),
+		done: make(chan struct{}),
+		subs: make(map[string]string),
+	}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	go c.writeLoop()
+	return c
+}
+
+func (h *hub) remove(c *wsClient, code int, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(c, code, reason)
+}
+
+func (h *hub) removeLocked(c *wsClient, code int, reason string) {
+	delete(h.clients, c)
+	c.once.Do(func() {
+		close(c.done)
+		// Closing the socket may block on a write deadline; keep it off the
+		// publisher's path.
+		go c.conn.Close(code, reason)
+	})
+}
+
+func (h *hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		h.removeLocked(c, closeNormal, "server shutting down")
+	}
+}
+
+func (h *hub) subscribe(c *wsClient, topic string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	id := fmt.Sprintf("0x%x", h.nextID)
+	c.subs[id] = topic
+	return id
+}
+
+func (h *hub) unsubscribe(c *wsClient, id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := c.subs[id]; !ok {
+		return false
+	}
+	delete(c.subs, id)
+	return true
+}
+
+func (c *wsClient) writeLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			if err := c.conn.WriteText(msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r, s.cfg.MaxBodyBytes)
+	if err != nil {
+		s.cfg.Logger.Warnf("RPC: WebSocket upgrade failed: %v", err)
+		return
+	}
+	c := s.hub.add(conn)
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			switch err {
+			case errWSProtocol:
+				s.hub.remove(c, closeProtocolError, err.Error())
+			case errWSTooBig:
+				s.hub.remove(c, closeTooBig, err.Error())
+			default:
+				s.hub.remove(c, closeNormal, "")
+			}
+			return
+		}
+		resp, ok := s.handleWebSocket(c, msg)
+		if !ok {
+			continue
+		}
+		out, err := json.Marshal(resp)
+		if err != nil {
+			s.cfg.Logger.Errorf("RPC: encode WebSocket response: %v", err)
+			continue
+		}
+		s.hub.enqueue(c, out)
+	}
+}
+
+// handleWebSocket serves subscribe and unsubscribe for c and hands every
+// other method to the regular dispatcher.
+func (s *Server) handleWebSocket(c *wsClient, raw json.RawMessage) (response, bool) {
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != "2.0" {
+		return s.handle(raw)
+	}
+	var result any
+	var err error
+	switch req.Method {
+	case "subscribe":
+		result, err = s.subscribe(c, req.Params)
+	case "unsubscribe":
+		result, err = s.unsubscribe(c, req.Params)
+	default:
+		return s.handle(raw)
+	}
+	resp := response{JSONRPC: "2.0", ID: json.RawMessage("null")}
+	if req.ID != nil {
+		resp.ID = req.ID
+	}
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		resp.Error = toError(err)
+	}
+	return resp, req.ID != nil
+}
+
+func (s *Server) subscribe(c *wsClient, params json.RawMessage) (any, error) {
+	var p struct {
+		Topic string `json:"topic"`
+	}
+	if err := decodeParams(params, &p, "topic"); err != nil {
+		return nil, err
+	}
+	if !topics[p.Topic] {
+		return nil, newError(CodeInvalidParams, "unknown subscription topic %q", p.Topic)
+	}
+	return s.hub.subscribe(c, p.Topic), nil
+}
+
+func (s *Server) unsubscribe(c *wsClient, params json.RawMessage) (any, error) {
+	var p struct {
+		Subscription string `json:"subscription"`
+	}
+	if err := decodeParams(params, &p, "subscription"); err != nil {
+		return nil, err
+	}
+	return s.hub.unsubscribe(c, p.Subscription), nil
+}
@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func maskedFrame(head byte, length []byte, payload []byte) []byte {
+	frame := append([]byte{head}, length...)
+	frame[1] |= 0x80
+	mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// FuzzWSReadFrame feeds arbitrary bytes to the frame reader as a client
+// would send them. It may not panic or allocate past the message limit, and
+// every frame it accepts must be a masked frame that unmasks to the payload
+// it returned.
+func FuzzWSReadFrame(f *testing.F) {
+	f.Add(maskedFrame(0x80|opText, []byte{5}, []byte("hello")))
+	f.Add(maskedFrame(opText, []byte{3}, []byte("par")))
+	f.Add(maskedFrame(0x80|opPing, []byte{0}, nil))
+	f.Add(maskedFrame(0x80|opClose, []byte{2}, []byte{0x03, 0xe8}))
+	f.Add(maskedFrame(0x80|opBinary, []byte{126, 0, 130}, bytes.Repeat([]byte{'x'}, 130)))
+	f.Add(maskedFrame(0x80|opText, binary.BigEndian.AppendUint64([]byte{127}, 1<<62), nil))
+	f.Add(maskedFrame(0x80|opPing, []byte{126, 0, 200}, nil))
+	f.Add([]byte{0x81, 0x05, 'h', 'e', 'l', 'l', 'o'})
+	f.Add([]byte{0xf1, 0x80, 0, 0, 0, 0})
+	const maxBytes = 1 << 10
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c := &wsConn{br: bufio.NewReader(bytes.NewReader(data)), maxBytes: maxBytes}
+		for consumed := 0; ; {
+			fin, op, payload, err := c.readFrame()
+			if err != nil {
+				return
+			}
+			if len(payload) > maxBytes {
+				t.Fatalf("accepted a %d byte frame over the %d byte limit", len(payload), maxBytes)
+			}
+			if op >= opClose && (!fin || len(payload) > 125) {
+				t.Fatalf("accepted control frame op %#x fin=%v with %d bytes", op, fin, len(payload))
+			}
+			frame := data[consumed:]
+			if frame[0]&0x70 != 0 || frame[1]&0x80 == 0 {
+				t.Fatalf("accepted frame with reserved bits or no mask: %x", frame[:2])
+			}
+			header := 2
+			switch frame[1] & 0x7F {
+			case 126:
+				header += 2
+			case 127:
+				header += 8
+			}
+			size := header + 4 + len(payload)
+			if size > len(frame) {
+				t.Fatalf("frame of %d bytes read from %d remaining", size, len(frame))
+			}
+			mask := frame[header : header+4]
+			for i, b := range payload {
+				if frame[header+4+i]^mask[i%4] != b {
+					t.Fatalf("payload byte %d not unmasked", i)
+				}
+			}
+			consumed += size
+		}
+	})
+}
@@ -0,0 +1,264 @@
+package rpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+)
+
+type testWS struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, srv *httptest.Server) *testWS {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	req := "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("bad handshake: %d %v", resp.StatusCode, resp.Header)
+	}
+	return &testWS{t: t, conn: conn, br: br}
+}
+
+func (c *testWS) send(v any) {
+	c.t.Helper()
+	payload, _ := json.Marshal(v)
+	frame := []byte{0x80 | opText}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	default:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("write frame: %v", err)
+	}
+}
+
+// read returns the next frame's opcode and payload.
+func (c *testWS) read() (byte, []byte) {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		c.t.Fatalf("read frame: %v", err)
+	}
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.br, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatalf("read payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+func (c *testWS) readJSON(v any) {
+	c.t.Helper()
+	op, payload := c.read()
+	if op != opText {
+		c.t.Fatalf("expected text frame, got opcode %d %q", op, payload)
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		c.t.Fatalf("decode %q: %v", payload, err)
+	}
+}
+
+func (c *testWS) subscribe(topic string) string {
+	c.t.Helper()
+	c.send(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "subscribe", "params": []any{topic}})
+	var resp testResponse
+	c.readJSON(&resp)
+	var id string
+	if resp.Error != nil || json.Unmarshal(resp.Result, &id) != nil || id == "" {
+		c.t.Fatalf("subscribe %s: %+v", topic, resp)
+	}
+	return id
+}
+
+type testNotification struct {
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	srv, bc, w := newTestServer(t)
+	ws := dialWS(t, srv)
+
+	heads := ws.subscribe(TopicNewHeads)
+	pending := ws.subscribe(TopicPendingTransactions)
+
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	var n testNotification
+	ws.readJSON(&n)
+	var head domain.BlockHeader
+	if err := json.Unmarshal(n.Params.Result, &head); err != nil {
+		t.Fatalf("decode head: %v", err)
+	}
+	if n.Method != "subscription" || n.Params.Subscription != heads || head.Hash != bc.CanonicalTipHash() || head.Index != 4 {
+		t.Fatalf("unexpected newHeads notification %+v %+v", n, head)
+	}
+
+	tx := domain.Transaction{To: "bob", Amount: 5, Fee: 1, Nonce: 1}
+	if err := consensus.SignTransaction(w.PrivateKey, &tx); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := bc.AddTx(tx); err != nil {
+		t.Fatalf("add tx: %v", err)
+	}
+	n = testNotification{}
+	ws.readJSON(&n)
+	var got domain.Transaction
+	if err := json.Unmarshal(n.Params.Result, &got); err != nil || n.Params.Subscription != pending || got.Hash != tx.Hash {
+		t.Fatalf("unexpected pendingTransactions notification %+v", n)
+	}
+
+	// Regular methods still work over the socket.
+	ws.send(map[string]any{"jsonrpc": "2.0", "id": 2, "method": "getTip"})
+	var resp testResponse
+	ws.readJSON(&resp)
+	var tip TipResult
+	if resp.Error != nil || json.Unmarshal(resp.Result, &tip) != nil || tip.Height != 4 {
+		t.Fatalf("getTip over websocket: %+v", resp)
+	}
+
+	ws.send(map[string]any{"jsonrpc": "2.0", "id": 3, "method": "unsubscribe", "params": []any{heads}})
+	resp = testResponse{}
+	ws.readJSON(&resp)
+	if string(resp.Result) != "true" {
+		t.Fatalf("unsubscribe: %+v", resp)
+	}
+	ws.send(map[string]any{"jsonrpc": "2.0", "id": 4, "method": "subscribe", "params": []any{"bogus"}})
+	resp = testResponse{}
+	ws.readJSON(&resp)
+	if resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Fatalf("expected invalid topic error, got %+v", resp)
+	}
+
+	// With newHeads unsubscribed, the next frame is the getTip response
+	// rather than a head notification.
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	ws.send(map[string]any{"jsonrpc": "2.0", "id": 5, "method": "getTip"})
+	resp = testResponse{}
+	ws.readJSON(&resp)
+	if string(resp.ID) != "5" {
+		t.Fatalf("expected getTip response after unsubscribe, got %+v", resp)
+	}
+}
+
+func TestWebSocketFinalized(t *testing.T) {
+	srv, bc, _ := newTestServer(t)
+	ws := dialWS(t, srv)
+	finalized := ws.subscribe(TopicFinalized)
+
+	if err := bc.AddBlock(nil); err != nil {
+		t.Fatalf("add block: %v", err)
+	}
+	var n testNotification
+	ws.readJSON(&n)
+	var info FinalizedEvent
+	if err := json.Unmarshal(n.Params.Result, &info); err != nil || n.Params.Subscription != finalized {
+		t.Fatalf("unexpected finalized notification %+v", n)
+	}
+	want, _ := bc.GetBlockByHeight(2)
+	if info.Slot != bc.FinalizedSlot() || info.Hash != want.Hash {
+		t.Fatalf("finalized %+v, want slot %d hash %s", info, bc.FinalizedSlot(), want.Hash)
+	}
+}
+
+func TestWebSocketSlowConsumerIsDropped(t *testing.T) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		t.Fatalf("wallet: %v", err)
+	}
+	bc := core.NewBlockchain(core.ChainConfig{EpochLength: 10, DeterministicPoH: true, PoHSeed: 1}, nil, nil)
+	if err := bc.AddValidator("Alice", 100, w.PublicKey, w.PrivateKey); err != nil {
+		t.Fatalf("validator: %v", err)
+	}
+	s := New(Config{Chain: bc, SendQueue: 2})
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	ws := dialWS(t, srv)
+	ws.subscribe(TopicNewHeads)
+
+	// Stall the writer so nothing drains, then overflow the queue.
+	s.hub.mu.Lock()
+	var client *wsClient
+	for c := range s.hub.clients {
+		client = c
+	}
+	s.hub.mu.Unlock()
+	client.conn.writeMu.Lock()
+	for i := 0; i < 5; i++ {
+		if err := bc.AddBlock(nil); err != nil {
+			t.Fatalf("add block: %v", err)
+		}
+	}
+	s.hub.mu.Lock()
+	_, still := s.hub.clients[client]
+	s.hub.mu.Unlock()
+	client.conn.writeMu.Unlock()
+	if still {
+		t.Fatalf("slow consumer was not dropped")
+	}
+
+	// Whatever was already queued may arrive first; the stream ends with a
+	// 1008 close frame.
+	for {
+		op, payload := ws.read()
+		if op != opClose {
+			continue
+		}
+		if len(payload) < 2 || binary.BigEndian.Uint16(payload) != closePolicy || string(payload[2:]) != "slow consumer" {
+			t.Fatalf("unexpected close frame %q", payload)
+		}
+		break
+	}
+}
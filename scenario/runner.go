@@ -0,0 +1,554 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"xenium/app"
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+	"xenium/invariant"
+	"xenium/ports"
+)
+
+// maxIdleSlots bounds how long a step waits for an online leader.
+const maxIdleSlots = 1000
+
+// Report is the outcome of a run. Failures lists every assertion that did
+// not hold, prefixed with the step that checked it.
+type Report struct {
+	Name     string
+	Steps    int
+	Height   uint64
+	Slot     uint64
+	Tip      string
+	Failures []string
+}
+
+func (r Report) Passed() bool { return len(r.Failures) == 0 }
+
+type branch struct {
+	tip     domain.Block
+	pending []domain.Block
+}
+
+type runner struct {
+	s        *Scenario
+	chain    *core.Blockchain
+	logger   ports.Logger
+	strategy map[string]strategy
+	inv      *invariant.Checker
+	wallets  map[string]*domain.Wallet
+	addrs    map[string]string // address -> name
+	slot     uint64
+	offline  map[string]uint64 // validator -> last offline slot
+	nonces   map[string]uint64
+	branch   map[string]*branch
+
+	reorgs        int
+	rejected      int
+	equivocations int
+	slashed       map[string]bool
+	missed        map[string]int
+	refused       map[string]int
+}
+
+// Run executes s on a fresh in-memory chain. A non-nil error means the
+// scenario could not be played out (a block was refused, a transaction
+// was invalid, ...); failed assertions are reported in the Report.
+func Run(s *Scenario, logger ports.Logger) (Report, error) {
+	cfg := app.DefaultConfig().Chain
+	cfg.DeterministicPoH = true
+	cfg.PoHSeed = s.Seed
+	if cfg.PoHSeed == 0 {
+		cfg.PoHSeed = 1
+	}
+	s.Chain.apply(&cfg)
+	r := &runner{
+		s:        s,
+		chain:    core.NewBlockchain(cfg, nil, logger),
+		logger:   logger,
+		strategy: make(map[string]strategy),
+		wallets:  make(map[string]*domain.Wallet),
+		addrs:    make(map[string]string),
+		offline:  make(map[string]uint64),
+		nonces:   make(map[string]uint64),
+		branch:   make(map[string]*branch),
+		slashed:  make(map[string]bool),
+		missed:   make(map[string]int),
+		refused:  make(map[string]int),
+	}
+	report := Report{Name: s.Name}
+	if err := r.genesis(); err != nil {
+		return report, err
+	}
+	sub := r.chain.Subscribe(r.onEvent)
+	defer r.chain.Unsubscribe(sub)
+	r.inv = invariant.Attach(r.chain)
+	defer r.inv.Detach()
+
+	for i, st := range s.Timeline {
+		report.Steps = i + 1
+		if st.Action == ActionExpect {
+			for _, f := range r.check(st.Expect) {
+				report.Failures = append(report.Failures, fmt.Sprintf("step %d: %s", i+1, f))
+			}
+			continue
+		}
+		err := r.step(st)
+		if err == nil {
+			err = r.inv.Check()
+		}
+		if err != nil {
+			return r.finish(report), fmt.Errorf("step %d (%s): %w", i+1, st.Action, err)
+		}
+	}
+	if s.Expect != nil {
+		for _, f := range r.check(*s.Expect) {
+			report.Failures = append(report.Failures, "final: "+f)
+		}
+	}
+	return r.finish(report), nil
+}
+
+func (r *runner) finish(report Report) Report {
+	tip := r.chain.Tip()
+	report.Height, report.Slot, report.Tip = tip.Index, tip.Slot, tip.Validator
+	return report
+}
+
+func (o ChainOverrides) apply(cfg *core.ChainConfig) {
+	if o.MaxReorgDepth != nil {
+		cfg.MaxReorgDepth = *o.MaxReorgDepth
+	}
+	if o.FinalitySlots != nil {
+		cfg.FinalitySlots = *o.FinalitySlots
+	}
+	if o.MinReorgWeightDeltaP != nil {
+		cfg.MinReorgWeightDeltaP = *o.MinReorgWeightDeltaP
+	}
+	if o.EpochLength != nil {
+		cfg.EpochLength = *o.EpochLength
+	}
+	if o.MaxBlockTxs != nil {
+		cfg.MaxBlockTxs = *o.MaxBlockTxs
+	}
+}
+
+func (r *runner) genesis() error {
+	for _, v := range r.s.Validators {
+		w, err := r.newWallet(v.Name)
+		if err != nil {
+			return err
+		}
+		if err := r.chain.AddValidator(v.Name, v.Stake, w.PublicKey, w.PrivateKey); err != nil {
+			return err
+		}
+		if v.Balance > 0 {
+			r.chain.SetBalance(w.Address, v.Balance)
+		}
+		r.strategy[v.Name] = honest{}
+		if v.Strategy != nil {
+			r.strategy[v.Name] = strategies[v.Strategy.Type](*v.Strategy)
+		}
+	}
+	names := make([]string, 0, len(r.s.Accounts))
+	for name := range r.s.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w, err := r.newWallet(name)
+		if err != nil {
+			return err
+		}
+		r.chain.SetBalance(w.Address, r.s.Accounts[name])
+	}
+	r.slot = r.chain.Tip().Slot
+	return nil
+}
+
+func (r *runner) newWallet(name string) (*domain.Wallet, error) {
+	w, err := domain.NewWallet()
+	if err != nil {
+		return nil, err
+	}
+	r.wallets[name] = w
+	r.addrs[w.Address] = name
+	return w, nil
+}
+
+// address resolves a validator or account name; anything else is taken as
+// a raw address.
+func (r *runner) address(name string) string {
+	if w, ok := r.wallets[name]; ok {
+		return w.Address
+	}
+	return name
+}
+
+func (r *runner) onEvent(e core.Event) {
+	switch ev := e.(type) {
+	case core.CanonicalTipChanged:
+		if ev.Depth > 0 {
+			r.reorgs++
+		}
+	case core.ReorgRejected:
+		r.rejected++
+	case core.EquivocationDetected:
+		r.equivocations++
+	case core.ValidatorSlashed:
+		r.slashed[ev.Validator] = true
+	case core.SlotMissed:
+		r.missed[ev.Leader]++
+	}
+}
+
+func (r *runner) step(st Step) error {
+	count := st.Count
+	if count <= 0 {
+		count = 1
+	}
+	switch st.Action {
+	case ActionProduce:
+		if st.Withhold {
+			return r.build(st.Branch, &branch{tip: r.chain.Tip()}, count, true)
+		}
+		if err := r.produce(count); err != nil {
+			return err
+		}
+		if st.Branch != "" {
+			r.branch[st.Branch] = &branch{tip: r.chain.Tip()}
+		}
+		return nil
+	case ActionFork:
+		parent, err := r.chain.GetBlockByHeight(*st.FromHeight)
+		if err != nil {
+			return fmt.Errorf("fork point %d: %w", *st.FromHeight, err)
+		}
+		return r.build(st.Branch, &branch{tip: parent}, count, st.Withhold)
+	case ActionExtend:
+		return r.build(st.Branch, r.branch[st.Branch], count, st.Withhold)
+	case ActionRelease:
+		b := r.branch[st.Branch]
+		for _, blk := range b.pending {
+			if err := r.importBlock(blk); err != nil {
+				return err
+			}
+		}
+		b.pending = nil
+		return nil
+	case ActionEquivocate:
+		return r.equivocate(st.Validator)
+	case ActionOffline:
+		r.offline[st.Validator] = r.slot + st.Slots
+		return nil
+	case ActionSkip:
+		r.slot += st.Slots
+		return nil
+	case ActionTx:
+		return r.sendTx(st)
+	}
+	return fmt.Errorf("unknown action %q", st.Action)
+}
+
+// produce hands count slots on the canonical tip to their leaders.
+func (r *runner) produce(count int) error {
+	for i := 0; i < count; i++ {
+		leader, ok := r.nextSlot()
+		if !ok {
+			return errors.New("no online leader")
+		}
+		if err := r.strategy[leader].lead(r, leader); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// build produces count empty blocks on b's tip in successive slots,
+// whoever leads them, and records b under name.
+func (r *runner) build(name string, b *branch, count int, withhold bool) error {
+	if !withhold && len(b.pending) > 0 {
+		return fmt.Errorf("branch %s has withheld blocks; release it first", name)
+	}
+	r.branch[name] = b
+	for made := 0; made < count; {
+		leader, ok := r.nextSlot()
+		if !ok {
+			return errors.New("no online leader")
+		}
+		blk, err := r.makeBlock(b, leader, r.tick(), nil)
+		if err != nil {
+			return err
+		}
+		if withhold {
+			b.pending = append(b.pending, blk)
+		} else if err := r.importBlock(blk); err != nil {
+			return err
+		}
+		b.tip = blk
+		made++
+	}
+	return nil
+}
+
+func (r *runner) importBlock(blk domain.Block) error {
+	if err := r.submit(blk); err != nil {
+		return fmt.Errorf("import block at slot %d by %s: %w", blk.Slot, blk.Validator, err)
+	}
+	return nil
+}
+
+// submit imports blk and runs the invariant checker. A violation is kept
+// by the checker and fails the step once it is done.
+func (r *runner) submit(blk domain.Block) error {
+	err := r.chain.ImportBlock(blk)
+	r.inv.Check()
+	return err
+}
+
+// nextSlot advances to the next slot whose leader is online.
+func (r *runner) nextSlot() (string, bool) {
+	for i := 0; i < maxIdleSlots; i++ {
+		r.slot++
+		leader := r.leader(r.slot)
+		if leader == "" || r.slot <= r.offline[leader] {
+			continue
+		}
+		if _, ok := r.strategy[leader].(offline); !ok {
+			return leader, true
+		}
+	}
+	return "", false
+}
+
+func (r *runner) tick() uint64 {
+	return r.slot * consensus.TicksPerSlot
+}
+
+func (r *runner) leader(slot uint64) string {
+	snap := r.chain.GetEpochSnapshot(slot)
+	if len(snap.Validators) == 0 {
+		return ""
+	}
+	return consensus.LeaderFromSnapshot(slot, snap.Validators)
+}
+
+// makeBlock builds and signs a block on b's tip. Withheld blocks are not
+// known to the chain, so the parent state is replayed from the last
+// imported ancestor.
+func (r *runner) makeBlock(b *branch, leader string, tick uint64, txs []domain.Transaction) (domain.Block, error) {
+	parent := b.tip
+	base := parent.Hash
+	if len(b.pending) > 0 {
+		base = b.pending[0].PrevHash
+	}
+	state, err := r.chain.StateAt(base)
+	if err != nil {
+		return domain.Block{}, err
+	}
+	for _, p := range b.pending {
+		if state, err = consensus.ApplyTransactions(state, p.Transactions, r.wallets[p.Validator].Address); err != nil {
+			return domain.Block{}, err
+		}
+	}
+	w := r.wallets[leader]
+	next, err := consensus.ApplyTransactions(state, txs, w.Address)
+	if err != nil {
+		return domain.Block{}, err
+	}
+	hash, err := consensus.ParsePoHHashHex(parent.PoHHash)
+	if err != nil {
+		return domain.Block{}, err
+	}
+	for t := parent.Tick + 1; t <= tick; t++ {
+		hash = consensus.HashPoH(hash, t)
+	}
+	blk := domain.Block{
+		Index:        parent.Index + 1,
+		PrevHash:     parent.Hash,
+		Slot:         tick / consensus.TicksPerSlot,
+		Tick:         tick,
+		Validator:    leader,
+		TxRoot:       consensus.TxRoot(txs),
+		StateRoot:    consensus.StateRoot(next),
+		PoHHash:      consensus.PoHHashHex(hash),
+		Transactions: txs,
+	}
+	if err := consensus.SignBlock(w.PrivateKey, &blk); err != nil {
+		return domain.Block{}, err
+	}
+	return blk, nil
+}
+
+// equivocate lets leaders act by their strategy until validator (or anyone,
+// if empty) leads a slot, and has it sign two conflicting blocks there.
+func (r *runner) equivocate(validator string) error {
+	for i := 0; i < maxIdleSlots; i++ {
+		leader, ok := r.nextSlot()
+		if !ok {
+			break
+		}
+		if validator != "" && leader != validator {
+			if err := r.strategy[leader].lead(r, leader); err != nil {
+				return err
+			}
+			continue
+		}
+		a, b, err := r.signTwice(leader)
+		if err != nil {
+			return err
+		}
+		if err := r.importBlock(a); err != nil {
+			return err
+		}
+		if err := r.submit(b); err != nil && !errors.Is(err, core.ErrEquivocation) {
+			return fmt.Errorf("import conflicting block: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no slot led by %s", validator)
+}
+
+// signTwice builds two blocks by leader on the tip for the current slot
+// that differ only in their PoH tick.
+func (r *runner) signTwice(leader string) (domain.Block, domain.Block, error) {
+	tip := &branch{tip: r.chain.Tip()}
+	a, err := r.makeBlock(tip, leader, r.tick(), nil)
+	if err != nil {
+		return domain.Block{}, domain.Block{}, err
+	}
+	b, err := r.makeBlock(tip, leader, r.tick()+1, nil)
+	if err != nil {
+		return domain.Block{}, domain.Block{}, err
+	}
+	return a, b, nil
+}
+
+func (r *runner) sendTx(st Step) error {
+	from := r.wallets[st.From]
+	next := r.nonces[from.Address]
+	if n := r.chain.Account(from.Address).Nonce; n > next {
+		next = n
+	}
+	next++
+	tx := domain.Transaction{To: r.address(st.To), Amount: st.Amount, Fee: st.Fee, Nonce: next}
+	if err := consensus.SignTransaction(from.PrivateKey, &tx); err != nil {
+		return err
+	}
+	if err := r.chain.AddTx(tx); err != nil {
+		return err
+	}
+	r.nonces[from.Address] = next
+	return nil
+}
+
+func (r *runner) check(e Expect) []string {
+	var out []string
+	fail := func(format string, args ...any) {
+		out = append(out, fmt.Sprintf(format, args...))
+	}
+	tip := r.chain.Tip()
+	if e.TipValidator != "" && tip.Validator != e.TipValidator {
+		fail("tip produced by %s, want %s", tip.Validator, e.TipValidator)
+	}
+	if e.TipBranch != "" {
+		if b := r.branch[e.TipBranch]; b == nil || b.tip.Hash != tip.Hash {
+			fail("canonical tip %s is not the tip of branch %s", short(tip.Hash), e.TipBranch)
+		}
+	}
+	if e.Height != nil && tip.Index != *e.Height {
+		fail("height %d, want %d", tip.Index, *e.Height)
+	}
+	if e.FinalizedSlot != nil {
+		if got := r.chain.FinalizedSlot(); got != *e.FinalizedSlot {
+			fail("finalized slot %d, want %d", got, *e.FinalizedSlot)
+		}
+	}
+	if e.Reorgs != nil && r.reorgs != *e.Reorgs {
+		fail("%d reorgs, want %d", r.reorgs, *e.Reorgs)
+	}
+	if e.Rejected != nil && r.rejected != *e.Rejected {
+		fail("%d rejected reorgs, want %d", r.rejected, *e.Rejected)
+	}
+	if e.Equivocations != nil && r.equivocations != *e.Equivocations {
+		fail("%d equivocations, want %d", r.equivocations, *e.Equivocations)
+	}
+	if e.Jailed != nil {
+		if got := r.chain.JailedValidators(); !sameSet(got, *e.Jailed) {
+			fail("jailed %v, want %v", got, *e.Jailed)
+		}
+	}
+	if e.Slashed != nil {
+		got := make([]string, 0, len(r.slashed))
+		for name := range r.slashed {
+			got = append(got, name)
+		}
+		sort.Strings(got)
+		if !sameSet(got, *e.Slashed) {
+			fail("slashed %v, want %v", got, *e.Slashed)
+		}
+	}
+	for _, name := range sortedKeys(e.MissedSlots) {
+		if got := r.missed[name]; got != e.MissedSlots[name] {
+			fail("%s missed %d slots, want %d", name, got, e.MissedSlots[name])
+		}
+	}
+	for _, name := range sortedKeys(e.RefusedBlocks) {
+		if got := r.refused[name]; got != e.RefusedBlocks[name] {
+			fail("%d blocks by %s refused, want %d", got, name, e.RefusedBlocks[name])
+		}
+	}
+	if e.Mempool != nil {
+		if got := r.chain.MempoolLen(); got != *e.Mempool {
+			fail("%d transactions in the mempool, want %d", got, *e.Mempool)
+		}
+	}
+	for _, name := range sortedKeys(e.Balances) {
+		if got := r.chain.Account(r.address(name)).Balance; got != e.Balances[name] {
+			fail("balance of %s is %d, want %d", name, got, e.Balances[name])
+		}
+	}
+	validators := r.chain.Validators()
+	for _, name := range sortedKeys(e.Stakes) {
+		if got := validators[name].Stake; got != e.Stakes[name] {
+			fail("stake of %s is %d, want %d", name, got, e.Stakes[name])
+		}
+	}
+	return out
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, s := range a {
+		seen[s] = true
+	}
+	for _, s := range b {
+		if !seen[s] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func short(hash string) string {
+	if len(hash) <= 12 {
+		return hash
+	}
+	return hash[:12]
+}
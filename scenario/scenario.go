@@ -0,0 +1,231 @@
+// Package scenario runs declarative consensus simulations: a set of
+// validators and balances, a timeline of actions (producing, forking,
+// withholding, equivocating, going offline, sending transactions) and
+// assertions on the resulting chain. Scenarios are JSON files; see the
+// scenarios directory for examples.
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Actions accepted in a timeline step.
+const (
+	ActionProduce    = "produce"
+	ActionFork       = "fork"
+	ActionExtend     = "extend"
+	ActionRelease    = "release"
+	ActionEquivocate = "equivocate"
+	ActionOffline    = "offline"
+	ActionSkip       = "skip"
+	ActionTx         = "tx"
+	ActionExpect     = "expect"
+)
+
+type Scenario struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Seed is the deterministic PoH seed, 1 by default.
+	Seed       int64          `json:"seed,omitempty"`
+	Chain      ChainOverrides `json:"chain,omitempty"`
+	Validators []Validator    `json:"validators"`
+	// Accounts are plain wallets with a genesis balance, addressable by name
+	// in tx steps and balance assertions.
+	Accounts map[string]int `json:"accounts,omitempty"`
+	Timeline []Step         `json:"timeline"`
+	Expect   *Expect        `json:"expect,omitempty"`
+}
+
+// ChainOverrides replaces fields of the default chain config.
+type ChainOverrides struct {
+	MaxReorgDepth        *int    `json:"max_reorg_depth,omitempty"`
+	FinalitySlots        *uint64 `json:"finality_slots,omitempty"`
+	MinReorgWeightDeltaP *int    `json:"min_reorg_weight_delta_percent,omitempty"`
+	EpochLength          *uint64 `json:"epoch_length,omitempty"`
+	MaxBlockTxs          *int    `json:"max_block_txs,omitempty"`
+}
+
+type Validator struct {
+	Name     string          `json:"name"`
+	Stake    int             `json:"stake"`
+	Balance  int             `json:"balance,omitempty"`
+	Strategy *StrategyConfig `json:"strategy,omitempty"`
+}
+
+// Step is one timeline entry. Which fields apply depends on Action:
+//
+//	produce    Count leader slots on the canonical tip, each leader acting
+//	           by its strategy (honest: one block with mempool txs). With
+//	           Withhold, Count blocks are kept private in Branch instead.
+//	fork       Count blocks on the canonical block at FromHeight, recorded
+//	           as Branch; Withhold keeps them private.
+//	extend     Count more blocks on Branch; Withhold keeps them private.
+//	release    Import Branch's withheld blocks.
+//	equivocate The leader of the next slot led by Validator (any leader if
+//	           empty) signs two conflicting blocks on the tip. Slots before
+//	           it are produced normally.
+//	offline    Validator produces nothing for the next Slots slots.
+//	skip       Slots pass without any block.
+//	tx         From sends Amount plus Fee to To through the mempool.
+//	expect     Check the embedded assertions now.
+//
+// Every block takes the next slot; slots led by an offline validator are
+// left empty and count as missed.
+type Step struct {
+	Action     string  `json:"action"`
+	Count      int     `json:"count,omitempty"`
+	Slots      uint64  `json:"slots,omitempty"`
+	FromHeight *uint64 `json:"from_height,omitempty"`
+	Branch     string  `json:"branch,omitempty"`
+	Withhold   bool    `json:"withhold,omitempty"`
+	Validator  string  `json:"validator,omitempty"`
+	From       string  `json:"from,omitempty"`
+	To         string  `json:"to,omitempty"`
+	Amount     int     `json:"amount,omitempty"`
+	Fee        int     `json:"fee,omitempty"`
+	Expect
+}
+
+// Expect holds assertions; unset fields are not checked. Names in Balances
+// may be validators, accounts or raw addresses.
+type Expect struct {
+	TipValidator  string         `json:"tip_validator,omitempty"`
+	TipBranch     string         `json:"tip_branch,omitempty"`
+	Height        *uint64        `json:"height,omitempty"`
+	FinalizedSlot *uint64        `json:"finalized_slot,omitempty"`
+	Reorgs        *int           `json:"reorgs,omitempty"`
+	Rejected      *int           `json:"rejected_reorgs,omitempty"`
+	Equivocations *int           `json:"equivocations,omitempty"`
+	Jailed        *[]string      `json:"jailed,omitempty"`
+	Slashed       *[]string      `json:"slashed,omitempty"`
+	MissedSlots   map[string]int `json:"missed_slots,omitempty"`
+	RefusedBlocks map[string]int `json:"refused_blocks,omitempty"`
+	Mempool       *int           `json:"mempool,omitempty"`
+	Balances      map[string]int `json:"balances,omitempty"`
+	Stakes        map[string]int `json:"stakes,omitempty"`
+}
+
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse decodes and validates a scenario. Unknown fields are rejected so
+// that typos in assertions do not pass silently.
+func Parse(data []byte) (*Scenario, error) {
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	var s Scenario
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Scenario) Validate() error {
+	if len(s.Validators) == 0 {
+		return errors.New("at least one validator is required")
+	}
+	names := make(map[string]bool)
+	for _, v := range s.Validators {
+		if v.Name == "" || v.Stake <= 0 {
+			return fmt.Errorf("validator %q needs a name and a positive stake", v.Name)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("duplicate name %q", v.Name)
+		}
+		names[v.Name] = true
+	}
+	for name := range s.Accounts {
+		if names[name] {
+			return fmt.Errorf("duplicate name %q", name)
+		}
+		names[name] = true
+	}
+	for _, v := range s.Validators {
+		if v.Strategy == nil {
+			continue
+		}
+		if err := v.Strategy.validate(names); err != nil {
+			return fmt.Errorf("validator %s: %w", v.Name, err)
+		}
+	}
+	branches := make(map[string]bool)
+	for i, st := range s.Timeline {
+		if err := st.validate(names, branches); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i+1, st.Action, err)
+		}
+	}
+	if s.Expect != nil && s.Expect.TipBranch != "" && !branches[s.Expect.TipBranch] {
+		return fmt.Errorf("expect: unknown branch %q", s.Expect.TipBranch)
+	}
+	return nil
+}
+
+func (st Step) validate(names, branches map[string]bool) error {
+	if st.Action != ActionExpect && !st.Expect.empty() {
+		return errors.New("assertions belong in an expect step")
+	}
+	switch st.Action {
+	case ActionProduce:
+		if st.Withhold && st.Branch == "" {
+			return errors.New("withheld blocks need a branch")
+		}
+		if st.Branch != "" {
+			branches[st.Branch] = true
+		}
+	case ActionFork:
+		if st.FromHeight == nil || st.Branch == "" {
+			return errors.New("from_height and branch are required")
+		}
+		branches[st.Branch] = true
+	case ActionExtend, ActionRelease:
+		if !branches[st.Branch] {
+			return fmt.Errorf("unknown branch %q", st.Branch)
+		}
+	case ActionEquivocate:
+		if st.Validator != "" && !names[st.Validator] {
+			return fmt.Errorf("unknown validator %q", st.Validator)
+		}
+	case ActionOffline:
+		if !names[st.Validator] || st.Slots == 0 {
+			return errors.New("validator and slots are required")
+		}
+	case ActionSkip:
+		if st.Slots == 0 {
+			return errors.New("slots is required")
+		}
+	case ActionTx:
+		if !names[st.From] || st.To == "" || st.Amount <= 0 {
+			return errors.New("from (a known name), to and a positive amount are required")
+		}
+	case ActionExpect:
+		if st.TipBranch != "" && !branches[st.TipBranch] {
+			return fmt.Errorf("unknown branch %q", st.TipBranch)
+		}
+	default:
+		return fmt.Errorf("unknown action %q", st.Action)
+	}
+	return nil
+}
+
+func (e Expect) empty() bool {
+	return e.TipValidator == "" && e.TipBranch == "" && e.Height == nil && e.FinalizedSlot == nil &&
+		e.Reorgs == nil && e.Rejected == nil && e.Equivocations == nil && e.Jailed == nil &&
+		e.Slashed == nil && e.MissedSlots == nil && e.RefusedBlocks == nil && e.Mempool == nil && e.Balances == nil &&
+		e.Stakes == nil
+}
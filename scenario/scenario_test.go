@@ -0,0 +1,71 @@
+package scenario
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"xenium/adapters"
+)
+
+func TestExampleScenarios(t *testing.T) {
+	paths, err := filepath.Glob("../scenarios/*.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no example scenarios: %v", err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			s, err := Load(path)
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			report, err := Run(s, adapters.NopLogger{})
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if !report.Passed() {
+				t.Fatalf("failures: %v", report.Failures)
+			}
+		})
+	}
+}
+
+func TestFailedAssertion(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"name": "wrong",
+		"validators": [{"name": "Alice", "stake": 10}],
+		"timeline": [
+			{"action": "produce", "count": 3},
+			{"action": "expect", "height": 2, "tip_validator": "Alice"}
+		],
+		"expect": {"reorgs": 1}
+	}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	report, err := Run(s, adapters.NopLogger{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := []string{"step 2: height 3, want 2", "final: 0 reorgs, want 1"}
+	if strings.Join(report.Failures, "|") != strings.Join(want, "|") {
+		t.Fatalf("failures %q, want %q", report.Failures, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct{ doc, want string }{
+		{`{"validators": [], "timeline": []}`, "at least one validator"},
+		{`{"validators": [{"name": "A", "stake": 1}], "timeline": [{"action": "jump"}]}`, "unknown action"},
+		{`{"validators": [{"name": "A", "stake": 1}], "timeline": [{"action": "expect", "heigth": 1}]}`, "unknown field"},
+		{`{"validators": [{"name": "A", "stake": 1}], "timeline": [{"action": "release", "branch": "x"}]}`, "unknown branch"},
+		{`{"validators": [{"name": "A", "stake": 1}], "timeline": [{"action": "produce", "height": 1}]}`, "expect step"},
+		{`{"validators": [{"name": "A", "stake": 1}], "timeline": [{"action": "tx", "from": "B", "to": "A", "amount": 1}]}`, "known name"},
+		{`{"validators": [{"name": "A", "stake": 1, "strategy": {"type": "lazy"}}], "timeline": []}`, "unknown strategy"},
+		{`{"validators": [{"name": "A", "stake": 1, "strategy": {"type": "censor", "targets": ["B"]}}], "timeline": []}`, "unknown target"},
+	} {
+		if _, err := Parse([]byte(tc.doc)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want %q", tc.doc, err, tc.want)
+		}
+	}
+}
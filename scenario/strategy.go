@@ -0,0 +1,244 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+
+	"xenium/consensus"
+	"xenium/core"
+	"xenium/domain"
+)
+
+// Strategy types accepted in a validator's strategy.
+const (
+	StrategyHonest           = "honest"
+	StrategyOffline          = "offline"
+	StrategySelfish          = "selfish"
+	StrategyDoubleSign       = "double-sign"
+	StrategyLongRange        = "long-range"
+	StrategyInvalidStateRoot = "invalid-state-root"
+	StrategyCensor           = "censor"
+)
+
+// StrategyConfig selects how a validator behaves in the slots it leads
+// while the timeline produces on the canonical tip:
+//
+//	honest             one block on the tip with mempool transactions
+//	offline            nothing; every slot it leads is missed
+//	selfish            blocks on a private branch, released once Withhold
+//	                   of them are held (2 by default)
+//	double-sign        two conflicting blocks on the tip
+//	long-range         extends a public branch forking just below the
+//	                   finalized slot, trying to rewrite finalized history
+//	invalid-state-root a block on the tip whose state root is wrong
+//	censor             like honest, but leaves out transactions from or to
+//	                   Targets and anything queued behind them
+//
+// Blocks the chain refuses are counted per validator (refused_blocks).
+// Scripted steps (fork, extend, withheld produce, equivocate's target
+// slot) are unaffected.
+type StrategyConfig struct {
+	Type     string   `json:"type"`
+	Withhold int      `json:"withhold,omitempty"`
+	Targets  []string `json:"targets,omitempty"`
+}
+
+type strategy interface {
+	lead(r *runner, name string) error
+}
+
+// strategies maps a strategy type to its constructor; new behaviours are
+// added here.
+var strategies = map[string]func(StrategyConfig) strategy{
+	StrategyHonest:           func(StrategyConfig) strategy { return honest{} },
+	StrategyOffline:          func(StrategyConfig) strategy { return offline{} },
+	StrategySelfish:          newSelfish,
+	StrategyDoubleSign:       func(StrategyConfig) strategy { return doubleSign{} },
+	StrategyLongRange:        func(StrategyConfig) strategy { return &longRange{} },
+	StrategyInvalidStateRoot: func(StrategyConfig) strategy { return invalidStateRoot{} },
+	StrategyCensor:           func(c StrategyConfig) strategy { return censor{targets: c.Targets} },
+}
+
+func (c StrategyConfig) validate(names map[string]bool) error {
+	if _, ok := strategies[c.Type]; !ok {
+		return fmt.Errorf("unknown strategy %q", c.Type)
+	}
+	if c.Withhold < 0 {
+		return errors.New("withhold must not be negative")
+	}
+	if c.Type == StrategyCensor && len(c.Targets) == 0 {
+		return errors.New("censor needs targets")
+	}
+	for _, t := range c.Targets {
+		if !names[t] {
+			return fmt.Errorf("unknown target %q", t)
+		}
+	}
+	return nil
+}
+
+type honest struct{}
+
+func (honest) lead(r *runner, name string) error {
+	txs := r.chain.SelectTxsForBlock(r.chain.Config().MaxBlockTxs, r.wallets[name].Address)
+	blk, err := r.makeBlock(&branch{tip: r.chain.Tip()}, name, r.tick(), txs)
+	if err != nil {
+		return err
+	}
+	return r.importBlock(blk)
+}
+
+// offline never gets to lead: nextSlot skips its slots.
+type offline struct{}
+
+func (offline) lead(*runner, string) error { return nil }
+
+type selfish struct {
+	withhold int
+	private  *branch
+}
+
+func newSelfish(c StrategyConfig) strategy {
+	if c.Withhold == 0 {
+		c.Withhold = 2
+	}
+	return &selfish{withhold: c.Withhold}
+}
+
+func (s *selfish) lead(r *runner, name string) error {
+	if s.private == nil {
+		s.private = &branch{tip: r.chain.Tip()}
+	}
+	blk, err := r.makeBlock(s.private, name, r.tick(), nil)
+	if err != nil {
+		return err
+	}
+	s.private.pending = append(s.private.pending, blk)
+	s.private.tip = blk
+	if len(s.private.pending) < s.withhold {
+		return nil
+	}
+	for _, b := range s.private.pending {
+		r.offer(b)
+	}
+	s.private = nil
+	return nil
+}
+
+type doubleSign struct{}
+
+func (doubleSign) lead(r *runner, name string) error {
+	a, b, err := r.signTwice(name)
+	if err != nil {
+		return err
+	}
+	r.offer(a)
+	r.offer(b)
+	return nil
+}
+
+// longRange keeps extending a branch rooted below the finalized block it
+// first sees, publishing every block.
+type longRange struct {
+	fork *branch
+}
+
+func (l *longRange) lead(r *runner, name string) error {
+	if l.fork == nil {
+		root, ok := r.belowFinality()
+		if !ok {
+			return honest{}.lead(r, name)
+		}
+		l.fork = &branch{tip: root}
+	}
+	blk, err := r.makeBlock(l.fork, name, r.tick(), nil)
+	if err != nil {
+		return err
+	}
+	if r.offer(blk) {
+		l.fork.tip = blk
+	}
+	return nil
+}
+
+// belowFinality returns the parent of the newest finalized canonical block.
+func (r *runner) belowFinality() (domain.Block, bool) {
+	finalized := r.chain.FinalizedSlot()
+	if finalized == 0 {
+		return domain.Block{}, false
+	}
+	chain := r.chain.CanonicalChain()
+	for i := len(chain) - 1; i > 0; i-- {
+		if chain[i].Slot <= finalized {
+			return chain[i-1], true
+		}
+	}
+	return domain.Block{}, false
+}
+
+type invalidStateRoot struct{}
+
+func (invalidStateRoot) lead(r *runner, name string) error {
+	blk, err := r.makeBlock(&branch{tip: r.chain.Tip()}, name, r.tick(), nil)
+	if err != nil {
+		return err
+	}
+	blk.StateRoot = consensus.StateRoot(map[string]domain.Account{"forged": {Balance: 1}})
+	if err := consensus.SignBlock(r.wallets[name].PrivateKey, &blk); err != nil {
+		return err
+	}
+	r.offer(blk)
+	return nil
+}
+
+type censor struct {
+	targets []string
+}
+
+// lead puts censored transactions back into the mempool, together with
+// later transactions from the same senders so nonces stay contiguous.
+func (c censor) lead(r *runner, name string) error {
+	banned := make(map[string]bool, len(c.targets))
+	for _, t := range c.targets {
+		banned[r.address(t)] = true
+	}
+	var txs, held []domain.Transaction
+	for _, tx := range r.chain.SelectTxsForBlock(r.chain.Config().MaxBlockTxs, r.wallets[name].Address) {
+		if banned[tx.From] || banned[tx.To] || heldFrom(held, tx.From) {
+			held = append(held, tx)
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	for _, tx := range held {
+		if err := r.chain.AddTx(tx); err != nil {
+			return err
+		}
+	}
+	blk, err := r.makeBlock(&branch{tip: r.chain.Tip()}, name, r.tick(), txs)
+	if err != nil {
+		return err
+	}
+	return r.importBlock(blk)
+}
+
+func heldFrom(held []domain.Transaction, from string) bool {
+	for _, tx := range held {
+		if tx.From == from {
+			return true
+		}
+	}
+	return false
+}
+
+// offer imports a block from a byzantine strategy; a refusal is recorded
+// rather than treated as a scenario error.
+func (r *runner) offer(blk domain.Block) bool {
+	err := r.submit(blk)
+	if err == nil || errors.Is(err, core.ErrEquivocation) {
+		return true
+	}
+	r.refused[blk.Validator]++
+	r.logger.Debugf("scenario: block by %s at slot %d refused: %v", blk.Validator, blk.Slot, err)
+	return false
+}